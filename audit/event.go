@@ -0,0 +1,100 @@
+// Package audit records who did what to which object. Events are stored in a SQL
+// table by a Writer which batches inserts in the background, the middleware
+// captures the actor and request information used to fill events
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/sql/types"
+)
+
+func init() {
+	structural.RegisterTypeOf(Changes{}, &structural.TypeInfo{SQLType: "text"})
+}
+
+// Changes is a list of field changes stored as JSON text
+type Changes []structural.FieldChange
+
+// Value for sql Valuer interface
+func (changes Changes) Value() (driver.Value, error) {
+	if changes == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal([]structural.FieldChange(changes))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan for sql Scanner interface
+func (changes *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*changes = Changes{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), changes)
+	case []byte:
+		return json.Unmarshal(v, changes)
+	}
+
+	return errors.New("incompatible type for changes")
+}
+
+// Event is an audit log entry
+type Event struct {
+	ID             uint64         `json:"id" db:"id" sql:"no update,NOT NULL AUTO_INCREMENT"`
+	CreatedAt      types.DateTime `json:"createdAt" db:"created_at" sql:"no update,DEFAULT CURRENT_TIMESTAMP"`
+	ActorID        string         `json:"actorId" db:"actor_id" sql:"override,VARCHAR(64) NOT NULL"`
+	OrganizationID uint64         `json:"organizationId" db:"organization_id" sql:"NOT NULL"`
+	Action         string         `json:"action" db:"action" sql:"override,VARCHAR(128) NOT NULL"`
+	TargetType     string         `json:"targetType" db:"target_type" sql:"override,VARCHAR(64) NOT NULL"`
+	TargetID       string         `json:"targetId" db:"target_id" sql:"override,VARCHAR(64) NOT NULL"`
+	Changes        Changes        `json:"changes" db:"changes"`
+	IP             string         `json:"ip" db:"ip" sql:"override,VARCHAR(45) NOT NULL"`
+	RequestID      string         `json:"requestId" db:"request_id" sql:"override,VARCHAR(64) NOT NULL"`
+}
+
+// NewEvent creates a new event for an action on a target
+func NewEvent(action string, targetType string, targetID string) *Event {
+	return &Event{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    Changes{},
+	}
+}
+
+// WithDiff sets the changes between the before and after state of the target,
+// see structural.Diff. Nested structs which were set or cleared are recorded per
+// field (see structural.ExpandChanges). Fields tagged with diff:"-" are not
+// recorded, use the redact tag to hide values of sensitive fields
+func (event *Event) WithDiff(before interface{}, after interface{}) (*Event, error) {
+	changes, err := structural.Diff(structural.Redact(before, ""), structural.Redact(after, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	event.Changes = structural.ExpandChanges(changes)
+
+	return event, nil
+}
+
+// WithInfo sets the actor and request information
+func (event *Event) WithInfo(info *Info) *Event {
+	if info != nil {
+		event.ActorID = info.ActorID
+		event.OrganizationID = info.OrganizationID
+		event.IP = info.IP
+		event.RequestID = info.RequestID
+	}
+
+	return event
+}
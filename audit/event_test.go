@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// recordingQueryer records executed queries
+type recordingQueryer struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (queryer *recordingQueryer) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (queryer *recordingQueryer) Get(dest interface{}, query string, args ...interface{}) error {
+	return nil
+}
+
+func (queryer *recordingQueryer) Select(dest interface{}, query string, args ...interface{}) error {
+	return nil
+}
+
+func (queryer *recordingQueryer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	queryer.queries = append(queryer.queries, query)
+	queryer.args = append(queryer.args, args)
+	return nil, nil
+}
+
+func TestInsertColumns(t *testing.T) {
+	table, err := NewTable(TableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := NewEvent("user.update", "user", "42").WithInfo(&Info{
+		ActorID:        "7",
+		OrganizationID: 3,
+		IP:             "10.0.0.1",
+		RequestID:      "req-1",
+	})
+
+	queryer := &recordingQueryer{}
+
+	_, err = table.Insert([]interface{}{event}, queryer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queryer.queries) != 1 {
+		t.Fatalf("expected 1 query, got %v", len(queryer.queries))
+	}
+
+	expected := "INSERT INTO `audit_event` (`actor_id`,`organization_id`,`action`,`target_type`,`target_id`,`changes`,`ip`,`request_id`)"
+	if !strings.HasPrefix(queryer.queries[0], expected) {
+		t.Errorf("expected query to start with %v, got %v", expected, queryer.queries[0])
+	}
+
+	args := queryer.args[0]
+	if len(args) != 8 || args[0] != "7" || args[1] != uint64(3) || args[6] != "10.0.0.1" || args[7] != "req-1" {
+		t.Errorf("unexpected insert values %v", args)
+	}
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/almerlucke/go-utils/server/middleware/ipfilter"
+)
+
+const (
+	// InfoKey to get the audit info from the request context
+	InfoKey = contextUtils.AuditInfoKey
+)
+
+// Info is the actor and request information added to events
+type Info struct {
+	ActorID        string
+	OrganizationID uint64
+	IP             string
+	RequestID      string
+}
+
+// ActorFunc returns the actor id and organization id of a request
+type ActorFunc func(r *http.Request) (actorID string, organizationID uint64)
+
+// Middleware stores audit info in the request context, it should run after the
+// authentication middleware so the actor is known
+type Middleware struct {
+	Actor ActorFunc
+
+	// RequestIDHeader is the header holding the request id
+	RequestIDHeader string
+
+	// Resolver resolves the client ip behind trusted proxies, if nil the remote
+	// address is used. The ip of the ipfilter middleware takes precedence
+	Resolver *ipfilter.Resolver
+}
+
+// NewMiddleware creates a new audit middleware
+func NewMiddleware(actor ActorFunc) *Middleware {
+	return &Middleware{
+		Actor:           actor,
+		RequestIDHeader: "X-Request-Id",
+	}
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	info := &Info{
+		IP:        ware.ip(r),
+		RequestID: r.Header.Get(ware.RequestIDHeader),
+	}
+
+	if ware.Actor != nil {
+		info.ActorID, info.OrganizationID = ware.Actor(r)
+	}
+
+	next(rw, r.WithContext(context.WithValue(r.Context(), InfoKey, info)))
+}
+
+func (ware *Middleware) ip(r *http.Request) string {
+	return ipfilter.RequestIP(r, ware.Resolver)
+}
+
+// GetInfo from context, nil if the middleware did not run
+func GetInfo(ctx context.Context) *Info {
+	info, _ := ctx.Value(InfoKey).(*Info)
+	return info
+}
+
+// EventFromContext creates a new event filled with the audit info of ctx
+func EventFromContext(ctx context.Context, action string, targetType string, targetID string) *Event {
+	return NewEvent(action, targetType, targetID).WithInfo(GetInfo(ctx))
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"strings"
+	"time"
+)
+
+// Query filters events, zero valued fields are ignored
+type Query struct {
+	ActorID        string
+	OrganizationID uint64
+	Action         string
+	TargetType     string
+	TargetID       string
+	From           time.Time
+	To             time.Time
+	Offset         int64
+	Limit          int64
+}
+
+// where returns the where condition and arguments of the query
+func (query *Query) where() (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if query.ActorID != "" {
+		add("{{ActorID}}=?", query.ActorID)
+	}
+
+	if query.OrganizationID != 0 {
+		add("{{OrganizationID}}=?", query.OrganizationID)
+	}
+
+	if query.Action != "" {
+		add("{{Action}}=?", query.Action)
+	}
+
+	if query.TargetType != "" {
+		add("{{TargetType}}=?", query.TargetType)
+	}
+
+	if query.TargetID != "" {
+		add("{{TargetID}}=?", query.TargetID)
+	}
+
+	if !query.From.IsZero() {
+		add("{{CreatedAt}}>=?", query.From)
+	}
+
+	if !query.To.IsZero() {
+		add("{{CreatedAt}}<?", query.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// Find returns events matching the query, newest first
+func (writer *Writer) Find(query *Query) ([]*Event, error) {
+	where, args := query.where()
+
+	sel := writer.Table.Select("*").OrderBy("{{ID}} DESC")
+
+	if where != "" {
+		sel = sel.Where(where)
+	}
+
+	if query.Limit > 0 {
+		sel = sel.Limit(query.Offset, query.Limit)
+	}
+
+	result, err := sel.Run(writer.Queryer, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*Event), nil
+}
+
+// Count returns the number of events matching the query, offset and limit are
+// ignored
+func (writer *Writer) Count(query *Query) (int64, error) {
+	where, args := query.where()
+
+	sel := writer.Table.Select("COUNT(*)")
+
+	if where != "" {
+		sel = sel.Where(where)
+	}
+
+	var count int64
+
+	err := writer.Queryer.Get(&count, sel.Query(), args...)
+
+	return count, err
+}
+
+// History returns the events of a target, newest first
+func (writer *Writer) History(targetType string, targetID string, offset int64, limit int64) ([]*Event, error) {
+	return writer.Find(&Query{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Offset:     offset,
+		Limit:      limit,
+	})
+}
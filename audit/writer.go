@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// TableName is the default name of the audit table
+const TableName = "audit_event"
+
+// ErrClosed is returned when logging to a closed writer
+var ErrClosed = errors.New("audit writer is closed")
+
+// Writer stores events in a table. Write inserts synchronously, Log queues events
+// which are inserted in batches by a background goroutine
+type Writer struct {
+	// Table is the audit table
+	Table *model.Table
+
+	// Queryer is used to insert and query events
+	Queryer database.Queryer
+
+	// Logger logs failed batch inserts, if nil the default logger is used
+	Logger logging.Logger
+
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan *Event
+	flush         chan chan error
+	done          chan struct{}
+	closeOnce     sync.Once
+	mutex         sync.RWMutex
+	closed        bool
+}
+
+// NewTable creates the audit table definition
+func NewTable(name string) (*model.Table, error) {
+	table, err := model.NewTable(name, &Event{})
+	if err != nil {
+		return nil, err
+	}
+
+	table.KeysAndConstraints = []string{
+		"KEY `target_index` (`target_type`, `target_id`)",
+		"KEY `actor_index` (`actor_id`)",
+		"KEY `organization_index` (`organization_id`)",
+	}
+
+	return table, nil
+}
+
+// NewWriter creates a writer with the default table, the table is created if it
+// does not exist. Queued events are inserted when batchSize events are queued or
+// every flushInterval
+func NewWriter(queryer database.Queryer, batchSize int, flushInterval time.Duration) (*Writer, error) {
+	table, err := NewTable(TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = queryer.Exec(table.TableQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	writer := &Writer{
+		Table:         table,
+		Queryer:       queryer,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan *Event, batchSize*4),
+		flush:         make(chan chan error),
+		done:          make(chan struct{}),
+	}
+
+	go writer.run()
+
+	return writer, nil
+}
+
+// Write inserts events synchronously
+func (writer *Writer) Write(events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows := make([]interface{}, len(events))
+	for i, event := range events {
+		if event.Changes == nil {
+			event.Changes = Changes{}
+		}
+
+		rows[i] = event
+	}
+
+	_, err := writer.Table.Insert(rows, writer.Queryer)
+
+	return err
+}
+
+// Log queues an event for a batched insert, blocks when the queue is full
+func (writer *Writer) Log(event *Event) error {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	if writer.closed {
+		return ErrClosed
+	}
+
+	writer.queue <- event
+
+	return nil
+}
+
+// Flush inserts all queued events and returns the insert error
+func (writer *Writer) Flush() error {
+	writer.mutex.RLock()
+	if writer.closed {
+		writer.mutex.RUnlock()
+		return ErrClosed
+	}
+
+	result := make(chan error)
+	writer.flush <- result
+	writer.mutex.RUnlock()
+
+	return <-result
+}
+
+// Close inserts the queued events and stops the background goroutine
+func (writer *Writer) Close() error {
+	writer.closeOnce.Do(func() {
+		writer.mutex.Lock()
+		writer.closed = true
+		close(writer.queue)
+		writer.mutex.Unlock()
+
+		<-writer.done
+	})
+
+	return nil
+}
+
+// run collects queued events and inserts them in batches
+func (writer *Writer) run() {
+	defer close(writer.done)
+
+	batch := make([]*Event, 0, writer.batchSize)
+
+	var tick <-chan time.Time
+	if writer.flushInterval > 0 {
+		ticker := time.NewTicker(writer.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	insert := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := writer.Write(batch...)
+		if err != nil {
+			logging.OrDefault(writer.Logger).Error("audit batch insert failed", "events", len(batch), "error", err)
+		}
+
+		batch = batch[:0]
+
+		return err
+	}
+
+	for {
+		select {
+		case event, ok := <-writer.queue:
+			if !ok {
+				insert()
+				return
+			}
+
+			batch = append(batch, event)
+
+			if len(batch) >= writer.batchSize {
+				insert()
+			}
+		case result := <-writer.flush:
+			// Drain events queued before the flush request
+			for drained := false; !drained; {
+				select {
+				case event, ok := <-writer.queue:
+					if !ok {
+						drained = true
+						break
+					}
+
+					batch = append(batch, event)
+				default:
+					drained = true
+				}
+			}
+
+			result <- insert()
+		case <-tick:
+			insert()
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package memory contains a generic in-memory cache with per entry TTL, LRU
+// eviction and single flight computation of missing values
+package memory
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	timeUtils "github.com/almerlucke/go-utils/time"
+)
+
+// errPanicked is returned to callers waiting on a computation which panicked
+var errPanicked = errors.New("cache compute function panicked")
+
+// entry is a cached value
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// call is an in flight computation
+type call[V any] struct {
+	wg    sync.WaitGroup
+	value V
+	err   error
+}
+
+// Cache is a concurrency safe in-memory cache, the zero value is an empty cache
+// without ttl and entry limit
+type Cache[K comparable, V any] struct {
+	// TTL is the default time to live of entries, 0 means entries do not expire
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of entries, the least recently used entry
+	// is evicted when the cache is full. 0 means no limit
+	MaxEntries int
+
+	// OnEvict is called when an entry is evicted or expires, can be nil. It is
+	// called with the cache lock held and must not call cache methods
+	OnEvict func(key K, value V)
+
+	mutex   sync.Mutex
+	entries map[K]*list.Element
+	lru     *list.List
+	calls   map[K]*call[V]
+	cancel  context.CancelFunc
+}
+
+// New creates a new cache with a default ttl and a maximum number of entries
+func New[K comparable, V any](ttl time.Duration, maxEntries int) *Cache[K, V] {
+	return &Cache[K, V]{
+		TTL:        ttl,
+		MaxEntries: maxEntries,
+		entries:    map[K]*list.Element{},
+		lru:        list.New(),
+		calls:      map[K]*call[V]{},
+	}
+}
+
+// lazyInit initializes the maps and list of a zero value cache, mutex must be held
+func (cache *Cache[K, V]) lazyInit() {
+	if cache.lru == nil {
+		cache.entries = map[K]*list.Element{}
+		cache.lru = list.New()
+		cache.calls = map[K]*call[V]{}
+	}
+}
+
+// Get returns the value for key, false if the key is missing or expired
+func (cache *Cache[K, V]) Get(key K) (V, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	return cache.get(key, time.Now())
+}
+
+// get a value, mutex must be held
+func (cache *Cache[K, V]) get(key K, now time.Time) (V, bool) {
+	var zero V
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := element.Value.(*entry[K, V])
+	if e.expired(now) {
+		cache.remove(element)
+		return zero, false
+	}
+
+	cache.lru.MoveToFront(element)
+
+	return e.value, true
+}
+
+// Set stores a value with the default ttl
+func (cache *Cache[K, V]) Set(key K, value V) {
+	cache.SetWithTTL(key, value, cache.TTL)
+}
+
+// SetWithTTL stores a value with a ttl, 0 means the entry does not expire
+func (cache *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	cache.set(key, value, ttl)
+}
+
+// set a value, mutex must be held
+func (cache *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.expires = expires
+		cache.lru.MoveToFront(element)
+
+		return
+	}
+
+	cache.entries[key] = cache.lru.PushFront(&entry[K, V]{
+		key:     key,
+		value:   value,
+		expires: expires,
+	})
+
+	if cache.MaxEntries > 0 {
+		for cache.lru.Len() > cache.MaxEntries {
+			cache.remove(cache.lru.Back())
+		}
+	}
+}
+
+// remove an element, mutex must be held
+func (cache *Cache[K, V]) remove(element *list.Element) {
+	e := element.Value.(*entry[K, V])
+
+	cache.lru.Remove(element)
+	delete(cache.entries, e.key)
+
+	if cache.OnEvict != nil {
+		cache.OnEvict(e.key, e.value)
+	}
+}
+
+// Delete removes a key, OnEvict is not called
+func (cache *Cache[K, V]) Delete(key K) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	if element, ok := cache.entries[key]; ok {
+		cache.lru.Remove(element)
+		delete(cache.entries, key)
+	}
+}
+
+// Len returns the number of entries, expired entries which are not yet removed
+// are included
+func (cache *Cache[K, V]) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	return cache.lru.Len()
+}
+
+// Clear removes all entries
+func (cache *Cache[K, V]) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	cache.entries = map[K]*list.Element{}
+	cache.lru.Init()
+}
+
+// GetOrCompute returns the cached value for key or calls fn to compute it. Concurrent
+// calls for the same missing key wait for a single call of fn. Values are only
+// cached if fn returns no error
+func (cache *Cache[K, V]) GetOrCompute(key K, fn func() (V, error)) (V, error) {
+	cache.mutex.Lock()
+	cache.lazyInit()
+
+	if value, ok := cache.get(key, time.Now()); ok {
+		cache.mutex.Unlock()
+		return value, nil
+	}
+
+	if c, ok := cache.calls[key]; ok {
+		cache.mutex.Unlock()
+		c.wg.Wait()
+
+		return c.value, c.err
+	}
+
+	c := &call[V]{}
+	c.wg.Add(1)
+	cache.calls[key] = c
+
+	cache.mutex.Unlock()
+
+	func() {
+		defer c.wg.Done()
+
+		// Make sure waiting callers are released if fn panics
+		defer func() {
+			cache.mutex.Lock()
+			delete(cache.calls, key)
+
+			if c.err == nil {
+				cache.set(key, c.value, cache.TTL)
+			}
+
+			cache.mutex.Unlock()
+		}()
+
+		c.err = errPanicked
+		c.value, c.err = fn()
+	}()
+
+	return c.value, c.err
+}
+
+// DeleteExpired removes all expired entries
+func (cache *Cache[K, V]) DeleteExpired() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.lazyInit()
+
+	now := time.Now()
+
+	for element := cache.lru.Back(); element != nil; {
+		prev := element.Prev()
+
+		if element.Value.(*entry[K, V]).expired(now) {
+			cache.remove(element)
+		}
+
+		element = prev
+	}
+}
+
+// StartJanitor starts a goroutine which removes expired entries every interval
+// until Close is called
+func (cache *Cache[K, V]) StartJanitor(interval time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.cancel != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, cache.cancel = context.WithCancel(context.Background())
+
+	go timeUtils.Every(ctx, interval, func(ctx context.Context) {
+		cache.DeleteExpired()
+	})
+}
+
+// Close stops the janitor goroutine
+func (cache *Cache[K, V]) Close() {
+	cache.mutex.Lock()
+	cancel := cache.cancel
+	cache.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
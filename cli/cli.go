@@ -0,0 +1,348 @@
+// Package cli makes the models, migrations and routes of a service scriptable. A
+// service embeds an App with its tables, migration versions and router in a small
+// main package:
+//
+//	func main() {
+//		app := &cli.App{
+//			Name:     "myservice",
+//			Tables:   tables,
+//			Versions: versions,
+//			Router:   router,
+//		}
+//
+//		app.Main()
+//	}
+//
+// The goutils command in cmd/goutils runs an App without service code, it reads
+// migrations from SQL scripts and generates tables from the structs of a package
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/almerlucke/go-utils/files"
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/server/grouprouter"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/migration"
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// ErrUsage is returned by Run for unknown commands and invalid flags
+var ErrUsage = errors.New("invalid usage")
+
+// RouteLister lists routes, implemented by grouprouter.Group and GroupRouter
+type RouteLister interface {
+	Routes() []grouprouter.Route
+}
+
+// App is a command line tool with the commands:
+//
+//	model gen                       print the CREATE TABLE queries of Tables
+//	gen tables -package name        print typed accessors of Tables, see WriteTableAccessors
+//	migrate up [-to version]        migrate the database to a version
+//	migrate down -to version        roll the database back to a version
+//	migrate status                  print the database version and pending versions
+//	routes list                     print the routes of Router
+//	new service name                generate a service skeleton, see NewService
+//
+// The migrate commands accept -db with a JSON database configuration (see
+// database.Configuration, ${VAR} references are read from the environment) and
+// -dir with a directory of migration scripts (see migration.LoadScriptVersions)
+type App struct {
+	// Name is used in the usage message
+	Name string
+
+	// Tables are printed by model gen
+	Tables []model.Tabler
+
+	// Versions are the migration versions in ascending order, if nil the scripts
+	// of MigrationsDir are used
+	Versions []*migration.Version
+
+	// CurrentVersion is the version migrate up migrates to by default, if empty the
+	// last version is used
+	CurrentVersion string
+
+	// MigrationsDir is the default of the -dir flag
+	MigrationsDir string
+
+	// Database returns the configuration for the migrate commands, if nil the JSON
+	// file given with -db is loaded
+	Database func() (*database.Configuration, error)
+
+	// Router is listed by routes list
+	Router RouteLister
+
+	// Output is written to by the commands, if nil os.Stdout is used
+	Output io.Writer
+}
+
+// Main runs the app with the command line arguments and exits with status 1 on
+// errors and status 2 on invalid usage
+func (app *App) Main() {
+	err := app.Run(os.Args[1:])
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+
+	if errors.Is(err, ErrUsage) {
+		app.usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	os.Exit(1)
+}
+
+// Run runs the command given by args, e.g. []string{"migrate", "up"}
+func (app *App) Run(args []string) error {
+	if len(args) < 2 {
+		return ErrUsage
+	}
+
+	command := args[0] + " " + args[1]
+
+	switch command {
+	case "model gen":
+		return app.modelGen(args[2:])
+	case "gen tables":
+		return app.genTables(args[2:])
+	case "migrate up", "migrate down", "migrate status":
+		return app.migrate(args[1], args[2:])
+	case "routes list":
+		return app.routesList(args[2:])
+	case "new service":
+		return app.newService(args[2:])
+	}
+
+	return fmt.Errorf("%w: unknown command %v", ErrUsage, command)
+}
+
+func (app *App) output() io.Writer {
+	if app.Output != nil {
+		return app.Output
+	}
+
+	return os.Stdout
+}
+
+func (app *App) usage(w io.Writer) {
+	name := app.Name
+	if name == "" {
+		name = "goutils"
+	}
+
+	fmt.Fprintf(w, `usage:
+  %[1]v model gen
+  %[1]v gen tables -package name
+  %[1]v migrate up [-db file] [-dir dir] [-to version]
+  %[1]v migrate down -to version [-db file] [-dir dir]
+  %[1]v migrate status [-db file] [-dir dir]
+  %[1]v routes list
+  %[1]v new service name [-dir dir] [-module path]
+`, name)
+}
+
+// parseFlags parses the flags of a command, extra arguments are not allowed
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(io.Discard)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", ErrUsage, strings.Join(flags.Args(), " "))
+	}
+
+	return nil
+}
+
+/*
+	Models
+*/
+
+// TableName returns the default table name of a template, the snake case name of
+// its struct type (e.g. UserProfile -> user_profile)
+func TableName(template interface{}) string {
+	t := reflect.TypeOf(template)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return model.SnakeCase(t.Name())
+}
+
+// NewTables creates a table for each template named with TableName
+func NewTables(templates ...interface{}) ([]model.Tabler, error) {
+	tables := make([]model.Tabler, len(templates))
+
+	for i, template := range templates {
+		table, err := model.NewTable(TableName(template), template)
+		if err != nil {
+			return nil, fmt.Errorf("%T: %v", template, err)
+		}
+
+		tables[i] = table
+	}
+
+	return tables, nil
+}
+
+func (app *App) modelGen(args []string) error {
+	err := parseFlags(flag.NewFlagSet("model gen", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	w := app.output()
+
+	for i, table := range app.Tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintln(w, model.TablerToQuery(table))
+	}
+
+	return nil
+}
+
+/*
+	Migrations
+*/
+
+func (app *App) migrate(command string, args []string) error {
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	dbFile := flags.String("db", "", "JSON database configuration file")
+	dir := flags.String("dir", app.MigrationsDir, "directory with migration scripts")
+	to := flags.String("to", "", "target version")
+
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if command == "down" && *to == "" {
+		return fmt.Errorf("%w: migrate down requires -to", ErrUsage)
+	}
+
+	versions := app.Versions
+	if versions == nil && *dir != "" {
+		versions, err = migration.LoadScriptVersions(*dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	config, err := app.databaseConfiguration(*dbFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.New(config)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	switch command {
+	case "up":
+		target := *to
+		if target == "" {
+			target = app.currentVersion(versions)
+		}
+
+		if target == "" {
+			return errors.New("no migration versions")
+		}
+
+		return migration.Migrate(db, target, versions)
+	case "down":
+		return migration.Rollback(db, *to, versions)
+	}
+
+	info, err := migration.Status(db)
+	if err != nil {
+		return err
+	}
+
+	w := app.output()
+
+	fmt.Fprintf(w, "version: %v\nmigrated: %v\n", info.Version, info.MigrationDate)
+
+	pending := migration.Pending(info, app.currentVersion(versions), versions)
+	if len(pending) == 0 {
+		fmt.Fprintln(w, "pending: none")
+		return nil
+	}
+
+	fmt.Fprintln(w, "pending:")
+
+	for _, version := range pending {
+		fmt.Fprintf(w, "  %v\n", version)
+	}
+
+	return nil
+}
+
+// currentVersion returns CurrentVersion or the last version
+func (app *App) currentVersion(versions []*migration.Version) string {
+	if app.CurrentVersion != "" {
+		return app.CurrentVersion
+	}
+
+	if len(versions) == 0 {
+		return ""
+	}
+
+	return versions[len(versions)-1].String()
+}
+
+// databaseConfiguration returns the configuration of the Database func or loads
+// the configuration file
+func (app *App) databaseConfiguration(filePath string) (*database.Configuration, error) {
+	if app.Database != nil && filePath == "" {
+		return app.Database()
+	}
+
+	if filePath == "" {
+		return nil, fmt.Errorf("%w: -db is required", ErrUsage)
+	}
+
+	config := database.NewConfiguration("", "", "", "")
+
+	err := files.LoadJSONConfig(filePath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+/*
+	Routes
+*/
+
+func (app *App) routesList(args []string) error {
+	err := parseFlags(flag.NewFlagSet("routes list", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	if app.Router == nil {
+		return errors.New("no router configured")
+	}
+
+	return structural.WriteTable(app.output(), app.Router.Routes(), "")
+}
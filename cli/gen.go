@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// GeneratedHeader is the first line of files generated by gen tables
+const GeneratedHeader = "// Code generated by goutils gen tables. DO NOT EDIT."
+
+var accessorsTemplate = template.Must(template.New("accessors").Parse(GeneratedHeader + `
+
+package {{.Package}}
+
+import (
+	"context"
+	"database/sql"
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Tables}}{{$type := .Type}}
+/*
+	{{.Type}}
+*/
+
+// Quoted columns of the {{.Name}} table for conditions, e.g.
+// Where({{.Type}}Column{{.Example}} + "=?")
+const (
+{{- range .Columns}}
+	{{$type}}Column{{.Field}} = "` + "`{{.Name}}`" + `"
+{{- end}}
+)
+
+// Field names of the {{.Name}} table columns for Select.Columns and Select.Omit
+const (
+{{- range .Columns}}
+	{{$type}}Field{{.Field}} = "{{.Field}}"
+{{- end}}
+)
+
+// {{.Type}}Table is a typed wrapper of the {{.Name}} table
+type {{.Type}}Table struct {
+	Table *model.Table
+}
+
+// New{{.Type}}Table creates the {{.Name}} table
+func New{{.Type}}Table() (*{{.Type}}Table, error) {
+	table, err := model.NewTable("{{.Name}}", &{{.Type}}{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &{{.Type}}Table{Table: table}, nil
+}
+
+// Find selects the {{.Type}} rows matching the where condition
+func (table *{{.Type}}Table) Find(ctx context.Context, queryer database.Queryer, where string, args ...interface{}) ([]*{{.Type}}, error) {
+	return model.RunAs[{{.Type}}](table.Table.Select("*").Where(where).Context(ctx), queryer, args...)
+}
+{{- if .Primary}}
+
+// GetBy{{.Primary.Field}} selects the {{.Type}} with the given {{.Primary.Field}}, returns sql.ErrNoRows if
+// it does not exist
+func (table *{{.Type}}Table) GetBy{{.Primary.Field}}(ctx context.Context, queryer database.Queryer, {{.Primary.Param}} {{.Primary.Type}}) (*{{.Type}}, error) {
+	rows, err := model.RunAs[{{.Type}}](table.Table.Select("*").Where({{.Type}}Column{{.Primary.Field}}+"=?").Context(ctx), queryer, {{.Primary.Param}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return rows[0], nil
+}
+{{- end}}
+
+// Insert inserts {{.Type}} rows
+func (table *{{.Type}}Table) Insert(queryer database.Queryer, objs ...*{{.Type}}) (sql.Result, error) {
+	values := make([]interface{}, len(objs))
+	for i, obj := range objs {
+		values[i] = obj
+	}
+
+	return table.Table.Insert(values, queryer)
+}
+{{- if .Primary}}
+
+// Update updates a {{.Type}} row
+func (table *{{.Type}}Table) Update(queryer database.Queryer, obj *{{.Type}}) (sql.Result, error) {
+	return table.Table.Update(obj, queryer)
+}
+
+// UpdateChanges updates the changed columns of a {{.Type}} row, see model.Table.UpdateChanges
+func (table *{{.Type}}Table) UpdateChanges(queryer database.Queryer, old *{{.Type}}, new *{{.Type}}) (sql.Result, error) {
+	return table.Table.UpdateChanges(old, new, queryer)
+}
+
+// Delete deletes a {{.Type}} row
+func (table *{{.Type}}Table) Delete(queryer database.Queryer, obj *{{.Type}}) (sql.Result, error) {
+	return table.Table.Delete(obj, queryer)
+}
+{{- end}}
+{{end}}`))
+
+// accessorColumn is a column of a generated table
+type accessorColumn struct {
+	Field string
+	Name  string
+
+	// Type and Param are only set for the primary key
+	Type  string
+	Param string
+}
+
+// accessorTable is a generated table
+type accessorTable struct {
+	Type    string
+	Name    string
+	Example string
+	Columns []*accessorColumn
+	Primary *accessorColumn
+}
+
+// WriteTableAccessors writes the Go source of typed wrappers for tables of structs
+// in package pkg: constants for the quoted columns and field names, which replace
+// {{Field}} templates so typos are caught at compile time, and a UserTable style
+// wrapper per table with Find, GetByID (named after the primary key field),
+// Insert, Update, UpdateChanges and Delete
+func WriteTableAccessors(w io.Writer, pkg string, tables ...*model.Table) error {
+	if len(tables) == 0 {
+		return errors.New("no tables to generate")
+	}
+
+	data := struct {
+		Package string
+		Imports []string
+		Tables  []*accessorTable
+	}{
+		Package: pkg,
+	}
+
+	imports := map[string]bool{
+		"github.com/almerlucke/go-utils/sql/database": true,
+		"github.com/almerlucke/go-utils/sql/model":    true,
+	}
+
+	for _, table := range tables {
+		resultType := table.ResultType()
+
+		generated := &accessorTable{
+			Type: resultType.Name(),
+			Name: table.TableName(),
+		}
+
+		desc := table.TableDescriptor()
+
+		for _, column := range desc.Columns {
+			field, ok := resultType.FieldByName(column.ActualName)
+			if !ok {
+				return fmt.Errorf("%v has no field %v", resultType, column.ActualName)
+			}
+
+			accessor := &accessorColumn{
+				Field: column.ActualName,
+				Name:  column.Name,
+			}
+
+			if column == desc.PrimaryColumn {
+				accessor.Type = typeName(field.Type, resultType.PkgPath(), imports)
+				accessor.Param = paramName(column.ActualName)
+				generated.Primary = accessor
+			}
+
+			generated.Columns = append(generated.Columns, accessor)
+		}
+
+		if len(generated.Columns) == 0 {
+			return fmt.Errorf("%v has no columns", resultType)
+		}
+
+		generated.Example = generated.Columns[0].Field
+		if generated.Primary != nil {
+			generated.Example = generated.Primary.Field
+		}
+
+		data.Tables = append(data.Tables, generated)
+	}
+
+	for path := range imports {
+		data.Imports = append(data.Imports, path)
+	}
+
+	sort.Strings(data.Imports)
+
+	var buffer bytes.Buffer
+
+	err := accessorsTemplate.Execute(&buffer, data)
+	if err != nil {
+		return err
+	}
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("can't format generated source: %v", err)
+	}
+
+	_, err = w.Write(source)
+
+	return err
+}
+
+// typeName returns the name of t in the generated package pkgPath, the import paths
+// of named types of other packages are added to imports
+func typeName(t reflect.Type, pkgPath string, imports map[string]bool) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem(), pkgPath, imports)
+	case reflect.Slice:
+		if t.Name() == "" {
+			return "[]" + typeName(t.Elem(), pkgPath, imports)
+		}
+	}
+
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+
+	if t.PkgPath() == pkgPath {
+		return t.Name()
+	}
+
+	imports[t.PkgPath()] = true
+
+	return t.String()
+}
+
+// paramName returns a parameter name for a field name, e.g. ID -> id, UserID -> userID.
+// Names which are keywords or clash with the other parameters are replaced with key
+func paramName(field string) string {
+	name := model.LowerCamelCase(field)
+
+	switch {
+	case token.IsKeyword(name), name == "ctx", name == "queryer", name == "table", name == "rows", name == "err":
+		return "key"
+	}
+
+	return name
+}
+
+func (app *App) genTables(args []string) error {
+	flags := flag.NewFlagSet("gen tables", flag.ContinueOnError)
+	pkg := flags.String("package", "", "package name of the generated source")
+
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *pkg == "" {
+		return fmt.Errorf("%w: gen tables requires -package", ErrUsage)
+	}
+
+	tables := make([]*model.Table, 0, len(app.Tables))
+
+	for _, tabler := range app.Tables {
+		table, ok := tabler.(*model.Table)
+		if !ok {
+			return errors.New("gen tables only supports *model.Table tables")
+		}
+
+		tables = append(tables, table)
+	}
+
+	return WriteTableAccessors(app.output(), strings.TrimSpace(*pkg), tables...)
+}
@@ -0,0 +1,509 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// ServiceGoVersion is the go version of the go.mod of generated services
+var ServiceGoVersion = "1.21"
+
+var serviceNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// scaffoldUser mirrors the User model of users.go in a generated service, the
+// initial migration creates its table
+type scaffoldUser struct {
+	model.Model
+	Email string `json:"email" db:"email" sql:"type=VARCHAR(255),NOT NULL"`
+	Name  string `json:"name" db:"name" sql:"type=VARCHAR(255),NOT NULL"`
+}
+
+// serviceFile is a file of a generated service, ~ is replaced with a backtick in
+// the template so it can contain struct tags. Templates use [[ ]] delimiters so
+// {{Field}} query templates are kept as is
+type serviceFile struct {
+	path     string
+	template string
+}
+
+var serviceFiles = []serviceFile{
+	{"go.mod", `module [[.Module]]
+
+go [[.GoVersion]]
+`},
+	{".gitignore", `.env
+/[[.Name]]
+`},
+	{".env.example", `# Copy to .env, variables of the environment take precedence
+PORT=8080
+REQUEST_TIMEOUT=30s
+JWT_SECRET=change-me
+DB_HOST=localhost
+DB_PORT=3306
+DB_USER=[[.Name]]
+DB_PASSWORD=
+DB_NAME=[[.Name]]
+`},
+	{"migrations/0001_create_users.up.sql", `[[.UsersTable]]
+`},
+	{"migrations/0001_create_users.down.sql", "DROP TABLE IF EXISTS ~users~;\n"},
+	{"main.go", `// Command [[.Name]] serves the [[.Name]] API. The configuration is read from the
+// environment and .env, see Config. Without arguments the database is migrated with
+// the scripts in migrations and the server is started, with arguments the command
+// runs a cli.App command, e.g. "[[.Name]] routes list" or "[[.Name]] migrate status"
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/almerlucke/go-utils/cli"
+	"github.com/almerlucke/go-utils/files"
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/migration"
+	"github.com/almerlucke/go-utils/sql/model"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// MigrationsDir contains the migration scripts, see migration.LoadScriptVersions
+const MigrationsDir = "migrations"
+
+func main() {
+	logger := logging.Default()
+
+	_, err := files.LoadDotEnvFile(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("can't load .env", "error", err)
+		os.Exit(1)
+	}
+
+	config := &Config{}
+
+	err = files.ValidateEnv(config)
+	if err != nil {
+		logger.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 {
+		runCommand(config)
+		return
+	}
+
+	db, err := database.New(config.Database.Configuration())
+	if err != nil {
+		logger.Error("can't connect to the database", "error", err)
+		os.Exit(1)
+	}
+
+	defer db.Close()
+
+	versions, err := migration.LoadScriptVersions(MigrationsDir)
+	if err == nil && len(versions) > 0 {
+		err = migration.Migrate(db, versions[len(versions)-1].String(), versions)
+	}
+
+	if err != nil {
+		logger.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	users, err := NewUserHandles(db)
+	if err != nil {
+		logger.Error("can't create users", "error", err)
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:           ":" + config.Port,
+		Handler:        NewRouter(config, db, users),
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   config.RequestTimeout + 5*time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("listening", "addr", server.Addr)
+
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed", "error", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.RequestTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("shutdown failed", "error", err)
+	}
+}
+
+// runCommand runs the cli.App command given by the arguments
+func runCommand(config *Config) {
+	users, err := NewUserHandles(nil)
+	if err != nil {
+		logging.Default().Error("can't create users", "error", err)
+		os.Exit(1)
+	}
+
+	app := &cli.App{
+		Name:          "[[.Name]]",
+		Tables:        []model.Tabler{users.Table},
+		MigrationsDir: MigrationsDir,
+		Database: func() (*database.Configuration, error) {
+			return config.Database.Configuration(), nil
+		},
+		Router: NewRouter(config, nil, users),
+	}
+
+	app.Main()
+}
+`},
+	{"config.go", `package main
+
+import (
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// Config is read from the environment with files.ValidateEnv
+type Config struct {
+	Port           string        ~env:"PORT" default:"8080"~
+	RequestTimeout time.Duration ~env:"REQUEST_TIMEOUT" default:"30s"~
+	JWTSecret      string        ~env:"JWT_SECRET" validate:"required"~
+	Database       DatabaseConfig ~envPrefix:"DB_"~
+}
+
+// DatabaseConfig is read from the DB_ prefixed env vars
+type DatabaseConfig struct {
+	Host     string ~env:"HOST" default:"localhost"~
+	Port     int    ~env:"PORT" default:"3306" validate:"min=1,max=65535"~
+	User     string ~env:"USER" validate:"required"~
+	Password string ~env:"PASSWORD"~
+	Name     string ~env:"NAME" validate:"required"~
+}
+
+// Configuration returns the database configuration
+func (config *DatabaseConfig) Configuration() *database.Configuration {
+	configuration := database.NewConfiguration(config.Host, config.User, config.Password, config.Name)
+	configuration.Port = config.Port
+	configuration.Parameters["parseTime"] = "true"
+
+	return configuration
+}
+`},
+	{"router.go", `package main
+
+import (
+	"net/http"
+
+	"github.com/almerlucke/go-utils/server/auth/jwt"
+	"github.com/almerlucke/go-utils/server/grouprouter"
+	"github.com/almerlucke/go-utils/server/middleware/authtoken"
+	"github.com/almerlucke/go-utils/server/middleware/recovery"
+	"github.com/almerlucke/go-utils/server/middleware/timeout"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Claims are the claims of the JWT required by private routes
+type Claims struct {
+	Subject string ~claim:"sub"~
+}
+
+// NewRouter wires the middleware stack and the routes. All routes share the
+// recovery and timeout middleware, the private group requires a valid JWT. db can
+// be nil when the router is only used to list the routes
+func NewRouter(config *Config, db *database.DB, users *UserHandles) *grouprouter.GroupRouter {
+	router := grouprouter.NewGroupRouter(nil)
+
+	api := grouprouter.NewPrefixGroup("/api/v1")
+	api.Middleware.Use(recovery.New())
+	api.Middleware.Use(timeout.New(config.RequestTimeout))
+	router.AddGroup(api)
+
+	public := api.AddNewGroup("")
+	public.Handle(http.MethodGet, "/api/v1/health", health(db))
+
+	private := api.AddNewGroup("/api/v1/users")
+	private.Middleware.Use(authtoken.New(jwt.StructFactory[Claims]{}, config.JWTSecret))
+	users.Register(private)
+
+	api.Prepare()
+
+	return router
+}
+
+// health reports the health of the database
+func health(db *database.DB) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+		status, err := db.HealthCheck(r.Context())
+		if err != nil {
+			response.ServiceUnavailable(rw, err.Error(), 0)
+			return
+		}
+
+		response.OK(rw, status)
+	}
+}
+`},
+	{"users.go", `package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/almerlucke/go-utils/server/grouprouter"
+	"github.com/almerlucke/go-utils/server/request/unmarshal"
+	"github.com/almerlucke/go-utils/server/request/validate"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// User is a user of the service, its table is created by the first migration
+type User struct {
+	model.Model
+	Email string ~json:"email" db:"email" sql:"type=VARCHAR(255),NOT NULL"~
+	Name  string ~json:"name" db:"name" sql:"type=VARCHAR(255),NOT NULL"~
+}
+
+// CreateUserRequest is the body of POST /api/v1/users
+type CreateUserRequest struct {
+	Email string ~json:"email" validate:"required,email"~
+	Name  string ~json:"name" validate:"required,max=255"~
+}
+
+// UserHandles serves the users routes
+type UserHandles struct {
+	DB    *database.DB
+	Table *model.Table
+}
+
+// NewUserHandles creates the users table
+func NewUserHandles(db *database.DB) (*UserHandles, error) {
+	table, err := model.NewTable("users", &User{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserHandles{
+		DB:    db,
+		Table: table,
+	}, nil
+}
+
+// Register registers the users routes on group
+func (handles *UserHandles) Register(group *grouprouter.Group) {
+	group.Handle(http.MethodGet, "/api/v1/users", handles.List)
+	group.Handle(http.MethodPost, "/api/v1/users", handles.Create)
+	group.Handle(http.MethodGet, "/api/v1/users/:id", handles.Get)
+}
+
+// List lists the first 100 users
+func (handles *UserHandles) List(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	sel := handles.Table.Select("*").Where("{{Deleted}}=0").OrderBy("{{ID}}").Limit(0, 100).Context(r.Context())
+
+	users, err := model.RunAs[User](sel, handles.DB)
+	if err != nil {
+		response.FromError(rw, err)
+		return
+	}
+
+	response.OK(rw, users)
+}
+
+// Get gets a user by id
+func (handles *UserHandles) Get(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	request := struct {
+		ID uint64 ~param:"id"~
+	}{}
+
+	err := unmarshal.Unmarshal(r, pm, false, &request)
+	if err != nil {
+		response.BadRequest(rw, response.Reason(err.Error()))
+		return
+	}
+
+	user, err := handles.Table.Get(request.ID, handles.DB)
+	if errors.Is(err, sql.ErrNoRows) {
+		response.NotFound(rw)
+		return
+	}
+
+	if err != nil {
+		response.FromError(rw, err)
+		return
+	}
+
+	response.OK(rw, user)
+}
+
+// Create creates a user
+func (handles *UserHandles) Create(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	request := &CreateUserRequest{}
+
+	err := unmarshal.Unmarshal(r, pm, true, request)
+	if err != nil {
+		response.BadRequest(rw, response.Reason(err.Error()))
+		return
+	}
+
+	err = validate.Validate(request)
+	if err != nil {
+		response.ValidationError(rw, err)
+		return
+	}
+
+	user := &User{
+		Email: request.Email,
+		Name:  request.Name,
+	}
+
+	result, err := handles.Table.Insert([]interface{}{user}, handles.DB)
+	if err != nil {
+		response.FromError(rw, err)
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err == nil {
+		user.ID = uint64(id)
+	}
+
+	response.Created(rw, user)
+}
+`},
+}
+
+// NewService generates a runnable service skeleton named name in dir/name with Go
+// module path module (if empty name is used): a GroupRouter with a public and a JWT
+// protected group, a middleware stack, env based configuration with a .env
+// template, a migrations folder and users routes. The skeleton depends on this
+// module, run go mod tidy in the generated directory to resolve its dependencies
+func NewService(dir string, name string, module string) (string, error) {
+	if !serviceNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid service name %v, use lowercase letters, digits, - and _", name)
+	}
+
+	if module == "" {
+		module = name
+	}
+
+	root := filepath.Join(dir, name)
+
+	_, err := os.Stat(root)
+	if err == nil {
+		return "", fmt.Errorf("%v already exists", root)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	usersTable, err := model.NewTable("users", &scaffoldUser{})
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]string{
+		"Name":       name,
+		"Module":     module,
+		"GoVersion":  ServiceGoVersion,
+		"UsersTable": model.TablerToQuery(usersTable),
+	}
+
+	for _, file := range serviceFiles {
+		source, err := renderServiceFile(file, data)
+		if err != nil {
+			return "", fmt.Errorf("%v: %v", file.path, err)
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(file.path))
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return "", err
+		}
+
+		err = os.WriteFile(path, source, 0644)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return root, nil
+}
+
+// renderServiceFile executes the template of file, Go files are formatted
+func renderServiceFile(file serviceFile, data map[string]string) ([]byte, error) {
+	tmpl, err := template.New(file.path).Delims("[[", "]]").Parse(strings.ReplaceAll(file.template, "~", "`"))
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(file.path) != ".go" {
+		return buffer.Bytes(), nil
+	}
+
+	return format.Source(buffer.Bytes())
+}
+
+func (app *App) newService(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("%w: new service requires a name", ErrUsage)
+	}
+
+	flags := flag.NewFlagSet("new service", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "directory to create the service in")
+	module := flags.String("module", "", "Go module path, defaults to the name")
+
+	err := parseFlags(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	root, err := NewService(*dir, args[0], *module)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(app.output(), "created %v, next steps:\n  cd %v\n  go mod tidy\n  cp .env.example .env\n  go run .\n", root, root)
+
+	return nil
+}
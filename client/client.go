@@ -0,0 +1,370 @@
+// Package client wraps http.Client with defaults for calling external services:
+// timeouts, retries for idempotent methods, logging hooks, JSON helpers and
+// propagation of tracing headers
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/resilience"
+)
+
+// maxErrorBody is the maximum number of body bytes kept in a StatusError
+const maxErrorBody = 4096
+
+// StatusError is returned by the JSON helpers for non 2xx responses
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+// Error error interface
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d", err.Method, err.URL, err.StatusCode)
+}
+
+// RequestHook is called before a request is sent
+type RequestHook func(req *http.Request)
+
+// ResponseHook is called after each attempt with the response or error
+type ResponseHook func(req *http.Request, resp *http.Response, duration time.Duration, err error)
+
+// Client is a HTTP client for external services
+type Client struct {
+	// HTTPClient is the underlying client
+	HTTPClient *http.Client
+
+	// BaseURL is prepended to request URLs which do not start with a scheme
+	BaseURL string
+
+	// Timeout is applied to each request (including retries) if the context has
+	// no earlier deadline, 0 means no timeout
+	Timeout time.Duration
+
+	// RetryPolicy is used for idempotent methods, if nil requests are not retried
+	RetryPolicy *resilience.Policy
+
+	// Header is added to every request
+	Header http.Header
+
+	// Logger logs each attempt at debug level and failures at warn level, if nil
+	// the default logger is used
+	Logger logging.Logger
+
+	// OnRequest is called before each attempt, can be nil
+	OnRequest RequestHook
+
+	// OnResponse is called after each attempt, can be nil
+	OnResponse ResponseHook
+}
+
+// New creates a client with a 30 second timeout and the default retry policy
+func New() *Client {
+	return &Client{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:          100,
+				MaxIdleConnsPerHost:   10,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+			},
+		},
+		Timeout:     30 * time.Second,
+		RetryPolicy: resilience.DefaultPolicy(),
+		Header:      http.Header{},
+	}
+}
+
+// IsIdempotent checks if requests with method can safely be retried
+func IsIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// retryableStatus checks if a response status is worth retrying
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// errRetryableStatus signals a retryable response status to the retry loop
+type errRetryableStatus struct {
+	status int
+}
+
+func (err *errRetryableStatus) Error() string {
+	return fmt.Sprintf("retryable status %d", err.status)
+}
+
+func (client *Client) httpClient() *http.Client {
+	if client.HTTPClient == nil {
+		return http.DefaultClient
+	}
+
+	return client.HTTPClient
+}
+
+// NewRequest creates a request, relative URLs are resolved against BaseURL
+func (client *Client) NewRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	if client.BaseURL != "" && !strings.Contains(url, "://") {
+		url = client.BaseURL + url
+	}
+
+	return http.NewRequestWithContext(ctx, method, url, body)
+}
+
+// Do sends a request, idempotent requests are retried on network errors and on
+// 429, 502, 503 and 504 responses. Client headers and tracing headers from the
+// request context are added. The caller must close the response body
+func (client *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+
+		resp, err := client.do(req.WithContext(ctx))
+		if err != nil || resp == nil {
+			cancel()
+			return resp, err
+		}
+
+		// Cancel the timeout context when the body is closed
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+		return resp, nil
+	}
+
+	return client.do(req)
+}
+
+func (client *Client) do(req *http.Request) (*http.Response, error) {
+	for key, values := range client.Header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+
+	propagateTraceHeaders(req)
+
+	retry := client.RetryPolicy != nil && IsIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil)
+	if !retry {
+		return client.attempt(req)
+	}
+
+	var resp *http.Response
+
+	attemptNumber := 0
+
+	err := resilience.Retry(req.Context(), client.RetryPolicy, func(ctx context.Context) error {
+		attemptNumber++
+
+		attemptReq := req
+		if attemptNumber > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resilience.Permanent(err)
+			}
+
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		r, err := client.attempt(attemptReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return resilience.Permanent(err)
+			}
+
+			return err
+		}
+
+		resp = r
+
+		if retryableStatus(r.StatusCode) {
+			// Buffer the body and release the connection, the response is returned
+			// as is when all attempts fail
+			data, _ := io.ReadAll(io.LimitReader(r.Body, maxErrorBody))
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(data))
+
+			return &errRetryableStatus{status: r.StatusCode}
+		}
+
+		return nil
+	})
+
+	var statusErr *errRetryableStatus
+	if errors.As(err, &statusErr) {
+		// Attempts exhausted, return the last response so the caller can inspect it
+		return resp, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// attempt sends the request once and calls the hooks
+func (client *Client) attempt(req *http.Request) (*http.Response, error) {
+	if client.OnRequest != nil {
+		client.OnRequest(req)
+	}
+
+	start := time.Now()
+
+	resp, err := client.httpClient().Do(req)
+
+	duration := time.Since(start)
+	logger := logging.OrDefault(client.Logger)
+
+	if err != nil {
+		logger.Warn("http request failed", "method", req.Method, "url", req.URL.Redacted(), "duration", duration, "error", err)
+	} else {
+		logger.Debug("http request", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", duration)
+	}
+
+	if client.OnResponse != nil {
+		client.OnResponse(req, resp, duration, err)
+	}
+
+	return resp, err
+}
+
+// cancelBody cancels a context when closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+
+	return err
+}
+
+/*
+	JSON helpers
+*/
+
+// DoJSON sends in as JSON body (if not nil) and decodes a 2xx response into out
+// (if not nil). Non 2xx responses return a *StatusError
+func (client *Client) DoJSON(ctx context.Context, method string, url string, in interface{}, out interface{}) error {
+	var body io.Reader
+
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	req, err := client.NewRequest(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+		return &StatusError{
+			Method:     method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Body:       data,
+		}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// GetJSON performs a GET request and decodes the JSON response into out
+func (client *Client) GetJSON(ctx context.Context, url string, out interface{}) error {
+	return client.DoJSON(ctx, http.MethodGet, url, nil, out)
+}
+
+// PostJSON posts in as JSON and decodes the JSON response into out
+func (client *Client) PostJSON(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return client.DoJSON(ctx, http.MethodPost, url, in, out)
+}
+
+// PutJSON puts in as JSON and decodes the JSON response into out
+func (client *Client) PutJSON(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return client.DoJSON(ctx, http.MethodPut, url, in, out)
+}
+
+// DeleteJSON performs a DELETE request and decodes the JSON response into out
+func (client *Client) DeleteJSON(ctx context.Context, url string, out interface{}) error {
+	return client.DoJSON(ctx, http.MethodDelete, url, nil, out)
+}
+
+/*
+	Default client
+*/
+
+// DefaultClient is used by the package level JSON helpers
+var DefaultClient = New()
+
+// GetJSON performs a GET request with the default client
+func GetJSON(ctx context.Context, url string, out interface{}) error {
+	return DefaultClient.GetJSON(ctx, url, out)
+}
+
+// PostJSON performs a POST request with the default client
+func PostJSON(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return DefaultClient.PostJSON(ctx, url, in, out)
+}
+
+// PutJSON performs a PUT request with the default client
+func PutJSON(ctx context.Context, url string, in interface{}, out interface{}) error {
+	return DefaultClient.PutJSON(ctx, url, in, out)
+}
+
+// DeleteJSON performs a DELETE request with the default client
+func DeleteJSON(ctx context.Context, url string, out interface{}) error {
+	return DefaultClient.DeleteJSON(ctx, url, out)
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+)
+
+const (
+	// TraceHeadersKey to store tracing headers in a context
+	TraceHeadersKey = contextUtils.TraceHeadersKey
+)
+
+// TraceHeaderNames are the headers copied by TraceHeadersFromRequest
+var TraceHeaderNames = []string{"Traceparent", "Tracestate", "X-Request-Id", "X-Correlation-Id"}
+
+// WithTraceHeaders returns a context with tracing headers which are added to
+// outgoing requests sent with that context
+func WithTraceHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, TraceHeadersKey, header)
+}
+
+// TraceHeadersFromRequest returns the context of r with the tracing headers of r
+// (see TraceHeaderNames), use it to propagate tracing to outgoing calls
+func TraceHeadersFromRequest(r *http.Request) context.Context {
+	header := http.Header{}
+
+	for _, name := range TraceHeaderNames {
+		if value := r.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+
+	return WithTraceHeaders(r.Context(), header)
+}
+
+// propagateTraceHeaders adds tracing headers from the request context which are
+// not already set on the request
+func propagateTraceHeaders(req *http.Request) {
+	header, ok := req.Context().Value(TraceHeadersKey).(http.Header)
+	if !ok {
+		return
+	}
+
+	for key, values := range header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+}
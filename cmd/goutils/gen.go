@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/almerlucke/go-utils/cli"
+)
+
+var genTemplate = template.Must(template.New("gen").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/almerlucke/go-utils/cli"
+	models "{{.ImportPath}}"
+)
+
+func main() {
+	tables, err := cli.NewTables(
+{{- range .Types}}
+		&models.{{.}}{},
+{{- end}}
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app := &cli.App{Tables: tables}
+
+	err = app.Run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`))
+
+// generatedPackage is a package with tagged structs
+type generatedPackage struct {
+	Name       string
+	ImportPath string
+	Dir        string
+	Types      []string
+}
+
+// loadPackage finds the tagged structs of a package
+func loadPackage(pkg string) (*generatedPackage, error) {
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}}\n{{.Dir}}", pkg).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("go list %v: %s", pkg, bytes.TrimSpace(exitErr.Stderr))
+		}
+
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("%v must be a single package", pkg)
+	}
+
+	generated := &generatedPackage{
+		ImportPath: lines[0],
+		Dir:        lines[1],
+	}
+
+	generated.Name, generated.Types, err = taggedStructs(generated.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(generated.Types) == 0 {
+		return nil, fmt.Errorf("package %v has no structs with db or sql tags", generated.ImportPath)
+	}
+
+	return generated, nil
+}
+
+// generateModels prints the tables of the tagged structs of a package
+func generateModels(pkg string) error {
+	generated, err := loadPackage(pkg)
+	if err != nil {
+		return err
+	}
+
+	return runGenerated(generated, os.Stdout, "model", "gen")
+}
+
+// generateTables writes typed table accessors for the tagged structs of a package
+// to output, by default tables_gen.go in the package directory. A previously
+// generated file is removed first so it can't break the build of the package
+func generateTables(pkg string, output string) error {
+	generated, err := loadPackage(pkg)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(generated.Dir, "tables_gen.go")
+	}
+
+	existing, err := os.ReadFile(output)
+	if err == nil {
+		if !bytes.HasPrefix(existing, []byte(cli.GeneratedHeader)) {
+			return fmt.Errorf("%v exists and is not generated", output)
+		}
+
+		err = os.Remove(output)
+		if err != nil {
+			return err
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	err = runGenerated(generated, &buffer, "gen", "tables", "-package", generated.Name)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, buffer.Bytes(), 0644)
+}
+
+// runGenerated runs a generated program which imports the package and runs a
+// cli.App with its tables and the given args
+func runGenerated(generated *generatedPackage, stdout io.Writer, args ...string) error {
+	// The program must be inside the module to import the package
+	dir, err := os.MkdirTemp(".", "goutils-gen-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	var buffer bytes.Buffer
+
+	err = genTemplate.Execute(&buffer, generated)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "main.go"), buffer.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "./" + filepath.Base(dir)}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// taggedStructs returns the package name and the sorted names of the exported struct
+// types in dir with db or sql tagged fields or an embedded Model
+func taggedStructs(dir string) (string, []string, error) {
+	fileSet := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fileSet, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return "", nil, fmt.Errorf("%v must contain a single package", dir)
+	}
+
+	pkgName := ""
+	types := []string{}
+
+	for name, pkg := range pkgs {
+		if name == "main" {
+			return "", nil, errors.New("can't import a main package")
+		}
+
+		pkgName = name
+
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok || !typeSpec.Name.IsExported() || typeSpec.TypeParams != nil {
+						continue
+					}
+
+					if isTaggedStruct(structType) {
+						types = append(types, typeSpec.Name.Name)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(types)
+
+	return pkgName, types, nil
+}
+
+// isTaggedStruct checks if a struct has db or sql tagged fields or embeds Model
+func isTaggedStruct(structType *ast.StructType) bool {
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 && embeddedName(field.Type) == "Model" {
+			return true
+		}
+
+		if field.Tag == nil {
+			continue
+		}
+
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+
+		structTag := reflect.StructTag(tag)
+
+		if _, ok := structTag.Lookup("db"); ok {
+			return true
+		}
+
+		if _, ok := structTag.Lookup("sql"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// embeddedName returns the type name of an embedded field
+func embeddedName(expr ast.Expr) string {
+	switch typed := expr.(type) {
+	case *ast.Ident:
+		return typed.Name
+	case *ast.SelectorExpr:
+		return typed.Sel.Name
+	case *ast.StarExpr:
+		return embeddedName(typed.X)
+	}
+
+	return ""
+}
@@ -0,0 +1,71 @@
+// Command goutils scripts the sql/model and sql/migration packages:
+//
+//	goutils model gen ./models                       print CREATE TABLE queries
+//	goutils gen tables ./models                      generate typed table accessors
+//	goutils migrate up -db db.json -dir migrations   migrate to the last version
+//	goutils migrate down -db db.json -dir migrations -to 0002
+//	goutils migrate status -db db.json -dir migrations
+//	goutils new service users -module github.com/acme/users
+//
+// model gen prints a table for each struct of the package with db or sql tagged
+// fields, tables are named with cli.TableName. It generates and runs a temporary
+// program in the current directory, so run it from the module of the package. gen
+// tables writes typed accessors (see cli.WriteTableAccessors) for the same structs
+// to tables_gen.go in the package directory. Migrations are read from SQL scripts,
+// see migration.LoadScriptVersions. Services embed a cli.App to script their own
+// tables, migrations and routes
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/almerlucke/go-utils/cli"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func main() {
+	args := os.Args[1:]
+
+	if len(args) >= 2 && args[0] == "model" && args[1] == "gen" {
+		if len(args) != 3 {
+			exitUsage("usage: goutils model gen package")
+		}
+
+		exitOnError(generateModels(args[2]))
+
+		return
+	}
+
+	if len(args) >= 2 && args[0] == "gen" && args[1] == "tables" {
+		flags := flag.NewFlagSet("gen tables", flag.ContinueOnError)
+		output := flags.String("o", "", "output file, defaults to tables_gen.go in the package directory")
+
+		if len(args) < 3 || flags.Parse(args[3:]) != nil || flags.NArg() > 0 {
+			exitUsage("usage: goutils gen tables package [-o file]")
+		}
+
+		exitOnError(generateTables(args[2], *output))
+
+		return
+	}
+
+	app := &cli.App{
+		Name: "goutils",
+	}
+
+	app.Main()
+}
+
+func exitUsage(usage string) {
+	fmt.Fprintln(os.Stderr, usage)
+	os.Exit(2)
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
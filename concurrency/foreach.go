@@ -0,0 +1,102 @@
+// Package concurrency contains helpers to process items in parallel and a
+// future type, see the pool sub package for a bounded worker pool
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ForEachN calls fn for every item with at most n calls running in parallel. All
+// items are processed, the errors of failed calls are joined. If ctx is cancelled
+// no new calls are started and the context error is included when items were skipped
+func ForEachN[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) error) error {
+	return forEachN(ctx, items, n, false, fn)
+}
+
+// ForEachNFailFast calls fn for every item with at most n calls running in parallel.
+// On the first error the context passed to running calls is cancelled, no new
+// calls are started and the first error is returned. If ctx is cancelled before all
+// items were started the context error is returned
+func ForEachNFailFast[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) error) error {
+	return forEachN(ctx, items, n, true, fn)
+}
+
+func forEachN[T any](ctx context.Context, items []T, n int, failFast bool, fn func(ctx context.Context, item T) error) error {
+	if n < 1 {
+		n = 1
+	}
+
+	parent := ctx
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		errs    []error
+		started int
+	)
+
+	addError := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		errs = append(errs, err)
+
+		if failFast {
+			cancel()
+		}
+	}
+
+	semaphore := make(chan struct{}, n)
+
+loop:
+	for _, item := range items {
+		select {
+		case <-ctx.Done():
+			break loop
+		case semaphore <- struct{}{}:
+		}
+
+		// Check again, select picks randomly when both cases are ready
+		if ctx.Err() != nil {
+			<-semaphore
+			break
+		}
+
+		wg.Add(1)
+		started++
+
+		go func(item T) {
+			defer func() {
+				if r := recover(); r != nil {
+					addError(fmt.Errorf("panic: %v", r))
+				}
+
+				<-semaphore
+				wg.Done()
+			}()
+
+			if err := fn(ctx, item); err != nil {
+				addError(err)
+			}
+		}(item)
+	}
+
+	wg.Wait()
+
+	if failFast && len(errs) > 0 {
+		return errs[0]
+	}
+
+	if started < len(items) {
+		// Parent context was cancelled before all items were processed
+		errs = append(errs, parent.Err())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+)
+
+// Future holds the result of an asynchronous computation
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Go runs fn in a new goroutine and returns a future for its result, a panic
+// in fn is returned as error
+func Go[T any](fn func() (T, error)) *Future[T] {
+	future := &Future[T]{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(future.done)
+
+		defer func() {
+			if r := recover(); r != nil {
+				future.err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		future.value, future.err = fn()
+	}()
+
+	return future
+}
+
+// Done returns a channel which is closed when the result is available
+func (future *Future[T]) Done() <-chan struct{} {
+	return future.done
+}
+
+// Await waits for the result or until ctx is done
+func (future *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-future.done:
+		return future.value, future.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Get waits for the result
+func (future *Future[T]) Get() (T, error) {
+	<-future.done
+	return future.value, future.err
+}
@@ -0,0 +1,124 @@
+// Package pool contains a bounded worker pool
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed is returned by Submit when the pool is closed
+var ErrClosed = errors.New("pool is closed")
+
+// Task is a unit of work submitted to the pool
+type Task func() error
+
+// Pool runs submitted tasks on a fixed number of workers
+type Pool struct {
+	tasks   chan Task
+	workers sync.WaitGroup
+	pending sync.WaitGroup
+
+	mutex  sync.Mutex
+	errs   []error
+	closed bool
+}
+
+// New creates a new pool with n workers, Submit blocks when all workers are busy
+func New(n int) *Pool {
+	if n < 1 {
+		n = 1
+	}
+
+	p := &Pool{
+		tasks: make(chan Task),
+	}
+
+	p.workers.Add(n)
+
+	for i := 0; i < n; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+
+	for task := range p.tasks {
+		p.run(task)
+	}
+}
+
+// run a task and collect its error, panics are recovered as errors
+func (p *Pool) run(task Task) {
+	defer p.pending.Done()
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.addError(fmt.Errorf("task panic: %v", r))
+		}
+	}()
+
+	if err := task(); err != nil {
+		p.addError(err)
+	}
+}
+
+func (p *Pool) addError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.errs = append(p.errs, err)
+}
+
+// Submit a task, blocks until a worker is available
+func (p *Pool) Submit(task Task) error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return ErrClosed
+	}
+
+	p.pending.Add(1)
+	p.mutex.Unlock()
+
+	p.tasks <- task
+
+	return nil
+}
+
+// Wait waits for all submitted tasks to finish and returns the joined errors of
+// the failed tasks, the collected errors are reset
+func (p *Pool) Wait() error {
+	p.pending.Wait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	err := errors.Join(p.errs...)
+	p.errs = nil
+
+	return err
+}
+
+// Close waits for all submitted tasks and stops the workers, the joined errors of
+// the failed tasks are returned
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return ErrClosed
+	}
+
+	p.closed = true
+	p.mutex.Unlock()
+
+	err := p.Wait()
+
+	close(p.tasks)
+	p.workers.Wait()
+
+	return err
+}
@@ -0,0 +1,205 @@
+// Package securevalue encodes values as encrypted and authenticated strings, for
+// example to store them in cookies. Values are JSON encoded, encrypted with
+// AES-256-GCM and bound to a name so a value can not be moved to another cookie.
+// Keys are versioned to allow key rotation
+package securevalue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrInvalidValue is returned when a value can not be decrypted or authenticated
+	ErrInvalidValue = errors.New("invalid secure value")
+
+	// ErrUnknownKey is returned when a value was encoded with a key version which
+	// is not known to the codec
+	ErrUnknownKey = errors.New("unknown secure value key version")
+
+	// ErrExpired is returned when a value is older than the max age of the codec
+	ErrExpired = errors.New("secure value expired")
+)
+
+// Key is a versioned secret, the secret should contain at least 32 random bytes
+type Key struct {
+	Version byte
+	Secret  []byte
+}
+
+// Codec encodes and decodes secure values
+type Codec struct {
+	// MaxAge is the maximum age of decoded values, 0 means no limit
+	MaxAge time.Duration
+
+	// Now returns the current time, can be replaced for testing
+	Now func() time.Time
+
+	current byte
+	aeads   map[byte]cipher.AEAD
+}
+
+// New creates a codec, the first key is used for encoding, all keys are used for
+// decoding. The encryption key is derived from each secret with HMAC-SHA256
+func New(keys ...Key) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	codec := &Codec{
+		MaxAge:  30 * 24 * time.Hour,
+		Now:     time.Now,
+		current: keys[0].Version,
+		aeads:   map[byte]cipher.AEAD{},
+	}
+
+	for _, key := range keys {
+		if len(key.Secret) < 16 {
+			return nil, errors.New("key secret must be at least 16 bytes")
+		}
+
+		if _, ok := codec.aeads[key.Version]; ok {
+			return nil, errors.New("duplicate key version")
+		}
+
+		block, err := aes.NewCipher(deriveKey(key.Secret, "securevalue encryption"))
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		codec.aeads[key.Version] = aead
+	}
+
+	return codec, nil
+}
+
+// deriveKey derives a 32 byte key for a purpose from a secret
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+
+	return mac.Sum(nil)
+}
+
+func (codec *Codec) now() time.Time {
+	if codec.Now == nil {
+		return time.Now()
+	}
+
+	return codec.Now()
+}
+
+// Encode encrypts the JSON encoding of value bound to name, the result is URL
+// safe base64 and can be used as cookie value
+func (codec *Codec) Encode(name string, value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	aead := codec.aeads[codec.current]
+
+	// Plaintext is the unix timestamp followed by the JSON data
+	plaintext := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(plaintext, uint64(codec.now().Unix()))
+	plaintext = append(plaintext, data...)
+
+	// Output is the key version, nonce and ciphertext
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = codec.current
+
+	_, err = rand.Read(out[1:])
+	if err != nil {
+		return "", err
+	}
+
+	out = aead.Seal(out, out[1:], plaintext, additionalData(name, codec.current))
+
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Decode decrypts a value encoded with Encode for the same name into dst
+func (codec *Codec) Decode(name string, encoded string, dst interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < 1 {
+		return ErrInvalidValue
+	}
+
+	version := raw[0]
+
+	aead, ok := codec.aeads[version]
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	if len(raw) < 1+aead.NonceSize()+aead.Overhead() {
+		return ErrInvalidValue
+	}
+
+	nonce := raw[1 : 1+aead.NonceSize()]
+	ciphertext := raw[1+aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(name, version))
+	if err != nil || len(plaintext) < 8 {
+		return ErrInvalidValue
+	}
+
+	if codec.MaxAge > 0 {
+		created := time.Unix(int64(binary.BigEndian.Uint64(plaintext[:8])), 0)
+		if codec.now().Sub(created) > codec.MaxAge {
+			return ErrExpired
+		}
+	}
+
+	return json.Unmarshal(plaintext[8:], dst)
+}
+
+// additionalData binds the ciphertext to the name and key version
+func additionalData(name string, version byte) []byte {
+	return append([]byte{version}, name...)
+}
+
+/*
+	Cookies
+*/
+
+// SetCookie encodes value and sets it as cookie, cookie is used as template for
+// the cookie attributes and its Value is replaced
+func (codec *Codec) SetCookie(rw http.ResponseWriter, cookie *http.Cookie, value interface{}) error {
+	encoded, err := codec.Encode(cookie.Name, value)
+	if err != nil {
+		return err
+	}
+
+	c := *cookie
+	c.Value = encoded
+
+	http.SetCookie(rw, &c)
+
+	return nil
+}
+
+// ReadCookie decodes the value of the named cookie into dst, http.ErrNoCookie is
+// returned if the cookie is not set
+func (codec *Codec) ReadCookie(r *http.Request, name string, dst interface{}) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+
+	return codec.Decode(name, cookie.Value, dst)
+}
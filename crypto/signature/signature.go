@@ -0,0 +1,297 @@
+// Package signature signs and verifies payloads with HMAC-SHA256. A Signer supports
+// key rotation by accepting multiple keys, timestamped signatures for webhooks and
+// signed query parameters for presigned links
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned when no key matches the signature
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrMalformedSignature is returned when a timestamped signature can not be parsed
+	ErrMalformedSignature = errors.New("malformed signature")
+
+	// ErrExpired is returned when the signature timestamp is too old or the link expired
+	ErrExpired = errors.New("signature expired")
+)
+
+const (
+	// SignatureParam is the query parameter holding the signature of a signed link
+	SignatureParam = "signature"
+
+	// ExpiresParam is the query parameter holding the unix expiry time of a signed link
+	ExpiresParam = "expires"
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of payload
+func Sign(payload []byte, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the hex encoded signature against each key with a constant time
+// comparison, true if one of the keys matches
+func Verify(payload []byte, signature string, keys ...[]byte) bool {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	valid := false
+
+	for _, key := range keys {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+
+		// Check all keys so timing does not reveal which key matched
+		if hmac.Equal(mac.Sum(nil), decoded) {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// Signer signs with the first key and verifies with all keys, to rotate keys add
+// the new key in front and remove the old key after all signatures have expired
+type Signer struct {
+	// Keys used for verification, the first key is used for signing
+	Keys [][]byte
+
+	// MaxAge is the maximum age of timestamped signatures, 0 means no limit
+	MaxAge time.Duration
+
+	// Now returns the current time, can be replaced for testing
+	Now func() time.Time
+}
+
+// NewSigner creates a new signer, the first key is used for signing
+func NewSigner(keys ...[]byte) *Signer {
+	return &Signer{
+		Keys:   keys,
+		MaxAge: 5 * time.Minute,
+		Now:    time.Now,
+	}
+}
+
+func (signer *Signer) now() time.Time {
+	if signer.Now == nil {
+		return time.Now()
+	}
+
+	return signer.Now()
+}
+
+// Sign payload with the first key
+func (signer *Signer) Sign(payload []byte) string {
+	if len(signer.Keys) == 0 {
+		panic("signature: signer has no keys")
+	}
+
+	return Sign(payload, signer.Keys[0])
+}
+
+// Verify signature of payload with all keys
+func (signer *Signer) Verify(payload []byte, signature string) error {
+	if !Verify(payload, signature, signer.Keys...) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// timestampedPayload prefixes the payload with the timestamp
+func timestampedPayload(timestamp int64, payload []byte) []byte {
+	prefix := strconv.FormatInt(timestamp, 10) + "."
+	return append([]byte(prefix), payload...)
+}
+
+// SignTimestamped returns a signature of the form "t=<unix>,v1=<hex>", the
+// timestamp is part of the signed content so it can not be altered
+func (signer *Signer) SignTimestamped(payload []byte) string {
+	timestamp := signer.now().Unix()
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, signer.Sign(timestampedPayload(timestamp, payload)))
+}
+
+// VerifyTimestamped verifies a signature created by SignTimestamped and checks
+// the timestamp against MaxAge
+func (signer *Signer) VerifyTimestamped(payload []byte, signature string) error {
+	var (
+		timestamp  int64
+		signatures []string
+		err        error
+	)
+
+	for _, part := range strings.Split(signature, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return ErrMalformedSignature
+		}
+
+		switch key {
+		case "t":
+			timestamp, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrMalformedSignature
+	}
+
+	if signer.MaxAge > 0 {
+		age := signer.now().Sub(time.Unix(timestamp, 0))
+		if age > signer.MaxAge || age < -signer.MaxAge {
+			return ErrExpired
+		}
+	}
+
+	signed := timestampedPayload(timestamp, payload)
+
+	for _, sig := range signatures {
+		if Verify(signed, sig, signer.Keys...) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+/*
+	Signed links
+*/
+
+// SignValues adds an expiry and signature to query values, the signature covers
+// all values. A ttl of 0 creates a link which does not expire. Use SignURL for links,
+// the signature of SignValues is valid for any path
+func (signer *Signer) SignValues(values url.Values, ttl time.Duration) url.Values {
+	return signer.signValues("", values, ttl)
+}
+
+// VerifyValues verifies query values signed with SignValues and checks the expiry
+func (signer *Signer) VerifyValues(values url.Values) error {
+	return signer.verifyValues("", values)
+}
+
+// signValues signs the values prefixed with the link location
+func (signer *Signer) signValues(location string, values url.Values, ttl time.Duration) url.Values {
+	signed := url.Values{}
+
+	for key, value := range values {
+		if key == SignatureParam {
+			continue
+		}
+
+		signed[key] = append([]string{}, value...)
+	}
+
+	if ttl > 0 {
+		signed.Set(ExpiresParam, strconv.FormatInt(signer.now().Add(ttl).Unix(), 10))
+	}
+
+	signed.Set(SignatureParam, signer.Sign(linkPayload(location, signed)))
+
+	return signed
+}
+
+// verifyValues verifies values signed with signValues for the same location
+func (signer *Signer) verifyValues(location string, values url.Values) error {
+	signature := values.Get(SignatureParam)
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+
+	err := signer.Verify(linkPayload(location, values), signature)
+	if err != nil {
+		return err
+	}
+
+	if expires := values.Get(ExpiresParam); expires != "" {
+		timestamp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return ErrMalformedSignature
+		}
+
+		if signer.now().After(time.Unix(timestamp, 0)) {
+			return ErrExpired
+		}
+	}
+
+	return nil
+}
+
+// linkPayload returns the signed content of a link, the location followed by the
+// encoded values without the signature
+func linkPayload(location string, values url.Values) []byte {
+	unsigned := url.Values{}
+
+	for key, value := range values {
+		if key != SignatureParam {
+			unsigned[key] = value
+		}
+	}
+
+	if location == "" {
+		return []byte(unsigned.Encode())
+	}
+
+	return []byte(location + "?" + unsigned.Encode())
+}
+
+// location returns the host and path of a link which are covered by the signature
+func location(u *url.URL) string {
+	return strings.ToLower(u.Host) + u.EscapedPath()
+}
+
+// SignURL returns rawURL with signed query parameters, the signature covers the host,
+// path and query so the link is only valid for the same location. Sign absolute URLs
+// when they are verified with VerifyRequest
+func (signer *Signer) SignURL(rawURL string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.RawQuery = signer.signValues(location(u), u.Query(), ttl).Encode()
+
+	return u.String(), nil
+}
+
+// VerifyURL verifies a URL signed with SignURL, rawURL must have the same host as the
+// signed URL
+func (signer *Signer) VerifyURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	return signer.verifyValues(location(u), u.Query())
+}
+
+// VerifyRequest verifies the URL of a request for an absolute URL signed with SignURL,
+// the host is taken from the Host header
+func (signer *Signer) VerifyRequest(r *http.Request) error {
+	u := *r.URL
+	u.Host = r.Host
+
+	return signer.verifyValues(location(&u), u.Query())
+}
@@ -0,0 +1,279 @@
+// Package errors defines typed errors with a kind, wrapping and stack capture.
+// The kind of an error can be mapped to a HTTP status (see response.FromError).
+// The standard library helpers Is, As, Unwrap and Join are re-exported so this
+// package can be used in place of the standard errors package
+package errors
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Kind classifies an error
+type Kind int
+
+// Error kinds
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindUnauthorized
+	KindForbidden
+	KindValidation
+)
+
+// String representation of kind
+func (kind Kind) String() string {
+	switch kind {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindValidation:
+		return "validation"
+	}
+
+	return "internal"
+}
+
+// maxStackDepth is the maximum number of frames captured
+const maxStackDepth = 32
+
+// Error is an error with a kind, an optional code, a message and an optional
+// wrapped cause
+type Error struct {
+	// Kind of the error
+	Kind Kind
+
+	// Code is an optional machine readable code, e.g. "invalid_credentials"
+	Code string
+
+	// Message is a human readable message, safe to return to clients
+	Message string
+
+	// Err is the wrapped cause, can be nil
+	Err error
+
+	stack []uintptr
+}
+
+// Error error interface
+func (err *Error) Error() string {
+	if err.Err == nil {
+		return err.Message
+	}
+
+	if err.Message == "" {
+		return err.Err.Error()
+	}
+
+	return err.Message + ": " + err.Err.Error()
+}
+
+// Unwrap returns the wrapped cause
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is an *Error with the same kind, code and message, this
+// allows comparing with sentinel errors created with New. The message is compared
+// so sentinels of the same kind without code are distinct
+func (err *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return err.Kind == t.Kind && err.Code == t.Code && err.Message == t.Message
+}
+
+// WithCode sets the code and returns the error
+func (err *Error) WithCode(code string) *Error {
+	err.Code = code
+	return err
+}
+
+// Frames returns the captured stack frames
+func (err *Error) Frames() []runtime.Frame {
+	frames := []runtime.Frame{}
+
+	if len(err.stack) == 0 {
+		return frames
+	}
+
+	iter := runtime.CallersFrames(err.stack)
+
+	for {
+		frame, more := iter.Next()
+		frames = append(frames, frame)
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// StackTrace returns the captured stack as a string, one "function file:line"
+// entry per line
+func (err *Error) StackTrace() string {
+	var builder strings.Builder
+
+	for _, frame := range err.Frames() {
+		fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+
+	return builder.String()
+}
+
+// callers captures the stack, skipping the callers inside this package
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+
+	return pcs[:n]
+}
+
+/*
+	Constructors
+*/
+
+// New creates a new error of kind with a message
+func New(kind Kind, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		stack:   callers(),
+	}
+}
+
+// Newf creates a new error of kind with a formatted message
+func Newf(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		stack:   callers(),
+	}
+}
+
+// Wrap wraps err with a kind and message, returns nil if err is nil
+func Wrap(err error, kind Kind, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Err:     err,
+		stack:   callers(),
+	}
+}
+
+// Wrapf wraps err with a kind and formatted message, returns nil if err is nil
+func Wrapf(err error, kind Kind, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		Err:     err,
+		stack:   callers(),
+	}
+}
+
+// NotFound creates a new not found error
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message, stack: callers()}
+}
+
+// Conflict creates a new conflict error
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message, stack: callers()}
+}
+
+// Unauthorized creates a new unauthorized error
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message, stack: callers()}
+}
+
+// Forbidden creates a new forbidden error
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message, stack: callers()}
+}
+
+// Validation creates a new validation error
+func Validation(message string) *Error {
+	return &Error{Kind: KindValidation, Message: message, stack: callers()}
+}
+
+// Internal creates a new internal error
+func Internal(message string) *Error {
+	return &Error{Kind: KindInternal, Message: message, stack: callers()}
+}
+
+/*
+	Inspection
+*/
+
+// KindOf returns the kind of the first *Error in the chain of err, errors
+// without kind are internal
+func KindOf(err error) Kind {
+	var e *Error
+	if stdErrors.As(err, &e) {
+		return e.Kind
+	}
+
+	return KindInternal
+}
+
+// CodeOf returns the first non empty code in the chain of err
+func CodeOf(err error) string {
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Code != "" {
+			return e.Code
+		}
+
+		err = stdErrors.Unwrap(err)
+	}
+
+	return ""
+}
+
+// IsKind reports whether err has kind
+func IsKind(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+/*
+	Standard library
+*/
+
+// Is calls errors.Is from the standard library
+func Is(err, target error) bool {
+	return stdErrors.Is(err, target)
+}
+
+// As calls errors.As from the standard library
+func As(err error, target interface{}) bool {
+	return stdErrors.As(err, target)
+}
+
+// Unwrap calls errors.Unwrap from the standard library
+func Unwrap(err error) error {
+	return stdErrors.Unwrap(err)
+}
+
+// Join calls errors.Join from the standard library
+func Join(errs ...error) error {
+	return stdErrors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+// Package featureflags contains feature flags stored in a SQL table. Flags can be
+// switched on or off, rolled out to a percentage of subjects and targeted at
+// organizations. A Store caches the flags in-process and refreshes them on an
+// interval, the middleware evaluates flags for each request
+package featureflags
+
+import (
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+func init() {
+	structural.RegisterTypeOf(IDList{}, &structural.TypeInfo{SQLType: "text"})
+}
+
+// IDList is a list of ids stored as comma separated text
+type IDList []uint64
+
+// Contains checks if id is in the list
+func (list IDList) Contains(id uint64) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value for sql Valuer interface
+func (list IDList) Value() (driver.Value, error) {
+	components := make([]string, len(list))
+
+	for i, id := range list {
+		components[i] = strconv.FormatUint(id, 10)
+	}
+
+	return strings.Join(components, ","), nil
+}
+
+// Scan for sql Scanner interface
+func (list *IDList) Scan(value interface{}) error {
+	var s string
+
+	switch v := value.(type) {
+	case nil:
+		*list = IDList{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return errors.New("incompatible type for id list")
+	}
+
+	ids := IDList{}
+
+	for _, component := range strings.Split(s, ",") {
+		component = strings.TrimSpace(component)
+		if component == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(component, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	*list = ids
+
+	return nil
+}
+
+// Flag is a feature flag
+type Flag struct {
+	model.Model
+	Key         string `json:"key" db:"key" validate:"required,max=128" sql:"override,VARCHAR(128) NOT NULL"`
+	Description string `json:"description" db:"description"`
+	Enabled     bool   `json:"enabled" db:"enabled" sql:"NOT NULL"`
+
+	// Percentage of subjects (0 - 100) for which the flag is on when enabled, it is
+	// stored as given so set it to 100 to roll a flag out to everyone
+	Percentage int `json:"percentage" db:"percentage" validate:"min=0,max=100" sql:"NOT NULL"`
+
+	// Organizations for which the flag is always on when enabled
+	Organizations IDList `json:"organizations" db:"organizations"`
+}
+
+// Subject is the entity a flag is evaluated for
+type Subject struct {
+	// ID identifies the subject for percentage rollouts, e.g. a user id
+	ID string
+
+	// OrganizationID is used for organization targeting, 0 means no organization
+	OrganizationID uint64
+}
+
+// Evaluate the flag for a subject. A disabled flag is always off, an enabled flag
+// is on for targeted organizations and for the rollout percentage of subjects.
+// Subjects are assigned to a stable bucket by hashing the flag key and subject id
+func (flag *Flag) Evaluate(subject Subject) bool {
+	if !flag.Enabled {
+		return false
+	}
+
+	if subject.OrganizationID != 0 && flag.Organizations.Contains(subject.OrganizationID) {
+		return true
+	}
+
+	if flag.Percentage >= 100 {
+		return true
+	}
+
+	if flag.Percentage <= 0 || subject.ID == "" {
+		return false
+	}
+
+	return bucket(flag.Key, subject.ID) < flag.Percentage
+}
+
+// bucket returns a stable bucket 0 - 99 for a key and subject id
+func bucket(key string, id string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	hash.Write([]byte{0})
+	hash.Write([]byte(id))
+
+	return int(hash.Sum32() % 100)
+}
@@ -0,0 +1,149 @@
+package featureflags
+
+import (
+	"net/http"
+
+	"github.com/almerlucke/go-utils/server/request/unmarshal"
+	"github.com/almerlucke/go-utils/server/request/validate"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Handles is an admin kit with CRUD handles for flags, protect the routes with
+// authentication middleware
+type Handles struct {
+	Store *Store
+}
+
+// NewHandles creates the admin handles for a store
+func NewHandles(store *Store) *Handles {
+	return &Handles{Store: store}
+}
+
+// Register the handles on a router under prefix (e.g. "/api/v1/admin/flags")
+func (handles *Handles) Register(router *httprouter.Router, prefix string) {
+	router.GET(prefix, handles.List)
+	router.POST(prefix, handles.Create)
+	router.GET(prefix+"/:id", handles.Get)
+	router.PUT(prefix+"/:id", handles.Update)
+	router.DELETE(prefix+"/:id", handles.Delete)
+}
+
+// idParams is used to unmarshal the flag id from the route
+type idParams struct {
+	ID uint64 `param:"id"`
+}
+
+// List all flags
+func (handles *Handles) List(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	response.OK(rw, handles.Store.Flags())
+}
+
+// Get a flag by id
+func (handles *Handles) Get(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	flag, ok := handles.load(rw, r, pm)
+	if !ok {
+		return
+	}
+
+	response.OK(rw, flag)
+}
+
+// Create a flag from the JSON body
+func (handles *Handles) Create(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	flag := &Flag{Percentage: 100}
+
+	err := unmarshal.Unmarshal(r, pm, true, flag)
+	if err != nil {
+		response.BadRequest(rw, response.Reason(err.Error()))
+		return
+	}
+
+	err = validate.Validate(flag)
+	if err != nil {
+		response.ValidationError(rw, err)
+		return
+	}
+
+	err = handles.Store.Create(flag)
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+		return
+	}
+
+	created, _ := handles.Store.Flag(flag.Key)
+
+	response.Created(rw, created)
+}
+
+// Update a flag with the JSON body
+func (handles *Handles) Update(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	flag, ok := handles.load(rw, r, pm)
+	if !ok {
+		return
+	}
+
+	id := flag.ID
+
+	err := unmarshal.Unmarshal(r, nil, true, flag)
+	if err != nil {
+		response.BadRequest(rw, response.Reason(err.Error()))
+		return
+	}
+
+	flag.ID = id
+
+	err = validate.Validate(flag)
+	if err != nil {
+		response.ValidationError(rw, err)
+		return
+	}
+
+	err = handles.Store.Update(flag)
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+		return
+	}
+
+	response.OK(rw, flag)
+}
+
+// Delete a flag
+func (handles *Handles) Delete(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	flag, ok := handles.load(rw, r, pm)
+	if !ok {
+		return
+	}
+
+	err := handles.Store.Delete(flag)
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+		return
+	}
+
+	response.OK(rw, nil)
+}
+
+// load the flag of the id route param, writes an error response on failure
+func (handles *Handles) load(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) (*Flag, bool) {
+	params := &idParams{}
+
+	err := unmarshal.Unmarshal(r, pm, false, params)
+	if err != nil {
+		response.BadRequest(rw, response.Reason(err.Error()))
+		return nil, false
+	}
+
+	flag, err := handles.Store.Get(params.ID)
+	if err == ErrNotFound {
+		response.NotFound(rw)
+		return nil, false
+	}
+
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+		return nil, false
+	}
+
+	return flag, true
+}
@@ -0,0 +1,64 @@
+package featureflags
+
+import (
+	"context"
+	"net/http"
+
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+)
+
+const (
+	// FlagsKey to get the evaluated flags from the request context
+	FlagsKey = contextUtils.FeatureFlagsKey
+)
+
+// Flags maps flag keys to their evaluated state
+type Flags map[string]bool
+
+// Enabled checks if a flag is on, unknown flags are off
+func (flags Flags) Enabled(key string) bool {
+	return flags[key]
+}
+
+// SubjectFunc returns the subject of a request
+type SubjectFunc func(r *http.Request) Subject
+
+// Middleware evaluates all flags for the subject of the request and stores them
+// in the request context
+type Middleware struct {
+	Store   *Store
+	Subject SubjectFunc
+}
+
+// NewMiddleware creates a new feature flag middleware, if subject is nil flags
+// are evaluated for an anonymous subject
+func NewMiddleware(store *Store, subject SubjectFunc) *Middleware {
+	return &Middleware{
+		Store:   store,
+		Subject: subject,
+	}
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	subject := Subject{}
+	if ware.Subject != nil {
+		subject = ware.Subject(r)
+	}
+
+	next(rw, r.WithContext(context.WithValue(r.Context(), FlagsKey, ware.Store.Evaluate(subject))))
+}
+
+// GetFlags from context, returns empty flags if the middleware did not run
+func GetFlags(ctx context.Context) Flags {
+	flags, ok := ctx.Value(FlagsKey).(Flags)
+	if !ok {
+		return Flags{}
+	}
+
+	return flags
+}
+
+// IsEnabled checks if a flag is on in the context
+func IsEnabled(ctx context.Context, key string) bool {
+	return GetFlags(ctx).Enabled(key)
+}
@@ -0,0 +1,229 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+	timeUtils "github.com/almerlucke/go-utils/time"
+)
+
+// TableName is the default name of the feature flag table
+const TableName = "feature_flag"
+
+// ErrNotFound is returned when a flag does not exist
+var ErrNotFound = errors.New("feature flag not found")
+
+// Store keeps an in-process copy of all flags which is refreshed on an interval
+type Store struct {
+	// Table is the flag table
+	Table *model.Table
+
+	// Queryer is used to query and modify flags
+	Queryer database.Queryer
+
+	// Logger logs refresh errors, if nil the default logger is used
+	Logger logging.Logger
+
+	mutex  sync.RWMutex
+	flags  map[string]*Flag
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTable creates the feature flag table definition
+func NewTable(name string) (*model.Table, error) {
+	table, err := model.NewTable(name, &Flag{})
+	if err != nil {
+		return nil, err
+	}
+
+	table.KeysAndConstraints = []string{"UNIQUE KEY `key_unique` (`key`)"}
+
+	return table, nil
+}
+
+// NewStore creates a store with the default table, the table is created if it
+// does not exist and the flags are loaded
+func NewStore(queryer database.Queryer) (*Store, error) {
+	table, err := NewTable(TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = queryer.Exec(table.TableQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{
+		Table:   table,
+		Queryer: queryer,
+		flags:   map[string]*Flag{},
+	}
+
+	err = store.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Refresh reloads all flags from the table
+func (store *Store) Refresh() error {
+	result, err := store.Table.Select("*").Where("{{Deleted}}=0").Run(store.Queryer)
+	if err != nil {
+		return err
+	}
+
+	flags := map[string]*Flag{}
+
+	for _, flag := range result.([]*Flag) {
+		flags[flag.Key] = flag
+	}
+
+	store.mutex.Lock()
+	store.flags = flags
+	store.mutex.Unlock()
+
+	return nil
+}
+
+// Start refreshes the flags every interval in the background until Stop is called
+// or ctx is cancelled
+func (store *Store) Start(ctx context.Context, interval time.Duration) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.cancel != nil {
+		return
+	}
+
+	ctx, store.cancel = context.WithCancel(ctx)
+	store.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		timeUtils.Every(ctx, interval, func(ctx context.Context) {
+			if err := store.Refresh(); err != nil {
+				logging.OrDefault(store.Logger).Error("feature flag refresh failed", "error", err)
+			}
+		})
+	}(store.done)
+}
+
+// Stop the background refresh
+func (store *Store) Stop() {
+	store.mutex.Lock()
+	cancel := store.cancel
+	done := store.done
+	store.cancel = nil
+	store.done = nil
+	store.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Flags returns all cached flags
+func (store *Store) Flags() []*Flag {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	flags := make([]*Flag, 0, len(store.flags))
+	for _, flag := range store.flags {
+		flags = append(flags, flag)
+	}
+
+	return flags
+}
+
+// Flag returns a cached flag by key
+func (store *Store) Flag(key string) (*Flag, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	flag, ok := store.flags[key]
+
+	return flag, ok
+}
+
+// IsEnabled evaluates a flag for a subject, unknown flags are off
+func (store *Store) IsEnabled(key string, subject Subject) bool {
+	flag, ok := store.Flag(key)
+	if !ok {
+		return false
+	}
+
+	return flag.Evaluate(subject)
+}
+
+// Evaluate all flags for a subject
+func (store *Store) Evaluate(subject Subject) Flags {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	flags := Flags{}
+	for key, flag := range store.flags {
+		flags[key] = flag.Evaluate(subject)
+	}
+
+	return flags
+}
+
+/*
+	CRUD
+*/
+
+// Create inserts a new flag and refreshes the cache
+func (store *Store) Create(flag *Flag) error {
+	_, err := store.Table.Insert([]interface{}{flag}, store.Queryer)
+	if err != nil {
+		return err
+	}
+
+	return store.Refresh()
+}
+
+// Get loads a flag by id from the table
+func (store *Store) Get(id uint64) (*Flag, error) {
+	result, err := store.Table.Select("*").Where("{{ID}}=? AND {{Deleted}}=0").Run(store.Queryer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := result.([]*Flag)
+	if len(flags) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return flags[0], nil
+}
+
+// Update a flag and refresh the cache
+func (store *Store) Update(flag *Flag) error {
+	_, err := store.Table.Update(flag, store.Queryer)
+	if err != nil {
+		return err
+	}
+
+	return store.Refresh()
+}
+
+// Delete a flag and refresh the cache
+func (store *Store) Delete(flag *Flag) error {
+	_, err := store.Table.Delete(flag, store.Queryer)
+	if err != nil {
+		return err
+	}
+
+	return store.Refresh()
+}
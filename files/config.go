@@ -0,0 +1,507 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+)
+
+const (
+	// EnvTag maps a schema field to an environment variable
+	EnvTag = "env"
+
+	// EnvPrefixTag is prepended to the env keys of the fields of a nested struct
+	EnvPrefixTag = "envPrefix"
+)
+
+// ConfigProblem is a single configuration problem
+type ConfigProblem struct {
+	// Key env key of the field, or the field path if the field has no env tag
+	Key string
+
+	Message string
+}
+
+// Error error interface
+func (problem *ConfigProblem) Error() string {
+	return problem.Key + ": " + problem.Message
+}
+
+// ConfigErrors is returned when the configuration has one or more problems, it
+// reports all problems at once
+type ConfigErrors []*ConfigProblem
+
+// Error error interface
+func (errs ConfigErrors) Error() string {
+	desc := "invalid configuration:"
+
+	for _, err := range errs {
+		desc += "\n  " + err.Error()
+	}
+
+	return desc
+}
+
+/*
+	Interpolation
+*/
+
+var interpolationRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Interpolate replaces ${VAR} with the value of VAR returned by lookup and
+// ${VAR:-default} with the default when VAR is not set or empty. Unknown vars
+// without default are replaced with an empty string
+func Interpolate(s string, lookup func(string) (string, bool)) string {
+	return interpolationRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		groups := interpolationRegexp.FindStringSubmatch(match)
+
+		value, ok := lookup(groups[1])
+		if (!ok || value == "") && groups[2] != "" {
+			return groups[3]
+		}
+
+		return value
+	})
+}
+
+// LoadDotEnvFile reads a .env file like ReadDotEnvFile and interpolates the values,
+// vars are looked up in the file first and in the environment second. The vars
+// are added to the environment, existing environment vars are not overwritten so
+// the environment of the process takes precedence over the file
+func LoadDotEnvFile(filePath string) (map[string]string, error) {
+	raw, err := ReadDotEnvFile(filePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]string{}
+
+	var lookup func(string) (string, bool)
+	resolving := map[string]bool{}
+
+	lookup = func(key string) (string, bool) {
+		if value, ok := m[key]; ok {
+			return value, true
+		}
+
+		value, ok := raw[key]
+		if !ok || resolving[key] {
+			return os.LookupEnv(key)
+		}
+
+		resolving[key] = true
+		m[key] = Interpolate(value, lookup)
+		delete(resolving, key)
+
+		return m[key], true
+	}
+
+	for key := range raw {
+		lookup(key)
+	}
+
+	for k, v := range m {
+		if _, exists := os.LookupEnv(k); exists {
+			continue
+		}
+
+		err = os.Setenv(k, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+/*
+	Schema validation
+*/
+
+// ValidateEnv fills a schema struct from the environment and validates it. Fields
+// declare their env var with the env tag, defaults with the default tag and rules
+// with the validate tag of the structural package, e.g.:
+//
+//	type Config struct {
+//		Port     int           `env:"PORT" default:"8080" validate:"min=1,max=65535"`
+//		LogLevel string        `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+//		DSN      string        `env:"DATABASE_DSN" validate:"required"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"30s"`
+//		Hosts    []string      `env:"HOSTS"`
+//	}
+//
+// Values are parsed to the field type with structural.SetValueFromString, slices
+// are given as a comma separated list. Nested structs are visited, their env keys
+// are prefixed with the envPrefix tag. Defaults are applied to zero fields before
+// the env vars are read, so an env var can set a zero value (e.g. DEBUG=false) and
+// env vars override values already in the schema. Use LoadJSONConfig to combine a
+// JSON config with env vars, the zero values of the file are kept as well. Call it at
+// startup, if the configuration has problems a ConfigErrors value with all problems
+// is returned
+func ValidateEnv(schema interface{}) error {
+	err := applySchemaDefaults(schema)
+	if err != nil {
+		return err
+	}
+
+	return validateEnv(schema)
+}
+
+// applySchemaDefaults checks that schema is a struct ptr and applies its defaults
+func applySchemaDefaults(schema interface{}) error {
+	v := reflect.ValueOf(schema)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("schema must be a struct ptr")
+	}
+
+	return structural.ApplyDefaults(schema)
+}
+
+// validateEnv reads the env vars into schema and validates it, defaults must
+// already be applied
+func validateEnv(schema interface{}) error {
+	env := newEnvReader()
+	env.read(reflect.ValueOf(schema).Elem(), "", "")
+
+	return env.validate(schema)
+}
+
+// MustValidateEnv calls ValidateEnv and panics with the report on problems
+func MustValidateEnv(schema interface{}) {
+	err := ValidateEnv(schema)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// envReader reads env vars into a schema and collects the problems
+type envReader struct {
+	// keys maps field paths to env keys
+	keys map[string]string
+
+	// set records the paths of fields with an env var
+	set map[string]bool
+
+	// failed records the paths of values which could not be parsed
+	failed map[string]bool
+
+	problems ConfigErrors
+}
+
+func newEnvReader() *envReader {
+	return &envReader{
+		keys:     map[string]string{},
+		set:      map[string]bool{},
+		failed:   map[string]bool{},
+		problems: ConfigErrors{},
+	}
+}
+
+// read sets the tagged fields of v from the environment
+func (env *envReader) read(v reflect.Value, pathPrefix string, envPrefix string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		path := pathPrefix + field.Name
+		fieldValue := v.Field(i)
+
+		tag, hasTag := field.Tag.Lookup(EnvTag)
+		if !hasTag {
+			if field.Type.Kind() == reflect.Struct {
+				env.read(fieldValue, path+".", envPrefix+field.Tag.Get(EnvPrefixTag))
+			}
+
+			continue
+		}
+
+		key := envPrefix + tag
+		env.keys[path] = key
+
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		env.set[path] = true
+
+		err := setEnvValue(fieldValue, value)
+		if err != nil {
+			env.failed[path] = true
+			env.problems = append(env.problems, &ConfigProblem{
+				Key:     key,
+				Message: "invalid value for " + field.Type.String() + ": " + err.Error(),
+			})
+		}
+	}
+}
+
+// validate validates schema, the validation errors are reported with the env keys
+// of the fields
+func (env *envReader) validate(schema interface{}) error {
+	problems := env.problems
+
+	err := structural.Validate(schema)
+	if err != nil {
+		var validationErrs structural.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return err
+		}
+
+		for _, fieldErr := range validationErrs {
+			if env.failed[fieldErr.Path] {
+				continue
+			}
+
+			key, ok := env.keys[fieldErr.Path]
+			if !ok {
+				key = fieldErr.Key
+			}
+
+			problems = append(problems, &ConfigProblem{Key: key, Message: fieldErr.Message})
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	return nil
+}
+
+// setEnvValue parses s to the type of v, slices are comma separated
+func setEnvValue(v reflect.Value, s string) error {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return structural.SetValueFromString(v, s)
+	}
+
+	components := []string{}
+	if s != "" {
+		components = strings.Split(s, ",")
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(components), len(components))
+
+	for i, component := range components {
+		err := structural.SetValueFromString(slice.Index(i), strings.TrimSpace(component))
+		if err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+
+	return nil
+}
+
+/*
+	JSON config
+*/
+
+// LoadJSONConfig reads a JSON config file into schema and validates it like
+// ValidateEnv. Defaults are applied first, so values of the file (including zero
+// values) override them. ${VAR} references in string values of the file are
+// interpolated from the environment before decoding, env tagged fields are
+// overridden by their env var when it is set
+func LoadJSONConfig(filePath string, schema interface{}) error {
+	err := applySchemaDefaults(schema)
+	if err != nil {
+		return err
+	}
+
+	err = readJSONConfig(filePath, schema)
+	if err != nil {
+		return err
+	}
+
+	return validateEnv(schema)
+}
+
+// LoadConfigLayers loads a configuration from layers in priority order: the JSON
+// files in the given order, followed by the env vars of the env tagged fields. Set
+// fields of a layer override the fields of the layers before it (see
+// structural.Merge), so e.g. config.local.json can override config.json. A field
+// is set by a file if its key is present, also with a zero value like false, and
+// by the env if its env var exists. Missing
+// files are skipped. Defaults are applied before the layers and the schema is
+// validated like ValidateEnv. The returned sources map each field path to the file or "env" layer
+// which set it, fields without source have their default or zero value
+func LoadConfigLayers(schema interface{}, filePaths ...string) (structural.MergeSources, error) {
+	err := applySchemaDefaults(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(schema)
+	sources := structural.MergeSources{}
+
+	for _, filePath := range filePaths {
+		layer := reflect.New(v.Elem().Type())
+
+		raw, err := decodeJSONConfig(filePath, layer.Interface())
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", filePath, err)
+		}
+
+		set := map[string]bool{}
+		jsonPaths(raw, v.Elem().Type(), "", set)
+
+		err = structural.Merge(schema, layer.Interface(), &structural.MergeOptions{Layer: filePath, Sources: sources, Set: set})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	env := newEnvReader()
+	layer := reflect.New(v.Elem().Type())
+	env.read(layer.Elem(), "", "")
+
+	err = structural.Merge(schema, layer.Interface(), &structural.MergeOptions{Layer: "env", Sources: sources, Set: env.set})
+	if err != nil {
+		return nil, err
+	}
+
+	return sources, env.validate(schema)
+}
+
+// readJSONConfig decodes a JSON config file into obj, ${VAR} references in string
+// values are interpolated from the environment. Numbers are kept as json.Number so
+// large integers survive the interpolation round trip
+func readJSONConfig(filePath string, obj interface{}) error {
+	_, err := decodeJSONConfig(filePath, obj)
+	return err
+}
+
+// decodeJSONConfig decodes a JSON config file into obj like readJSONConfig and
+// returns the interpolated raw JSON value
+func decodeJSONConfig(filePath string, obj interface{}) (interface{}, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	raw = interpolateJSON(raw)
+
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw, json.Unmarshal(data, obj)
+}
+
+// jsonPaths adds the field paths of t which are present in the decoded JSON object
+// raw to paths, keys are matched to fields like encoding/json does
+func jsonPaths(raw interface{}, t reflect.Type, prefix string, paths map[string]bool) {
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				jsonPaths(object, embedded, prefix, paths)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		value, found := jsonValue(object, name)
+		if !found {
+			continue
+		}
+
+		path := prefix + field.Name
+		paths[path] = true
+
+		jsonPaths(value, field.Type, path+".", paths)
+	}
+}
+
+// jsonValue returns the value of key in object, keys are matched case insensitively
+// when there is no exact match
+func jsonValue(object map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := object[key]; ok {
+		return value, true
+	}
+
+	for k, value := range object {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// interpolateJSON interpolates all string values of a decoded JSON value
+func interpolateJSON(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return Interpolate(typed, os.LookupEnv)
+	case []interface{}:
+		for i, elem := range typed {
+			typed[i] = interpolateJSON(elem)
+		}
+	case map[string]interface{}:
+		for k, elem := range typed {
+			typed[k] = interpolateJSON(elem)
+		}
+	}
+
+	return value
+}
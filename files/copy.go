@@ -0,0 +1,178 @@
+package files
+
+import (
+	"io"
+	"time"
+)
+
+// copyBufferSize is the buffer size of CopyWithProgress
+const copyBufferSize = 32 * 1024
+
+// CopyWithProgress copies src to dst like io.Copy and calls cb with the number of
+// bytes written at most once per every, and when the copy finished. With an
+// every of 0 cb is called after each write. Wrap src with NewRateLimitedReader to
+// limit the bandwidth of the copy
+func CopyWithProgress(dst io.Writer, src io.Reader, every time.Duration, cb func(written int64)) (int64, error) {
+	buf := make([]byte, copyBufferSize)
+
+	var written, reported int64
+	var err error
+
+	last := time.Now()
+
+	for {
+		nr, readErr := src.Read(buf)
+
+		if nr > 0 {
+			nw, writeErr := dst.Write(buf[:nr])
+			if nw > 0 {
+				written += int64(nw)
+			}
+
+			if writeErr == nil && nw != nr {
+				writeErr = io.ErrShortWrite
+			}
+
+			if writeErr != nil {
+				err = writeErr
+				break
+			}
+
+			if cb != nil && time.Since(last) >= every {
+				last = time.Now()
+				reported = written
+				cb(written)
+			}
+		}
+
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+
+			break
+		}
+	}
+
+	if cb != nil && (written != reported || written == 0) {
+		cb(written)
+	}
+
+	return written, err
+}
+
+/*
+	Rate limiting
+*/
+
+// rateLimiter spreads a number of bytes per second over time
+type rateLimiter struct {
+	bytesPerSecond int64
+	start          time.Time
+	transferred    int64
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+// chunk limits n so a single read or write transfers at most a tenth of a second
+func (limiter *rateLimiter) chunk(n int) int {
+	max := limiter.bytesPerSecond / 10
+	if max < 1 {
+		max = 1
+	}
+
+	if int64(n) > max {
+		return int(max)
+	}
+
+	return n
+}
+
+// wait records n transferred bytes and sleeps until the transfer is within the rate
+func (limiter *rateLimiter) wait(n int) {
+	if limiter.start.IsZero() {
+		limiter.start = time.Now()
+	}
+
+	limiter.transferred += int64(n)
+
+	expected := time.Duration(float64(limiter.transferred) / float64(limiter.bytesPerSecond) * float64(time.Second))
+	if elapsed := time.Since(limiter.start); expected > elapsed {
+		time.Sleep(expected - elapsed)
+	}
+}
+
+// RateLimitedReader limits the number of bytes read per second
+type RateLimitedReader struct {
+	reader  io.Reader
+	limiter *rateLimiter
+}
+
+// NewRateLimitedReader creates a reader which reads from r at most bytesPerSecond,
+// a rate of 0 or less returns r unlimited
+func NewRateLimitedReader(r io.Reader, bytesPerSecond int64) io.Reader {
+	if bytesPerSecond <= 0 {
+		return r
+	}
+
+	return &RateLimitedReader{
+		reader:  r,
+		limiter: newRateLimiter(bytesPerSecond),
+	}
+}
+
+// Read for io.Reader
+func (reader *RateLimitedReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p[:reader.limiter.chunk(len(p))])
+	if n > 0 {
+		reader.limiter.wait(n)
+	}
+
+	return n, err
+}
+
+// RateLimitedWriter limits the number of bytes written per second
+type RateLimitedWriter struct {
+	writer  io.Writer
+	limiter *rateLimiter
+}
+
+// NewRateLimitedWriter creates a writer which writes to w at most bytesPerSecond,
+// e.g. to limit a download written to a response writer. A rate of 0 or less
+// returns w unlimited
+func NewRateLimitedWriter(w io.Writer, bytesPerSecond int64) io.Writer {
+	if bytesPerSecond <= 0 {
+		return w
+	}
+
+	return &RateLimitedWriter{
+		writer:  w,
+		limiter: newRateLimiter(bytesPerSecond),
+	}
+}
+
+// Write for io.Writer, p is written in chunks to spread it over time
+func (writer *RateLimitedWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for written < len(p) {
+		chunk := p[written : written+writer.limiter.chunk(len(p)-written)]
+
+		n, err := writer.writer.Write(chunk)
+		written += n
+
+		if n > 0 {
+			writer.limiter.wait(n)
+		}
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
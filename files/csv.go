@@ -0,0 +1,199 @@
+package files
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+)
+
+// CSVTag is the struct tag used by CSVReader.ReadInto to match columns, fields
+// without tag are matched by name case insensitively
+const CSVTag = "csv"
+
+// CSVReader reads CSV files with a header row, records are returned keyed by column
+// name. Header names are trimmed and lower cased
+type CSVReader struct {
+	Header []string
+
+	reader *csv.Reader
+	line   int
+}
+
+// NewCSVReader creates a reader and reads the header row
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read csv header: %v", err)
+	}
+
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	return &CSVReader{
+		Header: header,
+		reader: reader,
+		line:   1,
+	}, nil
+}
+
+// Line returns the line number of the last read record, the header is line 1
+func (reader *CSVReader) Line() int {
+	return reader.line
+}
+
+// Read returns the next record keyed by column name, values are trimmed. Missing
+// trailing columns are empty, extra columns are ignored. Returns io.EOF at the end
+func (reader *CSVReader) Read() (map[string]string, error) {
+	for {
+		values, err := reader.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		reader.line++
+
+		if isEmptyRecord(values) {
+			continue
+		}
+
+		record := make(map[string]string, len(reader.Header))
+
+		for i, name := range reader.Header {
+			if i < len(values) {
+				record[name] = strings.TrimSpace(values[i])
+			} else {
+				record[name] = ""
+			}
+		}
+
+		return record, nil
+	}
+}
+
+// ReadInto reads the next record into a struct ptr, columns are matched with the csv
+// tag and values are converted to the field types. Fields of empty columns are left
+// untouched. Returns io.EOF at the end
+func (reader *CSVReader) ReadInto(obj interface{}) error {
+	record, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]interface{}, len(record))
+	for name, value := range record {
+		if value != "" {
+			m[name] = value
+		}
+	}
+
+	err = structural.FromMap(m, obj, CSVTag)
+	if err != nil {
+		return fmt.Errorf("line %v: %v", reader.line, err)
+	}
+
+	return nil
+}
+
+// isEmptyRecord checks if all values of a record are empty
+func isEmptyRecord(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CSVWriter writes structs as CSV rows with a header row. Columns are named with the
+// csv tag, nested structs are flattened with structural.FlattenWith to columns like
+// "profile.name"
+type CSVWriter struct {
+	Header []string
+
+	writer *csv.Writer
+}
+
+// NewCSVWriter creates a writer, the header is written with the first row
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{
+		writer: csv.NewWriter(w),
+	}
+}
+
+// Write writes obj as a row, obj must be a struct or struct ptr of the same type as
+// the first written row. Text marshalers are written with MarshalText, nil values
+// as empty string
+func (writer *CSVWriter) Write(obj interface{}) error {
+	pairs, err := structural.FlattenWith(obj, CSVTag, ".")
+	if err != nil {
+		return err
+	}
+
+	if writer.Header == nil {
+		writer.Header = make([]string, len(pairs))
+		for i, pair := range pairs {
+			writer.Header[i] = pair.Key
+		}
+
+		err = writer.writer.Write(writer.Header)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(pairs) != len(writer.Header) {
+		return fmt.Errorf("row has %v columns, header has %v", len(pairs), len(writer.Header))
+	}
+
+	record := make([]string, len(pairs))
+
+	for i, pair := range pairs {
+		record[i], err = csvValue(pair.Value)
+		if err != nil {
+			return fmt.Errorf("column %v: %v", pair.Key, err)
+		}
+	}
+
+	return writer.writer.Write(record)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (writer *CSVWriter) Flush() error {
+	writer.writer.Flush()
+	return writer.writer.Error()
+}
+
+// csvValue formats a value for a CSV column
+func csvValue(value interface{}) (string, error) {
+	v := reflect.ValueOf(value)
+
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+
+	return fmt.Sprint(v.Interface()), nil
+}
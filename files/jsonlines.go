@@ -0,0 +1,251 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MaxJSONLineSize is the maximum size of a single JSON line
+const MaxJSONLineSize = 16 * 1024 * 1024
+
+// JSONLine holds a decoded line and a possible error, like ScanLine. A line which
+// can't be decoded has an error but does not stop the stream
+type JSONLine[T any] struct {
+	Value T
+	Count int
+	Error error
+}
+
+// JSONLinesReader reads newline delimited JSON, gzip compressed input is detected
+// and decompressed transparently. Empty lines are skipped
+type JSONLinesReader[T any] struct {
+	scanner *bufio.Scanner
+	closer  io.Closer
+	line    int
+}
+
+// NewJSONLinesReader creates a reader, r is checked for the gzip header
+func NewJSONLinesReader[T any](r io.Reader) (*JSONLinesReader[T], error) {
+	buffered := bufio.NewReader(r)
+
+	var source io.Reader = buffered
+	var closer io.Closer
+
+	magic, _ := buffered.Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		source = gzipReader
+		closer = gzipReader
+	}
+
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 64*1024), MaxJSONLineSize)
+
+	return &JSONLinesReader[T]{
+		scanner: scanner,
+		closer:  closer,
+	}, nil
+}
+
+// Line returns the line number of the last read value, starting at 1
+func (reader *JSONLinesReader[T]) Line() int {
+	return reader.line
+}
+
+// Read returns the next value. Decode errors contain the line number and reading
+// can continue after them. Returns io.EOF at the end
+func (reader *JSONLinesReader[T]) Read() (T, error) {
+	var value T
+
+	for reader.scanner.Scan() {
+		reader.line++
+
+		line := bytes.TrimSpace(reader.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		err := json.Unmarshal(line, &value)
+		if err != nil {
+			return value, fmt.Errorf("line %d: %v", reader.line, err)
+		}
+
+		return value, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return value, err
+	}
+
+	return value, io.EOF
+}
+
+// Lines streams all values over a channel. Lines which can't be decoded are sent
+// with an error, a read error is sent as last line. The channel is closed at the end
+func (reader *JSONLinesReader[T]) Lines() chan JSONLine[T] {
+	lineChannel := make(chan JSONLine[T])
+
+	go func() {
+		defer close(lineChannel)
+
+		for {
+			value, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+
+			lineChannel <- JSONLine[T]{
+				Value: value,
+				Count: reader.line,
+				Error: err,
+			}
+
+			if err != nil && reader.scanner.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return lineChannel
+}
+
+// Close closes the gzip reader if the input was compressed, the underlying reader
+// is not closed
+func (reader *JSONLinesReader[T]) Close() error {
+	if reader.closer != nil {
+		return reader.closer.Close()
+	}
+
+	return nil
+}
+
+// ScanJSONLinesFile streams the values of a JSON lines file like ScanFile, the file
+// may be gzip compressed
+func ScanJSONLinesFile[T any](filePath string) (chan JSONLine[T], error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewJSONLinesReader[T](file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	lineChannel := make(chan JSONLine[T])
+
+	go func() {
+		defer file.Close()
+		defer reader.Close()
+
+		for line := range reader.Lines() {
+			lineChannel <- line
+		}
+
+		close(lineChannel)
+	}()
+
+	return lineChannel, nil
+}
+
+// JSONLinesWriter writes values as newline delimited JSON, optionally gzip
+// compressed
+type JSONLinesWriter[T any] struct {
+	writer  *bufio.Writer
+	gzip    *gzip.Writer
+	closer  io.Closer
+	encoder *json.Encoder
+}
+
+// NewJSONLinesWriter creates a writer, with compress the output is gzip compressed.
+// Close the writer to flush the output
+func NewJSONLinesWriter[T any](w io.Writer, compress bool) *JSONLinesWriter[T] {
+	writer := &JSONLinesWriter[T]{}
+
+	if compress {
+		writer.gzip = gzip.NewWriter(w)
+		w = writer.gzip
+	}
+
+	writer.writer = bufio.NewWriter(w)
+	writer.encoder = json.NewEncoder(writer.writer)
+	writer.encoder.SetEscapeHTML(false)
+
+	return writer
+}
+
+// CreateJSONLinesFile creates a JSON lines file, the output is gzip compressed when
+// the file path ends with .gz. Close the writer to close the file
+func CreateJSONLinesFile[T any](filePath string) (*JSONLinesWriter[T], error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := NewJSONLinesWriter[T](file, strings.HasSuffix(filePath, ".gz"))
+	writer.closer = file
+
+	return writer, nil
+}
+
+// Write writes a value as a single line
+func (writer *JSONLinesWriter[T]) Write(value T) error {
+	return writer.encoder.Encode(value)
+}
+
+// WriteAll writes all values received from values until the channel is closed
+func (writer *JSONLinesWriter[T]) WriteAll(values <-chan T) error {
+	for value := range values {
+		err := writer.Write(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush flushes buffered lines to the underlying writer
+func (writer *JSONLinesWriter[T]) Flush() error {
+	err := writer.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	if writer.gzip != nil {
+		return writer.gzip.Flush()
+	}
+
+	return nil
+}
+
+// Close flushes the output and closes the gzip stream and the file of
+// CreateJSONLinesFile, other underlying writers are not closed
+func (writer *JSONLinesWriter[T]) Close() error {
+	err := writer.writer.Flush()
+
+	if writer.gzip != nil {
+		if gzipErr := writer.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+
+	if writer.closer != nil {
+		if closeErr := writer.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
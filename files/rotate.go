@@ -0,0 +1,278 @@
+package files
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotationTimeFormat is the timestamp format of rotated file names, it sorts in
+// chronological order
+const rotationTimeFormat = "20060102T150405.000"
+
+// RotatingWriter is an io.WriteCloser which writes to a file and rotates it when it
+// exceeds MaxSize or is older than MaxAge. Rotated files are renamed with a
+// timestamp (app.log becomes app-20060102T150405.000.log), optionally gzipped, and
+// only the newest MaxBackups are kept. It is safe for concurrent writes, use it as
+// sink of a logger, e.g. logging.NewStd(log.New(writer, "", log.LstdFlags), level)
+type RotatingWriter struct {
+	// Path of the active file
+	Path string
+
+	// MaxSize in bytes, 0 disables size based rotation
+	MaxSize int64
+
+	// MaxAge of the active file since it was opened, 0 disables age based rotation
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep, 0 keeps all
+	MaxBackups int
+
+	// Compress gzips rotated files
+	Compress bool
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// cleanup serializes compression and retention of rotated files, which run in
+	// the background
+	cleanup sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewRotatingWriter creates a rotating writer, the file is opened (or created) on
+// the first write and appended to
+func NewRotatingWriter(path string, maxSize int64, maxBackups int) *RotatingWriter {
+	return &RotatingWriter{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
+}
+
+// Write for io.Writer, the file is rotated before p is written if p does not fit
+func (writer *RotatingWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.file == nil {
+		err := writer.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if writer.shouldRotate(int64(len(p))) {
+		err := writer.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := writer.file.Write(p)
+	writer.size += int64(n)
+
+	return n, err
+}
+
+// Rotate rotates the file now, e.g. on SIGHUP
+func (writer *RotatingWriter) Rotate() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.file == nil {
+		err := writer.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.rotate()
+}
+
+// Close closes the active file and waits for background compression to finish
+func (writer *RotatingWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	var err error
+
+	if writer.file != nil {
+		err = writer.file.Close()
+		writer.file = nil
+	}
+
+	writer.wg.Wait()
+
+	return err
+}
+
+// shouldRotate checks if the active file must be rotated before writing n bytes,
+// empty files are never rotated
+func (writer *RotatingWriter) shouldRotate(n int64) bool {
+	if writer.size == 0 {
+		return false
+	}
+
+	if writer.MaxSize > 0 && writer.size+n > writer.MaxSize {
+		return true
+	}
+
+	return writer.MaxAge > 0 && time.Since(writer.openedAt) >= writer.MaxAge
+}
+
+// open opens the active file for appending
+func (writer *RotatingWriter) open() error {
+	err := os.MkdirAll(filepath.Dir(writer.Path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(writer.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.file = file
+	writer.size = info.Size()
+	writer.openedAt = time.Now()
+
+	return nil
+}
+
+// rotate renames the active file, opens a new one and starts the cleanup of rotated
+// files in the background
+func (writer *RotatingWriter) rotate() error {
+	err := writer.file.Close()
+	writer.file = nil
+
+	if err != nil {
+		return err
+	}
+
+	rotated := writer.rotatedName(time.Now())
+
+	err = os.Rename(writer.Path, rotated)
+	if err != nil {
+		return err
+	}
+
+	err = writer.open()
+	if err != nil {
+		return err
+	}
+
+	writer.wg.Add(1)
+
+	go func() {
+		defer writer.wg.Done()
+
+		writer.cleanup.Lock()
+		defer writer.cleanup.Unlock()
+
+		if writer.Compress {
+			// Keep the uncompressed file if compression fails
+			if compressFile(rotated) == nil {
+				os.Remove(rotated)
+			}
+		}
+
+		writer.removeOldBackups()
+	}()
+
+	return nil
+}
+
+// rotatedName returns the name of the active file rotated at t
+func (writer *RotatingWriter) rotatedName(t time.Time) string {
+	ext := filepath.Ext(writer.Path)
+	base := strings.TrimSuffix(writer.Path, ext)
+
+	return base + "-" + t.Format(rotationTimeFormat) + ext
+}
+
+// backups returns the rotated files from old to new
+func (writer *RotatingWriter) backups() []string {
+	ext := filepath.Ext(writer.Path)
+	base := strings.TrimSuffix(writer.Path, ext)
+
+	matches, _ := filepath.Glob(base + "-*" + ext)
+	compressed, _ := filepath.Glob(base + "-*" + ext + ".gz")
+
+	backups := []string{}
+
+	// The globs also match other files like app-error.log, keep only rotated names
+	for _, match := range append(matches, compressed...) {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(match, ".gz"), ext)
+		stamp = strings.TrimPrefix(stamp, base+"-")
+
+		if _, err := time.Parse(rotationTimeFormat, stamp); err == nil {
+			backups = append(backups, match)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+
+	return backups
+}
+
+// removeOldBackups removes the oldest rotated files exceeding MaxBackups
+func (writer *RotatingWriter) removeOldBackups() {
+	if writer.MaxBackups <= 0 {
+		return
+	}
+
+	backups := writer.backups()
+
+	for len(backups) > writer.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// compressFile gzips filePath to filePath.gz
+func compressFile(filePath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	dst, err := os.Create(filePath + ".gz")
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(dst)
+
+	_, err = io.Copy(gzipWriter, src)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(filePath + ".gz")
+	}
+
+	return err
+}
@@ -0,0 +1,240 @@
+package files
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+)
+
+// SniffLength is the number of bytes read by DetectContentType
+const SniffLength = 512
+
+// Content types detected by DetectContentType next to the types of
+// http.DetectContentType
+const (
+	ContentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	ContentTypeOLE  = "application/x-ole-storage"
+	ContentTypeHEIC = "image/heic"
+	ContentTypeSVG  = "image/svg+xml"
+)
+
+var (
+	// ErrFileTooLarge is returned when a file exceeds its size limit
+	ErrFileTooLarge = errorUtils.Validation("file too large").WithCode("file_too_large")
+
+	// ErrContentTypeMismatch is returned when the extension of a file does not
+	// agree with its content
+	ErrContentTypeMismatch = errorUtils.Validation("file extension does not match content").WithCode("content_type_mismatch")
+
+	// ErrContentTypeNotAllowed is returned when the content type of a file is not
+	// in the allowed list
+	ErrContentTypeNotAllowed = errorUtils.Validation("file type not allowed").WithCode("content_type_not_allowed")
+)
+
+// extensionTypes maps extensions to the content types their content may have,
+// office formats are zip files and old office formats are OLE files so those
+// types are accepted as well
+var extensionTypes = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".bmp":  {"image/bmp"},
+	".ico":  {"image/x-icon"},
+	".heic": {ContentTypeHEIC},
+	".svg":  {ContentTypeSVG, "text/xml"},
+	".pdf":  {"application/pdf"},
+	".zip":  {"application/zip"},
+	".gz":   {"application/x-gzip"},
+	".docx": {ContentTypeDOCX, "application/zip"},
+	".xlsx": {ContentTypeXLSX, "application/zip"},
+	".pptx": {ContentTypePPTX, "application/zip"},
+	".doc":  {ContentTypeOLE},
+	".xls":  {ContentTypeOLE},
+	".ppt":  {ContentTypeOLE},
+	".txt":  {"text/plain"},
+	".csv":  {"text/plain"},
+	".json": {"text/plain"},
+	".mp3":  {"audio/mpeg"},
+	".mp4":  {"video/mp4"},
+	".webm": {"video/webm"},
+}
+
+// DetectContentType sniffs the content type of r from its magic bytes. Next to the
+// types of http.DetectContentType office documents (docx, xlsx, pptx and OLE
+// files), heic and svg images are detected. The returned reader replays the
+// sniffed bytes followed by the rest of r, use it to read the full content
+func DetectContentType(r io.Reader) (string, io.Reader, error) {
+	head := make([]byte, SniffLength)
+
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+
+	head = head[:n]
+
+	return detectContentType(head), io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// detectContentType detects the content type of the first bytes of a file
+func detectContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return detectZipType(head)
+	case bytes.HasPrefix(head, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")):
+		return ContentTypeOLE
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && isHEICBrand(string(head[8:12])):
+		return ContentTypeHEIC
+	}
+
+	contentType := http.DetectContentType(head)
+
+	if strings.HasPrefix(contentType, "text/") && bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return ContentTypeSVG
+	}
+
+	return contentType
+}
+
+// detectZipType detects office documents by the names of the first zip entries
+func detectZipType(head []byte) string {
+	switch {
+	case bytes.Contains(head, []byte("word/")):
+		return ContentTypeDOCX
+	case bytes.Contains(head, []byte("xl/")):
+		return ContentTypeXLSX
+	case bytes.Contains(head, []byte("ppt/")):
+		return ContentTypePPTX
+	}
+
+	return "application/zip"
+}
+
+func isHEICBrand(brand string) bool {
+	switch brand {
+	case "heic", "heix", "hevc", "hevx", "mif1", "msf1":
+		return true
+	}
+
+	return false
+}
+
+// ExtensionMatches reports if the extension of filename agrees with contentType.
+// Unknown extensions are looked up with mime.TypeByExtension, extensions without
+// known type do not match
+func ExtensionMatches(filename string, contentType string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	if types, ok := extensionTypes[ext]; ok {
+		for _, t := range types {
+			if baseType(t) == baseType(contentType) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	extType := mime.TypeByExtension(ext)
+	if extType == "" {
+		return false
+	}
+
+	return baseType(extType) == baseType(contentType)
+}
+
+// baseType strips the parameters of a content type
+func baseType(contentType string) string {
+	return strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+}
+
+// LimitedReader reads at most Max bytes from Reader and returns ErrFileTooLarge when
+// the content is larger, unlike io.LimitReader which truncates silently
+type LimitedReader struct {
+	Reader io.Reader
+	Max    int64
+
+	read int64
+}
+
+// NewLimitedReader creates a limited reader
+func NewLimitedReader(r io.Reader, max int64) *LimitedReader {
+	return &LimitedReader{
+		Reader: r,
+		Max:    max,
+	}
+}
+
+// Read for io.Reader
+func (reader *LimitedReader) Read(p []byte) (int, error) {
+	if reader.read > reader.Max {
+		return 0, ErrFileTooLarge
+	}
+
+	// Read one byte past the limit to detect content which is too large
+	if remaining := reader.Max - reader.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := reader.Reader.Read(p)
+	reader.read += int64(n)
+
+	if reader.read > reader.Max {
+		return n - int(reader.read-reader.Max), ErrFileTooLarge
+	}
+
+	return n, err
+}
+
+// CheckUpload sniffs the content type of an uploaded file and checks it: the
+// extension of filename must match the content, the type must be one of allowed
+// (all types are allowed when empty, wildcards like "image/*" do not allow svg) and the size may not exceed maxSize (no
+// limit when 0 or less). The returned reader replays the full content and returns
+// ErrFileTooLarge when the limit is exceeded while reading
+func CheckUpload(r io.Reader, filename string, maxSize int64, allowed ...string) (string, io.Reader, error) {
+	if maxSize > 0 {
+		r = NewLimitedReader(r, maxSize)
+	}
+
+	contentType, r, err := DetectContentType(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(allowed) > 0 && !isAllowed(contentType, allowed) {
+		return contentType, nil, ErrContentTypeNotAllowed
+	}
+
+	if !ExtensionMatches(filename, contentType) {
+		return contentType, nil, ErrContentTypeMismatch
+	}
+
+	return contentType, r, nil
+}
+
+// isAllowed reports if contentType is in allowed, entries like "image/*" allow all
+// subtypes except svg which can contain scripts and must be listed explicitly
+func isAllowed(contentType string, allowed []string) bool {
+	base := baseType(contentType)
+
+	for _, a := range allowed {
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(base, strings.TrimSuffix(a, "*")) && base != ContentTypeSVG {
+			return true
+		}
+
+		if baseType(a) == base {
+			return true
+		}
+	}
+
+	return false
+}
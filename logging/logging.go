@@ -0,0 +1,119 @@
+// Package logging contains a leveled, structured Logger interface used across
+// the packages of this module. Applications control log output by passing their
+// own Logger or replacing the default with SetDefault
+package logging
+
+import (
+	"strings"
+	"sync"
+)
+
+// Level of a log message
+type Level int
+
+// Log levels
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String representation of level
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+
+	return "UNKNOWN"
+}
+
+// ParseLevel parses a level name (debug, info, warn or error), unknown names
+// return LevelInfo and false
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	}
+
+	return LevelInfo, false
+}
+
+// Logger is a leveled, structured logger. Key values are given as alternating
+// keys and values, e.g. logger.Info("query", "sql", query, "duration", d)
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+
+	// With returns a logger which adds keyvals to every message
+	With(keyvals ...interface{}) Logger
+}
+
+var (
+	defaultMutex  sync.RWMutex
+	defaultLogger Logger = NewStd(nil, LevelInfo)
+)
+
+// Default returns the default logger, used by packages when no logger is given
+func Default() Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger, nil sets the no-op logger
+func SetDefault(logger Logger) {
+	if logger == nil {
+		logger = Nop()
+	}
+
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultLogger = logger
+}
+
+// OrDefault returns logger or the default logger if logger is nil
+func OrDefault(logger Logger) Logger {
+	if logger == nil {
+		return Default()
+	}
+
+	return logger
+}
+
+/*
+	No-op logger
+*/
+
+type nopLogger struct{}
+
+// Nop returns a logger which discards all messages
+func Nop() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (nopLogger) Info(msg string, keyvals ...interface{})  {}
+func (nopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (nopLogger) Error(msg string, keyvals ...interface{}) {}
+
+func (logger nopLogger) With(keyvals ...interface{}) Logger {
+	return logger
+}
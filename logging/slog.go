@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger adapts a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlog creates a logger backed by a *slog.Logger, if logger is nil
+// slog.Default() is used
+func NewSlog(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &slogLogger{logger: logger}
+}
+
+func (logger *slogLogger) Debug(msg string, keyvals ...interface{}) {
+	logger.logger.Log(context.Background(), slog.LevelDebug, msg, keyvals...)
+}
+
+func (logger *slogLogger) Info(msg string, keyvals ...interface{}) {
+	logger.logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+func (logger *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	logger.logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+func (logger *slogLogger) Error(msg string, keyvals ...interface{}) {
+	logger.logger.Log(context.Background(), slog.LevelError, msg, keyvals...)
+}
+
+func (logger *slogLogger) With(keyvals ...interface{}) Logger {
+	return &slogLogger{logger: logger.logger.With(keyvals...)}
+}
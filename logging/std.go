@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stdLogger writes messages as "LEVEL msg key=value ..." lines to a *log.Logger
+type stdLogger struct {
+	logger  *log.Logger
+	level   Level
+	keyvals []interface{}
+}
+
+// NewStd creates a logger writing to a stdlib logger, messages below level are
+// discarded. If logger is nil a logger writing to stderr is used
+func NewStd(logger *log.Logger, level Level) Logger {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return &stdLogger{
+		logger: logger,
+		level:  level,
+	}
+}
+
+func (logger *stdLogger) Debug(msg string, keyvals ...interface{}) {
+	logger.log(LevelDebug, msg, keyvals)
+}
+
+func (logger *stdLogger) Info(msg string, keyvals ...interface{}) {
+	logger.log(LevelInfo, msg, keyvals)
+}
+
+func (logger *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	logger.log(LevelWarn, msg, keyvals)
+}
+
+func (logger *stdLogger) Error(msg string, keyvals ...interface{}) {
+	logger.log(LevelError, msg, keyvals)
+}
+
+func (logger *stdLogger) With(keyvals ...interface{}) Logger {
+	combined := make([]interface{}, 0, len(logger.keyvals)+len(keyvals))
+	combined = append(combined, logger.keyvals...)
+	combined = append(combined, keyvals...)
+
+	return &stdLogger{
+		logger:  logger.logger,
+		level:   logger.level,
+		keyvals: combined,
+	}
+}
+
+func (logger *stdLogger) log(level Level, msg string, keyvals []interface{}) {
+	if level < logger.level {
+		return
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString(level.String())
+	builder.WriteRune(' ')
+	builder.WriteString(msg)
+
+	writeKeyvals(&builder, logger.keyvals)
+	writeKeyvals(&builder, keyvals)
+
+	logger.logger.Print(builder.String())
+}
+
+func writeKeyvals(builder *strings.Builder, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "MISSING"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		builder.WriteRune(' ')
+		builder.WriteString(fmt.Sprint(keyvals[i]))
+		builder.WriteRune('=')
+
+		s := fmt.Sprint(value)
+		if strings.ContainsAny(s, " \t\n\"=") {
+			s = strconv.Quote(s)
+		}
+
+		builder.WriteString(s)
+	}
+}
@@ -0,0 +1,43 @@
+package structural
+
+import (
+	"reflect"
+	"sync"
+)
+
+// cachedField holds the reflected information of a struct field that is
+// needed during scanning
+type cachedField struct {
+	field      reflect.StructField
+	structType reflect.Type
+	isStruct   bool
+}
+
+// Cache of struct fields keyed by reflect.Type, reflecting on fields is done
+// only once per type
+var fieldCache sync.Map
+
+// cachedFields returns the (cached) fields of a struct type
+func cachedFields(t reflect.Type) []cachedField {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]cachedField)
+	}
+
+	numField := t.NumField()
+	fields := make([]cachedField, numField)
+
+	for i := 0; i < numField; i++ {
+		field := t.Field(i)
+		structType, isStruct := fieldStructType(field.Type)
+
+		fields[i] = cachedField{
+			field:      field,
+			structType: structType,
+			isStruct:   isStruct,
+		}
+	}
+
+	actual, _ := fieldCache.LoadOrStore(t, fields)
+
+	return actual.([]cachedField)
+}
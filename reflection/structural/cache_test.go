@@ -0,0 +1,55 @@
+package structural
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type benchmarkAddress struct {
+	Street  string
+	City    string
+	Country string
+}
+
+type benchmarkUser struct {
+	ID        int64
+	Name      string
+	Email     string
+	Active    bool
+	CreatedAt time.Time
+	Address   benchmarkAddress
+	Tags      []string
+}
+
+func scanAll(b *testing.B, obj interface{}) {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		b.Fatal("no struct descriptor")
+	}
+
+	err := desc.ScanFields(true, true, nil, func(field FieldDescriptor, context interface{}) error {
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkScanFieldsCached(b *testing.B) {
+	obj := &benchmarkUser{}
+
+	for i := 0; i < b.N; i++ {
+		scanAll(b, obj)
+	}
+}
+
+func BenchmarkScanFieldsUncached(b *testing.B) {
+	obj := &benchmarkUser{}
+
+	for i := 0; i < b.N; i++ {
+		fieldCache.Delete(reflect.TypeOf(benchmarkUser{}))
+		fieldCache.Delete(reflect.TypeOf(benchmarkAddress{}))
+		scanAll(b, obj)
+	}
+}
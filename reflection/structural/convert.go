@@ -0,0 +1,227 @@
+package structural
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// SetValueFromString parses a string to the kind of value and sets it. Pointers
+// are allocated when nil, types implementing encoding.TextUnmarshaler are
+// unmarshaled and time.Duration values are parsed with time.ParseDuration
+func SetValueFromString(value reflect.Value, s string) error {
+	if !value.CanSet() {
+		return fmt.Errorf("value of type %v can not be set", value.Type())
+	}
+
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+
+		err := SetValueFromString(elem.Elem(), s)
+		if err != nil {
+			return err
+		}
+
+		value.Set(elem)
+
+		return nil
+	}
+
+	if reflect.PtrTo(value.Type()).Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		value.SetUint(uintValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		value.SetFloat(floatValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		value.SetBool(boolValue)
+	case reflect.String:
+		value.SetString(s)
+	default:
+		return fmt.Errorf("unsupported value type %v", value.Type())
+	}
+
+	return nil
+}
+
+// SetValue sets value from src, coercing src when it is not directly assignable.
+// Strings are parsed with SetValueFromString, numbers are converted between
+// numeric kinds (e.g. float64 from JSON to int) and fail when the number does not
+// fit or has a fraction, maps are set on structs by
+// field name and slices are converted element by element. A nil src sets the
+// zero value
+func SetValue(value reflect.Value, src interface{}) error {
+	if !value.CanSet() {
+		return fmt.Errorf("value of type %v can not be set", value.Type())
+	}
+
+	if src == nil {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	srcValue := reflect.ValueOf(src)
+	srcType := srcValue.Type()
+
+	if srcType.AssignableTo(value.Type()) {
+		value.Set(srcValue)
+		return nil
+	}
+
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+
+		err := SetValue(elem.Elem(), src)
+		if err != nil {
+			return err
+		}
+
+		value.Set(elem)
+
+		return nil
+	}
+
+	if srcValue.Kind() == reflect.String {
+		return SetValueFromString(value, srcValue.String())
+	}
+
+	if isNumberKind(srcValue.Kind()) && isNumberKind(value.Kind()) {
+		return setNumber(value, srcValue)
+	}
+
+	if srcValue.Kind() == reflect.Map && value.Kind() == reflect.Struct {
+		if m, ok := src.(map[string]interface{}); ok {
+			return fromMap(m, value, "")
+		}
+	}
+
+	if srcValue.Kind() == reflect.Slice && value.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(value.Type(), srcValue.Len(), srcValue.Len())
+
+		for i := 0; i < srcValue.Len(); i++ {
+			err := SetValue(slice.Index(i), srcValue.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+		value.Set(slice)
+
+		return nil
+	}
+
+	if srcType.ConvertibleTo(value.Type()) {
+		value.Set(srcValue.Convert(value.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("can't set value of type %v from %v", value.Type(), srcType)
+}
+
+// setNumber converts a number to the numeric type of value, precision loss between
+// floats is accepted but overflow and loss of a fraction or sign return an error
+func setNumber(value reflect.Value, srcValue reflect.Value) error {
+	converted := srcValue.Convert(value.Type())
+	lossy := false
+
+	switch {
+	case isFloatKind(value.Kind()):
+		lossy = math.IsInf(converted.Float(), 0) && !(isFloatKind(srcValue.Kind()) && math.IsInf(srcValue.Float(), 0))
+	case isUintKind(value.Kind()) && isIntKind(srcValue.Kind()):
+		lossy = srcValue.Int() < 0
+	case isUintKind(value.Kind()) && isFloatKind(srcValue.Kind()):
+		lossy = srcValue.Float() < 0
+	case isIntKind(value.Kind()) && isUintKind(srcValue.Kind()):
+		lossy = converted.Int() < 0
+	}
+
+	// Converting back reveals truncation and wrap around
+	if !lossy && !isFloatKind(value.Kind()) {
+		lossy = converted.Convert(srcValue.Type()).Interface() != srcValue.Interface()
+	}
+
+	if lossy {
+		return fmt.Errorf("can't set value of type %v from %v without loss", value.Type(), srcValue.Interface())
+	}
+
+	value.Set(converted)
+
+	return nil
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+
+	return false
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
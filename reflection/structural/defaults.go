@@ -0,0 +1,81 @@
+package structural
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// DefaultTag is the tag read by ApplyDefaults
+const DefaultTag = "default"
+
+// ApplyDefaults sets zero valued exported fields to the value of their default
+// tag, the tag value is parsed to the kind of the field with SetValueFromString.
+// Slice defaults are given as a comma separated list (default:"a,b,c"). Embedded
+// and nested structs are visited as well, nil struct pointers are left untouched
+func ApplyDefaults(obj interface{}) error {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return errors.New("object is not a struct or struct ptr")
+	}
+
+	if !desc.CanSet() {
+		return errors.New("object fields can not be set")
+	}
+
+	return applyDefaults(desc.Value())
+}
+
+func applyDefaults(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		tag, hasDefault := field.Tag.Lookup(DefaultTag)
+
+		if hasDefault && fieldValue.IsZero() {
+			err := setDefault(fieldValue, tag)
+			if err != nil {
+				return fieldError(field.Name, err)
+			}
+
+			continue
+		}
+
+		if isNestedStruct(field.Type) {
+			if elem, ok := structValue(fieldValue); ok {
+				err := applyDefaults(elem)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func setDefault(v reflect.Value, tag string) error {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return SetValueFromString(v, tag)
+	}
+
+	components := strings.Split(tag, ",")
+	slice := reflect.MakeSlice(v.Type(), len(components), len(components))
+
+	for i, component := range components {
+		err := SetValueFromString(slice.Index(i), strings.TrimSpace(component))
+		if err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+
+	return nil
+}
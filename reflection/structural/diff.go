@@ -0,0 +1,147 @@
+package structural
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// DiffTag is the tag used to exclude fields from Diff, fields tagged with
+// diff:"-" are skipped
+const DiffTag = "diff"
+
+// FieldChange describes a changed field. Path is the dotted path of the field,
+// fields of embedded structs are not prefixed with the embedded struct name
+type FieldChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Diff compares the exported fields of two structs of the same type and returns
+// the fields that changed. Embedded structs are flattened, nested structs with
+// exported fields are compared field by field with a dotted path (e.g. "Profile.Name").
+// Fields tagged with diff:"-" are excluded
+func Diff(old interface{}, new interface{}) ([]FieldChange, error) {
+	oldDesc, ok := NewStructDescriptor(old)
+	if !ok {
+		return nil, errors.New("old is not a struct or struct ptr")
+	}
+
+	newDesc, ok := NewStructDescriptor(new)
+	if !ok {
+		return nil, errors.New("new is not a struct or struct ptr")
+	}
+
+	if oldDesc.Type() != newDesc.Type() {
+		return nil, errors.New("can't diff structs of different types")
+	}
+
+	changes := []FieldChange{}
+
+	diffStruct(oldDesc.Value(), newDesc.Value(), "", &changes)
+
+	return changes, nil
+}
+
+// diffStruct compares struct values field by field
+func diffStruct(oldValue reflect.Value, newValue reflect.Value, prefix string, changes *[]FieldChange) {
+	t := oldValue.Type()
+	numField := t.NumField()
+
+	for i := 0; i < numField; i++ {
+		field := t.Field(i)
+
+		// Skip unexported and excluded fields
+		if field.PkgPath != "" || field.Tag.Get(DiffTag) == "-" {
+			continue
+		}
+
+		oldField := oldValue.Field(i)
+		newField := newValue.Field(i)
+
+		if field.Anonymous {
+			oldElem, oldOk := structValue(oldField)
+			newElem, newOk := structValue(newField)
+
+			if oldOk && newOk {
+				diffStruct(oldElem, newElem, prefix, changes)
+				continue
+			}
+		}
+
+		path := prefix + field.Name
+
+		if isNestedStruct(field.Type) {
+			oldElem, oldOk := structValue(oldField)
+			newElem, newOk := structValue(newField)
+
+			if oldOk && newOk {
+				diffStruct(oldElem, newElem, path+".", changes)
+				continue
+			}
+		}
+
+		if !valuesEqual(oldField, newField) {
+			*changes = append(*changes, FieldChange{
+				Path: path,
+				Old:  oldField.Interface(),
+				New:  newField.Interface(),
+			})
+		}
+	}
+}
+
+// structValue returns the struct value of a struct or non nil struct ptr value
+func structValue(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	return v, true
+}
+
+// isNestedStruct true if type is a struct or struct ptr with exported fields,
+// structs with only unexported fields (e.g. time.Time) and structs implementing
+// encoding.TextMarshaler (e.g. time.DateOnly) are treated as a single value
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// valuesEqual compares two values, time values (and types with time.Time as
+// underlying type) are compared with time.Equal
+func valuesEqual(a reflect.Value, b reflect.Value) bool {
+	if a.Type().ConvertibleTo(timeType) && a.Kind() == reflect.Struct {
+		return a.Convert(timeType).Interface().(time.Time).Equal(b.Convert(timeType).Interface().(time.Time))
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,258 @@
+package structural
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DumpOptions configures DumpWith
+type DumpOptions struct {
+	// MaxDepth maximum depth of nested values, deeper values are printed as {...}.
+	// A value <= 0 means no limit
+	MaxDepth int
+
+	// Indent is used per level of nesting
+	Indent string
+
+	// RedactTag fields tagged with RedactTag:"true" are printed redacted, see Redact
+	RedactTag string
+}
+
+// DefaultDumpOptions are the options used by Dump
+var DefaultDumpOptions = DumpOptions{
+	MaxDepth:  10,
+	Indent:    "  ",
+	RedactTag: RedactTag,
+}
+
+// Dump pretty prints obj with DefaultDumpOptions in a Go like notation, e.g. for
+// debug endpoints or CLI output:
+//
+//	&User{
+//	  Name: "john",
+//	  Password: "***",
+//	  Roles: []string{"admin"},
+//	}
+//
+// Redacted fields are never printed, pointers which are already being printed
+// higher up are printed as <cycle *T>, map keys are sorted
+func Dump(obj interface{}) string {
+	return DumpWith(obj, DefaultDumpOptions)
+}
+
+// DumpWith pretty prints obj like Dump with the given options
+func DumpWith(obj interface{}, options DumpOptions) string {
+	if options.RedactTag == "" {
+		options.RedactTag = RedactTag
+	}
+
+	d := &dumper{
+		options:  options,
+		visiting: map[uintptr]bool{},
+	}
+
+	d.dump(reflect.ValueOf(obj), 0)
+
+	return d.builder.String()
+}
+
+// dumper writes a single dump
+type dumper struct {
+	options  DumpOptions
+	builder  strings.Builder
+	visiting map[uintptr]bool
+}
+
+func (d *dumper) write(s string) {
+	d.builder.WriteString(s)
+}
+
+func (d *dumper) newline(depth int) {
+	d.write("\n")
+	d.write(strings.Repeat(d.options.Indent, depth))
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.write("nil")
+		return
+	}
+
+	if s, ok := dumpScalar(v); ok {
+		d.write(s)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.write("nil")
+			return
+		}
+
+		if d.visiting[v.Pointer()] {
+			d.write("<cycle " + v.Type().String() + ">")
+			return
+		}
+
+		d.visiting[v.Pointer()] = true
+		defer delete(d.visiting, v.Pointer())
+
+		d.write("&")
+		d.dump(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			d.write("nil")
+			return
+		}
+
+		d.dump(v.Elem(), depth)
+	case reflect.Struct:
+		d.dumpStruct(v, depth)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			d.write("nil")
+			return
+		}
+
+		d.write(v.Type().String() + "{")
+
+		if v.Len() == 0 {
+			d.write("}")
+			return
+		}
+
+		if d.maxDepth(depth) {
+			d.write("...}")
+			return
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			d.newline(depth + 1)
+			d.dump(v.Index(i), depth+1)
+			d.write(",")
+		}
+
+		d.newline(depth)
+		d.write("}")
+	case reflect.Map:
+		if v.IsNil() {
+			d.write("nil")
+			return
+		}
+
+		d.write(v.Type().String() + "{")
+
+		if v.Len() == 0 {
+			d.write("}")
+			return
+		}
+
+		if d.maxDepth(depth) {
+			d.write("...}")
+			return
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, key := range keys {
+			d.newline(depth + 1)
+			d.dump(key, depth+1)
+			d.write(": ")
+			d.dump(v.MapIndex(key), depth+1)
+			d.write(",")
+		}
+
+		d.newline(depth)
+		d.write("}")
+	default:
+		d.write(v.Type().String())
+	}
+}
+
+func (d *dumper) dumpStruct(v reflect.Value, depth int) {
+	t := v.Type()
+
+	d.write(t.String() + "{")
+
+	if d.maxDepth(depth) {
+		d.write("...}")
+		return
+	}
+
+	written := false
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		d.newline(depth + 1)
+		d.write(field.Name + ": ")
+
+		fieldValue := v.Field(i)
+
+		if redact, _ := strconv.ParseBool(field.Tag.Get(d.options.RedactTag)); redact {
+			if fieldValue.IsZero() {
+				d.write(`""`)
+			} else {
+				d.write(strconv.Quote(RedactedString))
+			}
+		} else {
+			d.dump(fieldValue, depth+1)
+		}
+
+		d.write(",")
+
+		written = true
+	}
+
+	if written {
+		d.newline(depth)
+	}
+
+	d.write("}")
+}
+
+func (d *dumper) maxDepth(depth int) bool {
+	return d.options.MaxDepth > 0 && depth >= d.options.MaxDepth
+}
+
+// dumpScalar formats values which are printed on a single line: basic kinds, byte
+// slices and types implementing encoding.TextMarshaler or error (e.g. time.Time)
+func dumpScalar(v reflect.Value) (string, bool) {
+	if v.CanInterface() && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		switch typed := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			text, err := typed.MarshalText()
+			if err == nil {
+				return strconv.Quote(string(text)), true
+			}
+		case error:
+			return strconv.Quote(typed.Error()), true
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String()), true
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Interface()), v.CanInterface()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && !v.IsNil() {
+			return fmt.Sprintf("%q", v.Bytes()), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,203 @@
+package structural
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// FlattenTag is the tag used by Flatten to name keys
+const FlattenTag = "json"
+
+// KeyValue is a flattened key with its value
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// Flatten converts a struct to key value pairs in field order, nested structs are
+// flattened with their keys joined by sep (e.g. "profile.name"). Keys are taken
+// from the json tag, fields tagged with "-" are excluded. See FlattenWith
+func Flatten(obj interface{}, sep string) ([]KeyValue, error) {
+	return FlattenWith(obj, FlattenTag, sep)
+}
+
+// FlattenWith flattens a struct like Flatten with keys taken from tagName (see
+// TagKey). Embedded structs are not prefixed. The fields of nil nested struct
+// pointers are included with nil values, so all structs of a type flatten to the
+// same keys (e.g. the columns of a CSV export)
+func FlattenWith(obj interface{}, tagName string, sep string) ([]KeyValue, error) {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return nil, errors.New("object is not a struct or struct ptr")
+	}
+
+	f := newFlattener(tagName, sep)
+	f.flatten(desc.Value(), desc.Type(), "")
+
+	return f.pairs, nil
+}
+
+// FlattenKeys returns the keys Flatten would produce for type t without a value
+func FlattenKeys(t reflect.Type, tagName string, sep string) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	f := newFlattener(tagName, sep)
+	f.flatten(reflect.Value{}, t, "")
+
+	keys := make([]string, len(f.pairs))
+	for i, pair := range f.pairs {
+		keys[i] = pair.Key
+	}
+
+	return keys
+}
+
+// flattener collects the leaf fields of a struct
+type flattener struct {
+	tagName string
+	sep     string
+	pairs   []KeyValue
+
+	// excludeTag excludes fields tagged with "-", next to the fields excluded by
+	// tagName
+	excludeTag string
+
+	// redactTag redacts fields tagged with redactTag:"true" like Redact, the fields of
+	// redacted nested structs are included with nil values
+	redactTag string
+
+	visiting map[reflect.Type]int
+}
+
+func newFlattener(tagName string, sep string) *flattener {
+	return &flattener{
+		tagName:  tagName,
+		sep:      sep,
+		pairs:    []KeyValue{},
+		visiting: map[reflect.Type]int{},
+	}
+}
+
+// flatten appends the leaf fields of struct type t, v is the struct value or
+// invalid if the struct is nil. Recursive types are not expanded below a nil
+// pointer to prevent endless recursion
+func (f *flattener) flatten(v reflect.Value, t reflect.Type, prefix string) {
+	if !v.IsValid() && f.visiting[t] > 0 {
+		return
+	}
+
+	f.visiting[t]++
+	defer func() { f.visiting[t]-- }()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, skip := TagKey(field, f.tagName)
+		if skip || (f.excludeTag != "" && field.Tag.Get(f.excludeTag) == "-") {
+			continue
+		}
+
+		var fieldValue reflect.Value
+		if v.IsValid() {
+			fieldValue = v.Field(i)
+		}
+
+		redacted := false
+		if f.redactTag != "" {
+			redacted, _ = strconv.ParseBool(field.Tag.Get(f.redactTag))
+		}
+
+		if isNestedStruct(field.Type) {
+			elemType := field.Type
+			if elemType.Kind() == reflect.Ptr {
+				elemType = elemType.Elem()
+			}
+
+			var elem reflect.Value
+			if !redacted {
+				elem, _ = structValue(fieldValue)
+			}
+
+			nestedPrefix := prefix + name + f.sep
+			if field.Anonymous && field.Tag.Get(f.tagName) == "" {
+				nestedPrefix = prefix
+			}
+
+			f.flatten(elem, elemType, nestedPrefix)
+
+			continue
+		}
+
+		var value interface{}
+		if fieldValue.IsValid() {
+			value = fieldValue.Interface()
+
+			if redacted {
+				value = redactedValue(fieldValue).Interface()
+			}
+		}
+
+		f.pairs = append(f.pairs, KeyValue{
+			Key:   prefix + name,
+			Value: value,
+		})
+	}
+}
+
+// ExpandChanges expands changes of whole nested structs, which Diff reports when a
+// struct pointer is set or cleared, into changes of their leaf fields with dotted
+// paths. Fields tagged with diff:"-" are excluded, other changes are kept as is
+func ExpandChanges(changes []FieldChange) []FieldChange {
+	expanded := make([]FieldChange, 0, len(changes))
+
+	for _, change := range changes {
+		oldValue := reflect.ValueOf(change.Old)
+		newValue := reflect.ValueOf(change.New)
+
+		if !oldValue.IsValid() || !newValue.IsValid() || oldValue.Type() != newValue.Type() || !isNestedStruct(oldValue.Type()) {
+			expanded = append(expanded, change)
+			continue
+		}
+
+		t := oldValue.Type()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		before := flattenChangeValue(oldValue, t, change.Path+".")
+		after := flattenChangeValue(newValue, t, change.Path+".")
+
+		for i, oldPair := range before {
+			newPair := after[i]
+
+			if reflect.DeepEqual(oldPair.Value, newPair.Value) {
+				continue
+			}
+
+			expanded = append(expanded, FieldChange{
+				Path: oldPair.Key,
+				Old:  oldPair.Value,
+				New:  newPair.Value,
+			})
+		}
+	}
+
+	return expanded
+}
+
+// flattenChangeValue flattens a changed struct value for ExpandChanges
+func flattenChangeValue(v reflect.Value, t reflect.Type, prefix string) []KeyValue {
+	f := newFlattener("", ".")
+	f.excludeTag = DiffTag
+
+	elem, _ := structValue(v)
+	f.flatten(elem, t, prefix)
+
+	return f.pairs
+}
@@ -0,0 +1,227 @@
+package structural
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// InterfaceType returns the type of interface T, e.g. InterfaceType[fmt.Stringer]()
+func InterfaceType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// implementsKey is the cache key of a type and interface pair
+type implementsKey struct {
+	t     reflect.Type
+	iface reflect.Type
+}
+
+// implementsCache caches TypeImplements results
+var implementsCache sync.Map
+
+// TypeImplements checks if t implements interface iface, results are cached. False
+// is returned if iface is not an interface type
+func TypeImplements(t reflect.Type, iface reflect.Type) bool {
+	if iface.Kind() != reflect.Interface {
+		return false
+	}
+
+	key := implementsKey{t: t, iface: iface}
+
+	if implements, ok := implementsCache.Load(key); ok {
+		return implements.(bool)
+	}
+
+	implements := t.Implements(iface)
+	implementsCache.Store(key, implements)
+
+	return implements
+}
+
+// Implements returns obj as T if obj implements interface T, false if T is not an
+// interface type
+func Implements[T any](obj interface{}) (T, bool) {
+	var zero T
+
+	if obj == nil || !TypeImplements(reflect.TypeOf(obj), InterfaceType[T]()) {
+		return zero, false
+	}
+
+	return obj.(T), true
+}
+
+/*
+	Capability probing
+*/
+
+// Capabilities is the set of interfaces of a Probe implemented by a type, bit i is
+// set when the type implements the i-th interface of the probe
+type Capabilities uint64
+
+// Has checks if the interface at index is implemented
+func (capabilities Capabilities) Has(index int) bool {
+	return capabilities&(1<<uint(index)) != 0
+}
+
+// Any checks if at least one interface is implemented
+func (capabilities Capabilities) Any() bool {
+	return capabilities != 0
+}
+
+// Probe checks types against a fixed list of interfaces at once and caches the
+// result per type, so dispatch on optional interfaces (e.g. hooks) costs a single
+// map lookup per call
+type Probe struct {
+	interfaces []reflect.Type
+	cache      sync.Map
+}
+
+// NewProbe creates a probe for at most 64 interfaces, the index of an interface in
+// the list is its index in Capabilities
+func NewProbe(interfaces ...reflect.Type) *Probe {
+	if len(interfaces) > 64 {
+		panic("a probe supports at most 64 interfaces")
+	}
+
+	for _, iface := range interfaces {
+		if iface.Kind() != reflect.Interface {
+			panic(fmt.Sprintf("%v is not an interface", iface))
+		}
+	}
+
+	return &Probe{
+		interfaces: interfaces,
+	}
+}
+
+// Capabilities returns the interfaces of the probe implemented by obj
+func (probe *Probe) Capabilities(obj interface{}) Capabilities {
+	if obj == nil {
+		return 0
+	}
+
+	return probe.TypeCapabilities(reflect.TypeOf(obj))
+}
+
+// TypeCapabilities returns the interfaces of the probe implemented by t
+func (probe *Probe) TypeCapabilities(t reflect.Type) Capabilities {
+	if capabilities, ok := probe.cache.Load(t); ok {
+		return capabilities.(Capabilities)
+	}
+
+	var capabilities Capabilities
+
+	for i, iface := range probe.interfaces {
+		if t.Implements(iface) {
+			capabilities |= 1 << uint(i)
+		}
+	}
+
+	probe.cache.Store(t, capabilities)
+
+	return capabilities
+}
+
+/*
+	Dynamic calls
+*/
+
+// methodKey is the cache key of a method lookup
+type methodKey struct {
+	t    reflect.Type
+	name string
+}
+
+// methodCache caches method indexes by type and name, -1 if the type has no such
+// method
+var methodCache sync.Map
+
+// methodIndex returns the index of the exported method name of t
+func methodIndex(t reflect.Type, name string) int {
+	key := methodKey{t: t, name: name}
+
+	if index, ok := methodCache.Load(key); ok {
+		return index.(int)
+	}
+
+	index := -1
+	if method, ok := t.MethodByName(name); ok {
+		index = method.Index
+	}
+
+	methodCache.Store(key, index)
+
+	return index
+}
+
+// CallIfImplements calls the exported method of obj with args if obj has it, called
+// reports if the method exists. Nil args are passed as zero values. If the last
+// result of the method is an error it is returned, other results are discarded.
+// Arguments which don't match the method signature return an error without
+// calling the method
+func CallIfImplements(obj interface{}, method string, args ...interface{}) (called bool, err error) {
+	if obj == nil {
+		return false, nil
+	}
+
+	v := reflect.ValueOf(obj)
+
+	index := methodIndex(v.Type(), method)
+	if index < 0 {
+		return false, nil
+	}
+
+	m := v.Method(index)
+	methodType := m.Type()
+
+	if methodType.IsVariadic() {
+		return false, fmt.Errorf("variadic method %v is not supported", method)
+	}
+
+	if methodType.NumIn() != len(args) {
+		return false, fmt.Errorf("method %v expects %v arguments, got %v", method, methodType.NumIn(), len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+
+	for i, arg := range args {
+		inType := methodType.In(i)
+
+		if arg == nil {
+			in[i] = reflect.Zero(inType)
+			continue
+		}
+
+		argValue := reflect.ValueOf(arg)
+		if !argValue.Type().AssignableTo(inType) {
+			return false, fmt.Errorf("argument %v of method %v must be %v, got %v", i, method, inType, argValue.Type())
+		}
+
+		in[i] = argValue
+	}
+
+	out := m.Call(in)
+
+	if len(out) > 0 {
+		last := out[len(out)-1]
+		if last.Type() == errorType && !last.IsNil() {
+			return true, last.Interface().(error)
+		}
+	}
+
+	return true, nil
+}
+
+// MustImplement returns obj as T and panics if obj does not implement T, use it to
+// check compliance of registered implementations at startup
+func MustImplement[T any](obj interface{}) T {
+	implementation, ok := Implements[T](obj)
+	if !ok {
+		panic(fmt.Sprintf("%T does not implement %v", obj, InterfaceType[T]()))
+	}
+
+	return implementation
+}
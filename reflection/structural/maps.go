@@ -0,0 +1,218 @@
+package structural
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TagKey returns the key for a field based on the given tag (e.g. db, json or
+// param). The first comma separated component of the tag is used, if the tag
+// is empty or tagName is empty the field name is used. If the tag is "-" skip is true
+func TagKey(field reflect.StructField, tagName string) (key string, skip bool) {
+	if tagName == "" {
+		return field.Name, false
+	}
+
+	tag := field.Tag.Get(tagName)
+	if tag == "-" {
+		return "", true
+	}
+
+	key = strings.SplitN(tag, ",", 2)[0]
+	if key == "" {
+		key = field.Name
+	}
+
+	return key, false
+}
+
+// ToMap converts the exported fields of a struct to a map, keys are taken from
+// the given tag (see TagKey). Embedded structs are flattened into the map, nested
+// structs with exported fields are converted to nested maps
+func ToMap(obj interface{}, tagName string) (map[string]interface{}, error) {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return nil, errors.New("object is not a struct or struct ptr")
+	}
+
+	m := map[string]interface{}{}
+
+	toMap(desc.Value(), tagName, m)
+
+	return m, nil
+}
+
+// ToFlatMap converts a struct to a map like ToMap, but nested structs are flattened
+// with their keys joined by sep (e.g. "profile.name")
+func ToFlatMap(obj interface{}, tagName string, sep string) (map[string]interface{}, error) {
+	m, err := ToMap(obj, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	flat := map[string]interface{}{}
+
+	flattenMap(m, "", sep, flat)
+
+	return flat, nil
+}
+
+func toMap(v reflect.Value, tagName string, m map[string]interface{}) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key, skip := TagKey(field, tagName)
+		if skip {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+
+		if field.Anonymous && field.Tag.Get(tagName) == "" {
+			if elem, ok := structValue(fieldValue); ok {
+				toMap(elem, tagName, m)
+				continue
+			}
+		}
+
+		if isNestedStruct(field.Type) {
+			if elem, ok := structValue(fieldValue); ok {
+				nested := map[string]interface{}{}
+				toMap(elem, tagName, nested)
+				m[key] = nested
+				continue
+			}
+		}
+
+		m[key] = fieldValue.Interface()
+	}
+}
+
+func flattenMap(m map[string]interface{}, prefix string, sep string, flat map[string]interface{}) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenMap(nested, prefix+k+sep, sep, flat)
+			continue
+		}
+
+		flat[prefix+k] = v
+	}
+}
+
+// FromMap sets the exported fields of a struct ptr from a map, keys are matched
+// with the given tag (see TagKey), falling back to a case insensitive match. Values
+// are coerced to the field type with SetValue, nested maps are set on nested
+// structs. Fields without a matching key are left untouched
+func FromMap(m map[string]interface{}, obj interface{}, tagName string) error {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return errors.New("object is not a struct or struct ptr")
+	}
+
+	if !desc.CanSet() {
+		return errors.New("object fields can not be set")
+	}
+
+	return fromMap(m, desc.Value(), tagName)
+}
+
+func lookupKey(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func fromMap(m map[string]interface{}, v reflect.Value, tagName string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key, skip := TagKey(field, tagName)
+		if skip {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+
+		if field.Anonymous && field.Tag.Get(tagName) == "" && isNestedStruct(field.Type) {
+			elem, err := allocStruct(fieldValue)
+			if err != nil {
+				return err
+			}
+
+			err = fromMap(m, elem, tagName)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		src, ok := lookupKey(m, key)
+		if !ok {
+			continue
+		}
+
+		if nested, ok := src.(map[string]interface{}); ok && isNestedStruct(field.Type) {
+			elem, err := allocStruct(fieldValue)
+			if err != nil {
+				return err
+			}
+
+			err = fromMap(nested, elem, tagName)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := SetValue(fieldValue, src)
+		if err != nil {
+			return fieldError(field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// allocStruct returns the struct value of a struct or struct ptr field, nil
+// pointers are allocated
+func allocStruct(v reflect.Value) (reflect.Value, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return reflect.Value{}, errors.New("nil struct ptr can not be set")
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return v.Elem(), nil
+	}
+
+	return v, nil
+}
+
+func fieldError(name string, err error) error {
+	return fmt.Errorf("%v: %v", name, err)
+}
@@ -0,0 +1,146 @@
+package structural
+
+import (
+	"errors"
+	"reflect"
+)
+
+// SliceStrategy determines how Merge combines slices
+type SliceStrategy int
+
+const (
+	// SliceReplace replaces the base slice with the overlay slice
+	SliceReplace SliceStrategy = iota
+
+	// SliceAppend appends the overlay slice to the base slice
+	SliceAppend
+)
+
+// MergeSources maps the dotted field paths set by Merge to the layer they came from
+type MergeSources map[string]string
+
+// MergeOptions configures Merge
+type MergeOptions struct {
+	// Slices is the strategy for slices
+	Slices SliceStrategy
+
+	// ReplaceMaps replaces base maps with overlay maps instead of merging the keys
+	ReplaceMaps bool
+
+	// Layer is the name of the overlay recorded in Sources, e.g. a file name or "env"
+	Layer string
+
+	// Sources records the layer of each field set by the overlay, can be nil
+	Sources MergeSources
+
+	// Set holds the paths of fields the overlay sets explicitly, they are merged even
+	// if they are zero (e.g. a false read from a config file). Can be nil
+	Set map[string]bool
+}
+
+// Merge overlays the set fields of overlay onto base, both must be of the same
+// struct type and base must be a pointer. Merge a list of layers in priority order
+// (e.g. defaults, config file, env) to build the final configuration:
+//   - zero fields of overlay are skipped unless their path is in Set, non-nil
+//     pointers are set even if they point to a zero value
+//   - nested structs and struct pointers are merged field by field, struct
+//     pointers of base are replaced with a merged copy
+//   - slices are replaced or appended, see SliceStrategy
+//   - maps are merged key by key into a new map unless ReplaceMaps is set
+//
+// Options can be nil. Paths use Go field names like Diff
+func Merge(base interface{}, overlay interface{}, options *MergeOptions) error {
+	baseValue := reflect.ValueOf(base)
+	if baseValue.Kind() != reflect.Ptr || baseValue.IsNil() || baseValue.Elem().Kind() != reflect.Struct {
+		return errors.New("base must be a struct ptr")
+	}
+
+	overlayDesc, ok := NewStructDescriptor(overlay)
+	if !ok {
+		return errors.New("overlay is not a struct or struct ptr")
+	}
+
+	if overlayDesc.Type() != baseValue.Elem().Type() {
+		return errors.New("can't merge structs of different types")
+	}
+
+	if options == nil {
+		options = &MergeOptions{}
+	}
+
+	mergeStruct(baseValue.Elem(), overlayDesc.Value(), "", options)
+
+	return nil
+}
+
+func mergeStruct(base reflect.Value, overlay reflect.Value, prefix string, options *MergeOptions) {
+	t := base.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		baseField := base.Field(i)
+		overlayField := overlay.Field(i)
+
+		path := prefix + field.Name
+		nestedPrefix := path + "."
+
+		if field.Anonymous {
+			nestedPrefix = prefix
+		}
+
+		if isNestedStruct(field.Type) {
+			if field.Type.Kind() == reflect.Struct {
+				mergeStruct(baseField, overlayField, nestedPrefix, options)
+				continue
+			}
+
+			if overlayField.IsNil() {
+				continue
+			}
+
+			// Merge into a copy, base may share the pointer with another struct
+			merged := reflect.New(field.Type.Elem())
+			if !baseField.IsNil() {
+				merged.Elem().Set(baseField.Elem())
+			}
+
+			mergeStruct(merged.Elem(), overlayField.Elem(), nestedPrefix, options)
+			baseField.Set(merged)
+
+			continue
+		}
+
+		if !isSet(overlayField) && !options.Set[path] {
+			continue
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.Slice && options.Slices == SliceAppend && !baseField.IsNil():
+			appended := reflect.MakeSlice(field.Type, 0, baseField.Len()+overlayField.Len())
+			appended = reflect.AppendSlice(appended, baseField)
+			baseField.Set(reflect.AppendSlice(appended, overlayField))
+		case field.Type.Kind() == reflect.Map && !options.ReplaceMaps && !baseField.IsNil():
+			merged := reflect.MakeMapWithSize(field.Type, baseField.Len()+overlayField.Len())
+
+			for _, key := range baseField.MapKeys() {
+				merged.SetMapIndex(key, baseField.MapIndex(key))
+			}
+
+			for _, key := range overlayField.MapKeys() {
+				merged.SetMapIndex(key, overlayField.MapIndex(key))
+			}
+
+			baseField.Set(merged)
+		default:
+			baseField.Set(overlayField)
+		}
+
+		if options.Sources != nil {
+			options.Sources[path] = options.Layer
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package structural
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GetPath returns the value at a dotted field path (e.g. "Profile.Avatar" or
+// "Items.0.Name"). Path components are struct field names (fields of embedded
+// structs are promoted), slice or array indices, or string map keys
+func GetPath(obj interface{}, path string) (interface{}, error) {
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() {
+		return nil, errors.New("object is nil")
+	}
+
+	for _, component := range splitPath(path) {
+		next, err := pathComponent(v, component, false)
+		if err != nil {
+			return nil, fmt.Errorf("path %v: %v", path, err)
+		}
+
+		v = next
+	}
+
+	return v.Interface(), nil
+}
+
+// SetPath sets the value at a dotted field path, see GetPath for the path format.
+// The object must be a pointer, nil pointers along the path are allocated and the
+// value is coerced to the field type with SetValue
+func SetPath(obj interface{}, path string, value interface{}) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("object must be a non nil pointer")
+	}
+
+	components := splitPath(path)
+	if len(components) == 0 {
+		return errors.New("empty path")
+	}
+
+	last := len(components) - 1
+
+	for _, component := range components[:last] {
+		next, err := pathComponent(v, component, true)
+		if err != nil {
+			return fmt.Errorf("path %v: %v", path, err)
+		}
+
+		v = next
+	}
+
+	v = indirect(v, true)
+
+	// Map values are not addressable, set a converted copy on the map
+	if v.Kind() == reflect.Map {
+		if v.IsNil() {
+			return fmt.Errorf("path %v: nil map", path)
+		}
+
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("path %v: only maps with string keys are supported", path)
+		}
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+
+		err := SetValue(elem, value)
+		if err != nil {
+			return fmt.Errorf("path %v: %v", path, err)
+		}
+
+		v.SetMapIndex(reflect.ValueOf(components[last]).Convert(v.Type().Key()), elem)
+
+		return nil
+	}
+
+	target, err := pathComponent(v, components[last], true)
+	if err != nil {
+		return fmt.Errorf("path %v: %v", path, err)
+	}
+
+	err = SetValue(target, value)
+	if err != nil {
+		return fmt.Errorf("path %v: %v", path, err)
+	}
+
+	return nil
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return []string{}
+	}
+
+	return strings.Split(path, ".")
+}
+
+// indirect follows pointers and interfaces, if alloc is true nil pointers are allocated
+func indirect(v reflect.Value, alloc bool) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if !alloc || v.Kind() != reflect.Ptr || !v.CanSet() {
+				return v
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// pathComponent resolves one path component on value v
+func pathComponent(v reflect.Value, component string, alloc bool) (reflect.Value, error) {
+	v = indirect(v, alloc)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := v.Type().FieldByName(component)
+		if !ok || field.PkgPath != "" {
+			return reflect.Value{}, fmt.Errorf("unknown field %v", component)
+		}
+
+		fieldValue, err := v.FieldByIndexErr(field.Index)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return fieldValue, nil
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(component)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid index %v", component)
+		}
+
+		if index < 0 || index >= v.Len() {
+			return reflect.Value{}, fmt.Errorf("index %v out of range", index)
+		}
+
+		return v.Index(index), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, errors.New("only maps with string keys are supported")
+		}
+
+		value := v.MapIndex(reflect.ValueOf(component).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return reflect.Value{}, fmt.Errorf("unknown key %v", component)
+		}
+
+		return value, nil
+	case reflect.Ptr, reflect.Interface:
+		return reflect.Value{}, fmt.Errorf("nil value at %v", component)
+	}
+
+	return reflect.Value{}, fmt.Errorf("can't resolve %v on value of kind %v", component, v.Kind())
+}
@@ -0,0 +1,240 @@
+package structural
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RedactTag is the default tag used by Redact
+	RedactTag = "redact"
+
+	// RedactedString replaces the value of redacted non empty string fields
+	RedactedString = "***"
+)
+
+// Redact returns a copy of obj where exported fields tagged with tag:"true" (e.g.
+// redact:"true", if tag is empty RedactTag is used) are redacted. Non empty strings
+// are replaced with RedactedString, other values are zeroed. Nested structs, pointers,
+// slices, arrays and maps are copied so the original object is never modified, shared
+// and cyclic references are copied once. obj can be any value, a pointer returns a
+// pointer to the copy
+func Redact(obj interface{}, tag string) interface{} {
+	if obj == nil {
+		return nil
+	}
+
+	if tag == "" {
+		tag = RedactTag
+	}
+
+	r := &redactor{
+		tag:    tag,
+		copies: map[redactRef]reflect.Value{},
+	}
+
+	return r.redact(reflect.ValueOf(obj)).Interface()
+}
+
+// redactRef identifies a pointer, slice or map that is already copied
+type redactRef struct {
+	pointer uintptr
+	t       reflect.Type
+	length  int
+}
+
+// redactor makes a single redacted copy
+type redactor struct {
+	tag    string
+	copies map[redactRef]reflect.Value
+}
+
+func (r *redactor) redact(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		ref := redactRef{pointer: v.Pointer(), t: v.Type()}
+		if copied, ok := r.copies[ref]; ok {
+			return copied
+		}
+
+		copied := reflect.New(v.Type().Elem())
+		r.copies[ref] = copied
+		copied.Elem().Set(r.redact(v.Elem()))
+
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(r.redact(v.Elem()))
+
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			fieldValue := copied.Field(i)
+
+			if redact, _ := strconv.ParseBool(field.Tag.Get(r.tag)); redact {
+				fieldValue.Set(redactedValue(fieldValue))
+				continue
+			}
+
+			fieldValue.Set(r.redact(fieldValue))
+		}
+
+		return copied
+	case reflect.Array:
+		if !containsStruct(v.Type().Elem()) {
+			return v
+		}
+
+		copied := reflect.New(v.Type()).Elem()
+
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(r.redact(v.Index(i)))
+		}
+
+		return copied
+	case reflect.Slice:
+		if v.IsNil() || !containsStruct(v.Type().Elem()) {
+			return v
+		}
+
+		ref := redactRef{pointer: v.Pointer(), t: v.Type(), length: v.Len()}
+		if copied, ok := r.copies[ref]; ok {
+			return copied
+		}
+
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		r.copies[ref] = copied
+
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(r.redact(v.Index(i)))
+		}
+
+		return copied
+	case reflect.Map:
+		if v.IsNil() || !containsStruct(v.Type().Elem()) {
+			return v
+		}
+
+		ref := redactRef{pointer: v.Pointer(), t: v.Type()}
+		if copied, ok := r.copies[ref]; ok {
+			return copied
+		}
+
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		r.copies[ref] = copied
+
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), r.redact(iter.Value()))
+		}
+
+		return copied
+	}
+
+	return v
+}
+
+// redactedValue returns RedactedString for non empty strings and the zero value for
+// other values
+func redactedValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.String && v.Len() > 0 {
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.SetString(RedactedString)
+
+		return redacted
+	}
+
+	return reflect.Zero(v.Type())
+}
+
+// containsStruct true if values of type t can contain struct fields that need redaction
+func containsStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Interface
+}
+
+// RedactMap returns a copy of m where the values of keys (case insensitive) are
+// redacted like Redact does, e.g. for decoded JSON bodies. Nested maps and slices
+// are copied and redacted as well
+func RedactMap(m map[string]interface{}, keys ...string) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	return redactMap(m, redactKeys(keys))
+}
+
+// RedactJSON redacts a decoded JSON value like RedactMap, values which are not a map
+// or slice are returned as is
+func RedactJSON(value interface{}, keys ...string) interface{} {
+	return redactAny(value, redactKeys(keys))
+}
+
+func redactKeys(keys []string) map[string]bool {
+	lookup := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		lookup[strings.ToLower(key)] = true
+	}
+
+	return lookup
+}
+
+func redactMap(m map[string]interface{}, keys map[string]bool) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+
+	for key, value := range m {
+		if keys[strings.ToLower(key)] {
+			if s, ok := value.(string); ok && s == "" {
+				copied[key] = s
+			} else if ok {
+				copied[key] = RedactedString
+			} else {
+				copied[key] = nil
+			}
+
+			continue
+		}
+
+		copied[key] = redactAny(value, keys)
+	}
+
+	return copied
+}
+
+func redactAny(value interface{}, keys map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactMap(v, keys)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = redactAny(elem, keys)
+		}
+
+		return copied
+	}
+
+	return value
+}
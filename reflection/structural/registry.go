@@ -0,0 +1,67 @@
+package structural
+
+import (
+	"reflect"
+	"sync"
+)
+
+// TypeInfo is metadata registered for a custom type, consumers like the sql model
+// package consult the registry instead of checking package paths
+type TypeInfo struct {
+	// SQLType is the SQL column type used for fields of this type
+	SQLType string
+
+	// Zero is the value considered empty for this type, if nil the reflect zero
+	// value is used
+	Zero interface{}
+
+	// Validator validates a value of this type, can be nil
+	Validator func(value interface{}) error
+}
+
+var (
+	typeRegistryMutex sync.RWMutex
+	typeRegistry      = map[reflect.Type]*TypeInfo{}
+)
+
+// RegisterType registers metadata for a type, registering a type twice replaces
+// the previous metadata
+func RegisterType(t reflect.Type, info *TypeInfo) {
+	typeRegistryMutex.Lock()
+	defer typeRegistryMutex.Unlock()
+
+	typeRegistry[t] = info
+}
+
+// RegisterTypeOf registers metadata for the type of obj
+func RegisterTypeOf(obj interface{}, info *TypeInfo) {
+	RegisterType(reflect.TypeOf(obj), info)
+}
+
+// LookupType returns the registered metadata of a type, pointer types fall back
+// to the metadata of their element type
+func LookupType(t reflect.Type) (*TypeInfo, bool) {
+	typeRegistryMutex.RLock()
+	defer typeRegistryMutex.RUnlock()
+
+	if info, ok := typeRegistry[t]; ok {
+		return info, true
+	}
+
+	if t.Kind() == reflect.Ptr {
+		info, ok := typeRegistry[t.Elem()]
+		return info, ok
+	}
+
+	return nil, false
+}
+
+// IsZeroValue checks if v is the zero value of its type, the registered Zero
+// value is used when available
+func IsZeroValue(v reflect.Value) bool {
+	if info, ok := LookupType(v.Type()); ok && info.Zero != nil {
+		return reflect.DeepEqual(v.Interface(), info.Zero)
+	}
+
+	return v.IsZero()
+}
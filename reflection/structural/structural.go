@@ -17,9 +17,27 @@ import (
 
 // ScanFunction defines a function that can be given to ScanFields method of
 // StructDescriptor objects. The scan function receives a field descriptor for
-// each field
+// each field, the path of the field is available with FieldDescriptor.Path
 type ScanFunction func(field FieldDescriptor, context interface{}) error
 
+// ScanOptions control which fields are visited by ScanFieldsWithOptions
+type ScanOptions struct {
+	// Exportable only visit exported fields
+	Exportable bool
+
+	// Embedded scan the fields of embedded structs or struct ptrs instead of
+	// passing the embedded field itself to the scan function
+	Embedded bool
+
+	// Nested visit the fields of non embedded struct or struct ptr fields after
+	// the field itself is passed to the scan function
+	Nested bool
+
+	// MaxDepth maximum depth of embedded or nested structs to scan, fields of the
+	// scanned struct itself have depth 0. A value < 0 means no limit
+	MaxDepth int
+}
+
 /*
  *
  *  Interfaces
@@ -72,6 +90,14 @@ type FieldDescriptor interface {
 	// StructDescriptor create a StructDescriptor, returns an error when
 	// field is not of struct or struct ptr kind
 	StructDescriptor() (StructDescriptor, error)
+
+	// Path dotted path of the field relative to the scanned struct, fields of
+	// embedded structs are not prefixed with the embedded struct name
+	Path() string
+
+	// Depth number of embedded or nested structs between the scanned struct
+	// and this field
+	Depth() int
 }
 
 // StructDescriptor describes a struct
@@ -86,6 +112,12 @@ type StructDescriptor interface {
 	// well.
 	ScanFields(exportable bool, embedded bool, context interface{}, scanFunction ScanFunction) error
 
+	// ScanFieldsWithOptions scan fields of struct descriptor with a scan function
+	// and scan options. Recursion stops at MaxDepth and at struct types that are
+	// already being scanned, so self referencing structs do not loop forever. Such
+	// fields are passed to the scan function as is
+	ScanFieldsWithOptions(options ScanOptions, context interface{}, scanFunction ScanFunction) error
+
 	// FieldByName get field descriptor by name
 	FieldByName(name string) (FieldDescriptor, bool)
 }
@@ -98,7 +130,9 @@ type StructDescriptor interface {
 
 type fieldDescriptorImp struct {
 	reflect.StructField
-	V reflect.Value
+	V     reflect.Value
+	path  string
+	depth int
 }
 
 func (desc *fieldDescriptorImp) Type() reflect.Type {
@@ -145,6 +179,18 @@ func (desc *fieldDescriptorImp) Field() reflect.StructField {
 	return desc.StructField
 }
 
+func (desc *fieldDescriptorImp) Path() string {
+	if desc.path == "" {
+		return desc.StructField.Name
+	}
+
+	return desc.path
+}
+
+func (desc *fieldDescriptorImp) Depth() int {
+	return desc.depth
+}
+
 func (desc *fieldDescriptorImp) StructDescriptor() (StructDescriptor, error) {
 	fieldType := desc.StructField.Type
 	fieldKind := fieldType.Kind()
@@ -165,7 +211,7 @@ func (desc *fieldDescriptorImp) StructDescriptor() (StructDescriptor, error) {
 		if elem.Kind() == reflect.Struct {
 			return &structDescriptorImp{
 				T: elem,
-				V: desc.V.Elem(),
+				V: elemValue(desc.V, elem),
 			}, nil
 		}
 	}
@@ -232,39 +278,77 @@ func (desc *structDescriptorImp) FieldByName(name string) (FieldDescriptor, bool
 }
 
 func (desc *structDescriptorImp) ScanFields(exportable bool, embedded bool, context interface{}, scanFunction ScanFunction) error {
-	// Get number of fields
-	numField := desc.T.NumField()
+	return desc.ScanFieldsWithOptions(ScanOptions{
+		Exportable: exportable,
+		Embedded:   embedded,
+		MaxDepth:   -1,
+	}, context, scanFunction)
+}
+
+func (desc *structDescriptorImp) ScanFieldsWithOptions(options ScanOptions, context interface{}, scanFunction ScanFunction) error {
+	return desc.scanFields(options, "", 0, map[reflect.Type]bool{}, context, scanFunction)
+}
 
-	// Loop through fields
-	for i := 0; i < numField; i++ {
+func (desc *structDescriptorImp) scanFields(options ScanOptions, prefix string, depth int, visiting map[reflect.Type]bool, context interface{}, scanFunction ScanFunction) error {
+	// Mark this struct type as being scanned to detect cycles
+	visiting[desc.T] = true
+	defer delete(visiting, desc.T)
+
+	// Loop through (cached) fields
+	for i, cached := range cachedFields(desc.T) {
 		fieldDesc := &fieldDescriptorImp{
-			StructField: desc.T.Field(i),
-			V:           desc.V.Field(i),
+			StructField: cached.field,
+			V:           fieldValue(desc.V, i),
+			path:        prefix + cached.field.Name,
+			depth:       depth,
 		}
 
 		// Check if we need to scan this field, if exportable is true and
 		// the field is not exportable, we do not scan it
-		if !exportable || (exportable && fieldDesc.IsExportable()) {
-			// Check if we want to scan embedded fields and if the field is
-			// actually embedded
-			if embedded && fieldDesc.Anonymous() {
-				// Create embedded structure descriptor
-				edesc, err := fieldDesc.StructDescriptor()
-				if err != nil {
-					return err
-				}
-
-				// Scan embedded descriptor fields
-				err = edesc.ScanFields(exportable, embedded, context, scanFunction)
-				if err != nil {
-					return err
-				}
-			} else {
-				// Pass field descriptor to the scan function
-				err := scanFunction(fieldDesc, context)
-				if err != nil {
-					return err
-				}
+		if options.Exportable && !fieldDesc.IsExportable() {
+			continue
+		}
+
+		// Check if we can recurse into the struct type of this field
+		canRecurse := options.MaxDepth < 0 || depth < options.MaxDepth
+		if !cached.isStruct || visiting[cached.structType] {
+			canRecurse = false
+		}
+
+		// Check if we want to scan embedded fields and if the field is
+		// actually embedded
+		if options.Embedded && fieldDesc.Anonymous() && canRecurse {
+			// Create embedded structure descriptor
+			edesc, err := fieldDesc.StructDescriptor()
+			if err != nil {
+				return err
+			}
+
+			// Scan embedded descriptor fields, embedded fields keep the prefix
+			err = edesc.(*structDescriptorImp).scanFields(options, prefix, depth+1, visiting, context, scanFunction)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// Pass field descriptor to the scan function
+		err := scanFunction(fieldDesc, context)
+		if err != nil {
+			return err
+		}
+
+		// Scan nested struct fields
+		if options.Nested && !fieldDesc.Anonymous() && canRecurse {
+			ndesc, err := fieldDesc.StructDescriptor()
+			if err != nil {
+				return err
+			}
+
+			err = ndesc.(*structDescriptorImp).scanFields(options, fieldDesc.path+".", depth+1, visiting, context, scanFunction)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -272,6 +356,35 @@ func (desc *structDescriptorImp) ScanFields(exportable bool, embedded bool, cont
 	return nil
 }
 
+// fieldStructType returns the struct type of a struct or struct ptr type
+func fieldStructType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t, t.Kind() == reflect.Struct
+}
+
+// fieldValue returns the value of field i, or an invalid value if the struct
+// value itself is invalid
+func fieldValue(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+
+	return v.Field(i)
+}
+
+// elemValue returns the element of a ptr value, a nil ptr returns the zero
+// value of the element type (which can not be set)
+func elemValue(v reflect.Value, elem reflect.Type) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Zero(elem)
+	}
+
+	return v.Elem()
+}
+
 /*
  *
  *  Non interface functions
@@ -305,7 +418,7 @@ func NewStructDescriptor(obj interface{}) (StructDescriptor, bool) {
 		// Check if elem kind is struct
 		if elem.Kind() == reflect.Struct {
 			desc.T = elem
-			desc.V = reflect.ValueOf(obj).Elem()
+			desc.V = elemValue(reflect.ValueOf(obj), elem)
 			return desc, true
 		}
 	}
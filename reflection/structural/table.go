@@ -0,0 +1,131 @@
+package structural
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableMaxCellWidth is the maximum width of a table cell, longer values are
+// truncated with "..."
+var TableMaxCellWidth = 40
+
+// WriteTable writes a slice of structs or struct ptrs as a plain text table with
+// aligned columns, e.g. for CLI output:
+//
+//	id  name  profile.email
+//	--  ----  -------------
+//	1   john  john@example.com
+//
+// Columns are the keys of FlattenWith with tagName (if empty FlattenTag is used),
+// fields tagged with redact:"true" are printed redacted like Redact does. Nil
+// elements are skipped, nil values are printed empty
+func WriteTable(w io.Writer, slice interface{}, tagName string) error {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return errors.New("table must be a slice of structs")
+	}
+
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return errors.New("table must be a slice of structs")
+	}
+
+	if tagName == "" {
+		tagName = FlattenTag
+	}
+
+	keys := FlattenKeys(elemType, tagName, ".")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	separators := make([]string, len(keys))
+	for i, key := range keys {
+		separators[i] = strings.Repeat("-", len(key))
+	}
+
+	writeTableRow(tw, keys)
+	writeTableRow(tw, separators)
+
+	for i := 0; i < v.Len(); i++ {
+		elem, ok := structValue(v.Index(i))
+		if !ok {
+			continue
+		}
+
+		f := newFlattener(tagName, ".")
+		f.redactTag = RedactTag
+		f.flatten(elem, elemType, "")
+
+		cells := make([]string, len(f.pairs))
+		for j, pair := range f.pairs {
+			cells[j] = tableCell(pair.Value)
+		}
+
+		writeTableRow(tw, cells)
+	}
+
+	return tw.Flush()
+}
+
+// FormatTable formats a slice of structs as a table like WriteTable
+func FormatTable(slice interface{}, tagName string) (string, error) {
+	builder := strings.Builder{}
+
+	err := WriteTable(&builder, slice, tagName)
+	if err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+func writeTableRow(w io.Writer, cells []string) {
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
+
+// tableCell formats a value as a single line cell
+func tableCell(value interface{}) string {
+	v := reflect.ValueOf(value)
+
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return ""
+	}
+
+	var s string
+
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return ""
+		}
+
+		s = string(text)
+	} else {
+		s = fmt.Sprint(v.Interface())
+	}
+
+	s = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+
+	if TableMaxCellWidth > 3 && len([]rune(s)) > TableMaxCellWidth {
+		s = string([]rune(s)[:TableMaxCellWidth-3]) + "..."
+	}
+
+	return s
+}
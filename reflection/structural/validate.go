@@ -0,0 +1,564 @@
+package structural
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ValidateTag is the tag read by Validate
+	ValidateTag = "validate"
+
+	// ValidationKeyTag is the tag used to derive the key of a field error, so
+	// errors can be reported with the names clients use
+	ValidationKeyTag = "json"
+)
+
+// FieldError is a validation error for a single field
+type FieldError struct {
+	// Path dotted path of the field with Go field names
+	Path string
+
+	// Key dotted path of the field with names taken from ValidationKeyTag
+	Key string
+
+	// Rule that failed and its parameter
+	Rule  string
+	Param string
+
+	// Message human readable message
+	Message string
+}
+
+// Error error interface
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("%v %v", err.Key, err.Message)
+}
+
+// ValidationErrors is returned by Validate when one or more fields are invalid
+type ValidationErrors []*FieldError
+
+// Error error interface
+func (errs ValidationErrors) Error() string {
+	desc := ""
+
+	for _, err := range errs {
+		desc += err.Error() + "\n"
+	}
+
+	return desc
+}
+
+// ValidationContext is passed to validator functions
+type ValidationContext struct {
+	// Value of the field
+	Value reflect.Value
+
+	// Param rule parameter, e.g. 3 for min=3
+	Param string
+
+	// Parent struct value the field belongs to, used for cross field rules
+	Parent reflect.Value
+
+	// Field struct field
+	Field reflect.StructField
+}
+
+// ValidatorFunc validates a field, returns false if the field is invalid
+type ValidatorFunc func(ctx *ValidationContext) bool
+
+// validator is a registered validator function with an error message format,
+// the message format receives the rule param
+type validator struct {
+	fn      ValidatorFunc
+	message string
+}
+
+var (
+	validatorsMutex sync.RWMutex
+	validators      = map[string]*validator{}
+	regexpCache     sync.Map
+)
+
+// RegisterValidator registers a custom validator under a rule name, message is
+// the error message and can contain a %v verb which is replaced by the rule parameter
+func RegisterValidator(name string, message string, fn ValidatorFunc) {
+	validatorsMutex.Lock()
+	defer validatorsMutex.Unlock()
+
+	validators[name] = &validator{fn: fn, message: message}
+}
+
+func lookupValidator(name string) (*validator, bool) {
+	validatorsMutex.RLock()
+	defer validatorsMutex.RUnlock()
+
+	v, ok := validators[name]
+
+	return v, ok
+}
+
+func init() {
+	RegisterValidator("required", "is required", func(ctx *ValidationContext) bool {
+		return !IsZeroValue(ctx.Value)
+	})
+
+	RegisterValidator("min", "must be at least %v", func(ctx *ValidationContext) bool {
+		result, ok := compareParam(ctx.Value, ctx.Param)
+		return ok && result >= 0
+	})
+
+	RegisterValidator("max", "must be at most %v", func(ctx *ValidationContext) bool {
+		result, ok := compareParam(ctx.Value, ctx.Param)
+		return ok && result <= 0
+	})
+
+	RegisterValidator("len", "must have length %v", func(ctx *ValidationContext) bool {
+		n, err := strconv.Atoi(ctx.Param)
+		l, ok := length(ctx.Value)
+		return err == nil && ok && l == n
+	})
+
+	RegisterValidator("eq", "must be equal to %v", func(ctx *ValidationContext) bool {
+		result, ok := compareParam(ctx.Value, ctx.Param)
+		return ok && result == 0
+	})
+
+	RegisterValidator("ne", "must not be equal to %v", func(ctx *ValidationContext) bool {
+		result, ok := compareParam(ctx.Value, ctx.Param)
+		return ok && result != 0
+	})
+
+	RegisterValidator("oneof", "must be one of %v", func(ctx *ValidationContext) bool {
+		s := fmt.Sprintf("%v", indirect(ctx.Value, false).Interface())
+		for _, option := range strings.Fields(ctx.Param) {
+			if s == option {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	RegisterValidator("email", "must be a valid email address", func(ctx *ValidationContext) bool {
+		s, ok := stringValue(ctx.Value)
+		if !ok {
+			return false
+		}
+
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	})
+
+	RegisterValidator("url", "must be a valid URL", func(ctx *ValidationContext) bool {
+		s, ok := stringValue(ctx.Value)
+		if !ok {
+			return false
+		}
+
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	})
+
+	RegisterValidator("regex", "must match %v", func(ctx *ValidationContext) bool {
+		s, ok := stringValue(ctx.Value)
+		if !ok {
+			return false
+		}
+
+		r, err := cachedRegexp(ctx.Param)
+		return err == nil && r.MatchString(s)
+	})
+
+	RegisterValidator("eqfield", "must be equal to %v", func(ctx *ValidationContext) bool {
+		equal, ok := equalField(ctx)
+		return ok && equal
+	})
+
+	RegisterValidator("nefield", "must not be equal to %v", func(ctx *ValidationContext) bool {
+		equal, ok := equalField(ctx)
+		return ok && !equal
+	})
+
+	RegisterValidator("gtfield", "must be greater than %v", func(ctx *ValidationContext) bool {
+		result, ok := compareField(ctx)
+		return ok && result > 0
+	})
+
+	RegisterValidator("gtefield", "must be greater than or equal to %v", func(ctx *ValidationContext) bool {
+		result, ok := compareField(ctx)
+		return ok && result >= 0
+	})
+
+	RegisterValidator("ltfield", "must be less than %v", func(ctx *ValidationContext) bool {
+		result, ok := compareField(ctx)
+		return ok && result < 0
+	})
+
+	RegisterValidator("ltefield", "must be less than or equal to %v", func(ctx *ValidationContext) bool {
+		result, ok := compareField(ctx)
+		return ok && result <= 0
+	})
+}
+
+// Validate validates the exported fields of a struct with the rules in their
+// validate tag. Rules are comma separated, rules with a parameter use rule=param:
+//   - required: the field must not be the zero value
+//   - omitempty: skip the other rules when the field is the zero value
+//   - min, max, eq, ne: compare numbers by value and strings, slices and maps by length
+//   - len: exact length of strings, slices and maps
+//   - oneof: space separated list of allowed values
+//   - email, url: string format checks
+//   - regex: the string must match the expression, regex must be the last rule
+//     as the expression can contain commas
+//   - eqfield, nefield, gtfield, gtefield, ltfield, ltefield: compare with
+//     another field of the same struct (e.g. eqfield=Password), the ordering
+//     rules fail for fields which are not numbers, strings or times
+//
+// Custom rules can be added with RegisterValidator. Validators registered for a
+// field type in the type registry are run as well. Nested structs and slices
+// of structs are validated recursively. If one or more fields are invalid a
+// ValidationErrors value is returned, other errors indicate invalid rules
+func Validate(obj interface{}) error {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return errors.New("object is not a struct or struct ptr")
+	}
+
+	errs := ValidationErrors{}
+
+	err := validateStruct(desc.Value(), "", "", &errs)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+// parseRules parses a validate tag, regex takes the remainder of the tag
+func parseRules(tag string) []rule {
+	rules := []rule{}
+
+	for tag != "" {
+		var component string
+
+		if strings.HasPrefix(tag, "regex=") {
+			component = tag
+			tag = ""
+		} else {
+			components := strings.SplitN(tag, ",", 2)
+			component = components[0]
+			tag = ""
+
+			if len(components) == 2 {
+				tag = components[1]
+			}
+		}
+
+		component = strings.TrimSpace(component)
+		if component == "" {
+			continue
+		}
+
+		nameAndParam := strings.SplitN(component, "=", 2)
+		r := rule{name: nameAndParam[0]}
+
+		if len(nameAndParam) == 2 {
+			r.param = nameAndParam[1]
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+func validateStruct(v reflect.Value, pathPrefix string, keyPrefix string, errs *ValidationErrors) error {
+	t := v.Type()
+
+	for i, cached := range cachedFields(t) {
+		field := cached.field
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+
+		// Embedded structs are validated with the same prefix
+		if field.Anonymous && field.Tag.Get(ValidateTag) == "" {
+			if elem, ok := structValue(fieldValue); ok {
+				err := validateStruct(elem, pathPrefix, keyPrefix, errs)
+				if err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		key, skip := TagKey(field, ValidationKeyTag)
+		if skip {
+			key = field.Name
+		}
+
+		path := pathPrefix + field.Name
+		key = keyPrefix + key
+
+		err := validateField(fieldValue, v, field, path, key, errs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateField(fieldValue reflect.Value, parent reflect.Value, field reflect.StructField, path string, key string, errs *ValidationErrors) error {
+	rules := parseRules(field.Tag.Get(ValidateTag))
+
+	isZero := IsZeroValue(fieldValue)
+	failed := false
+
+	for _, r := range rules {
+		if r.name == "omitempty" {
+			if isZero {
+				return nil
+			}
+
+			continue
+		}
+
+		if r.name == "-" {
+			return nil
+		}
+
+		validator, ok := lookupValidator(r.name)
+		if !ok {
+			return fmt.Errorf("unknown validation rule %v on field %v", r.name, path)
+		}
+
+		valid := validator.fn(&ValidationContext{
+			Value:  fieldValue,
+			Param:  r.param,
+			Parent: parent,
+			Field:  field,
+		})
+
+		if !valid {
+			*errs = append(*errs, newFieldError(path, key, r, validator.message))
+			failed = true
+
+			// Further rules are meaningless for a missing value
+			if r.name == "required" {
+				break
+			}
+		}
+	}
+
+	if failed {
+		return nil
+	}
+
+	// Validators registered for the field type
+	if info, ok := LookupType(fieldValue.Type()); ok && info.Validator != nil && !isZero {
+		if err := info.Validator(fieldValue.Interface()); err != nil {
+			*errs = append(*errs, &FieldError{
+				Path:    path,
+				Key:     key,
+				Rule:    "type",
+				Message: err.Error(),
+			})
+		}
+
+		return nil
+	}
+
+	// Validate nested structs and slices of structs
+	if elem, ok := structValue(fieldValue); ok && isNestedStruct(elem.Type()) {
+		return validateStruct(elem, path+".", key+".", errs)
+	}
+
+	if fieldValue.Kind() == reflect.Slice || fieldValue.Kind() == reflect.Array {
+		for i := 0; i < fieldValue.Len(); i++ {
+			if elem, ok := structValue(fieldValue.Index(i)); ok && isNestedStruct(elem.Type()) {
+				index := strconv.Itoa(i)
+
+				err := validateStruct(elem, path+"."+index+".", key+"."+index+".", errs)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func newFieldError(path string, key string, r rule, message string) *FieldError {
+	if strings.Contains(message, "%v") {
+		message = fmt.Sprintf(message, r.param)
+	}
+
+	return &FieldError{
+		Path:    path,
+		Key:     key,
+		Rule:    r.name,
+		Param:   r.param,
+		Message: message,
+	}
+}
+
+func cachedRegexp(expr string) (*regexp.Regexp, error) {
+	if r, ok := regexpCache.Load(expr); ok {
+		return r.(*regexp.Regexp), nil
+	}
+
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	regexpCache.Store(expr, r)
+
+	return r, nil
+}
+
+func stringValue(v reflect.Value) (string, bool) {
+	v = indirect(v, false)
+	if v.Kind() != reflect.String {
+		return "", false
+	}
+
+	return v.String(), true
+}
+
+// length returns the length of strings (in runes), slices, arrays and maps
+func length(v reflect.Value) (int, bool) {
+	v = indirect(v, false)
+
+	switch v.Kind() {
+	case reflect.String:
+		return len([]rune(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+
+	return 0, false
+}
+
+// compareParam compares a value with a rule parameter, numbers are compared by
+// value, strings, slices and maps by length
+func compareParam(v reflect.Value, param string) (int, bool) {
+	v = indirect(v, false)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(v.Int(), p), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(v.Uint(), p), true
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(v.Float(), p), true
+	}
+
+	l, ok := length(v)
+	if !ok {
+		return 0, false
+	}
+
+	p, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, false
+	}
+
+	return cmp.Compare(int64(l), int64(p)), true
+}
+
+// otherField returns the field named by the rule parameter and the field itself,
+// false if the named field does not exist or is unexported
+func otherField(ctx *ValidationContext) (reflect.Value, reflect.Value, bool) {
+	field, ok := ctx.Parent.Type().FieldByName(ctx.Param)
+	if !ok || field.PkgPath != "" {
+		return reflect.Value{}, reflect.Value{}, false
+	}
+
+	other := ctx.Parent.FieldByIndex(field.Index)
+
+	a := indirect(ctx.Value, false)
+	b := indirect(other, false)
+
+	return a, b, a.Kind() == b.Kind()
+}
+
+// equalField checks if a field equals the field named by the rule parameter, values
+// of kinds without order are compared deeply
+func equalField(ctx *ValidationContext) (bool, bool) {
+	if result, ok := compareField(ctx); ok {
+		return result == 0, true
+	}
+
+	a, b, ok := otherField(ctx)
+	if !ok || !a.IsValid() || !b.IsValid() {
+		return false, false
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface()), true
+}
+
+// compareField compares a field with the field named by the rule parameter, false if
+// the fields are not numbers, strings or times of the same kind
+func compareField(ctx *ValidationContext) (int, bool) {
+	a, b, ok := otherField(ctx)
+	if !ok || !a.IsValid() {
+		return 0, false
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float()), true
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	}
+
+	if a.Type() == b.Type() && a.Type().ConvertibleTo(timeType) && a.Kind() == reflect.Struct {
+		return a.Convert(timeType).Interface().(time.Time).Compare(b.Convert(timeType).Interface().(time.Time)), true
+	}
+
+	return 0, false
+}
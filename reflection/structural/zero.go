@@ -0,0 +1,183 @@
+package structural
+
+import (
+	"errors"
+	"reflect"
+)
+
+// IsZero checks if obj is deeply zero: nil, a pointer to a zero value, an empty
+// slice or map, or a struct of which all fields are zero. Registered types are
+// checked with their registered Zero value, structs implementing
+// encoding.TextMarshaler (e.g. time.Time) are checked as a whole
+func IsZero(obj interface{}) bool {
+	return isZeroDeep(reflect.ValueOf(obj))
+}
+
+func isZeroDeep(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+
+	if _, ok := LookupType(v.Type()); ok {
+		return IsZeroValue(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || isZeroDeep(v.Elem())
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Struct:
+		if !isNestedStruct(v.Type()) {
+			return v.IsZero()
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			if !isZeroDeep(v.Field(i)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return v.IsZero()
+}
+
+// isSet checks if a field value was provided: non-nil pointers are set even if they
+// point to a zero value, so pointer fields can carry explicit zero values (e.g. a
+// *bool set to false). Other values are set when they are not zero
+func isSet(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return !v.IsNil()
+	}
+
+	return !IsZeroValue(v)
+}
+
+// NonZeroFields returns the dotted paths of the set fields of a struct, like the
+// paths of Diff. Nested structs are visited, embedded structs are not prefixed.
+// Non-nil pointers and non-nil slices and maps count as set, see CopyNonZero
+func NonZeroFields(obj interface{}) ([]string, error) {
+	desc, ok := NewStructDescriptor(obj)
+	if !ok {
+		return nil, errors.New("object is not a struct or struct ptr")
+	}
+
+	paths := []string{}
+
+	nonZeroFields(desc.Value(), "", &paths)
+
+	return paths, nil
+}
+
+func nonZeroFields(v reflect.Value, prefix string, paths *[]string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+
+		path := prefix + field.Name
+		nestedPrefix := path + "."
+
+		if field.Anonymous {
+			nestedPrefix = prefix
+		}
+
+		if isNestedStruct(field.Type) && fieldValue.Kind() == reflect.Struct {
+			nonZeroFields(fieldValue, nestedPrefix, paths)
+			continue
+		}
+
+		if !isSet(fieldValue) {
+			continue
+		}
+
+		if elem, ok := structValue(fieldValue); ok && isNestedStruct(elem.Type()) {
+			nonZeroFields(elem, nestedPrefix, paths)
+			continue
+		}
+
+		*paths = append(*paths, path)
+	}
+}
+
+// CopyNonZero copies the set fields of src onto dst, both must be of the same
+// struct type and dst must be a pointer. Use it to merge the fields provided in a
+// PATCH request onto an existing model, then store it with Table.UpdateChanges:
+//
+//	updated := *existing
+//	err := structural.CopyNonZero(&updated, patch)
+//	...
+//	_, err = table.UpdateChanges(existing, &updated, db)
+//
+// Nested structs are merged field by field, nested struct pointers of dst are
+// replaced with a merged copy so a shallow copy of dst is not modified. Non-nil
+// pointers, slices and maps count as set, so use pointer fields for values which
+// may be set to their zero value (e.g. *bool). Slices and maps are copied by
+// reference
+func CopyNonZero(dst interface{}, src interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() || dstValue.Elem().Kind() != reflect.Struct {
+		return errors.New("dst must be a struct ptr")
+	}
+
+	srcDesc, ok := NewStructDescriptor(src)
+	if !ok {
+		return errors.New("src is not a struct or struct ptr")
+	}
+
+	if srcDesc.Type() != dstValue.Elem().Type() {
+		return errors.New("can't copy structs of different types")
+	}
+
+	copyNonZero(dstValue.Elem(), srcDesc.Value())
+
+	return nil
+}
+
+func copyNonZero(dst reflect.Value, src reflect.Value) {
+	t := dst.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		dstField := dst.Field(i)
+		srcField := src.Field(i)
+
+		if isNestedStruct(field.Type) {
+			if field.Type.Kind() == reflect.Struct {
+				copyNonZero(dstField, srcField)
+				continue
+			}
+
+			if srcField.IsNil() {
+				continue
+			}
+
+			// Merge into a copy, dst may share the pointer with another struct
+			merged := reflect.New(field.Type.Elem())
+			if !dstField.IsNil() {
+				merged.Elem().Set(dstField.Elem())
+			}
+
+			copyNonZero(merged.Elem(), srcField.Elem())
+			dstField.Set(merged)
+
+			continue
+		}
+
+		if isSet(srcField) {
+			dstField.Set(srcField)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by the circuit breaker when calls are rejected
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of a circuit breaker
+type State int
+
+// Circuit breaker states
+const (
+	// StateClosed allows all calls
+	StateClosed State = iota
+
+	// StateOpen rejects all calls until the cooldown has passed
+	StateOpen
+
+	// StateHalfOpen allows a single trial call
+	StateHalfOpen
+)
+
+// String representation of state
+func (state State) String() string {
+	switch state {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+// CircuitBreaker stops calling a failing service after a number of consecutive
+// failures. After the cooldown a single trial call is allowed, if it succeeds the
+// breaker closes again, otherwise it opens for another cooldown
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures which opens the breaker
+	Threshold int
+
+	// Cooldown is the time the breaker stays open
+	Cooldown time.Duration
+
+	// IsFailure classifies errors, if nil all errors are failures
+	IsFailure func(err error) bool
+
+	// OnStateChange is called when the state changes, can be nil. It is called
+	// without holding the lock of the breaker so it can use the breaker
+	OnStateChange func(from State, to State)
+
+	mutex    sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+	changes  []stateChange
+}
+
+// stateChange is a state change which is reported when the lock is released
+type stateChange struct {
+	from State
+	to   State
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+	}
+}
+
+// State returns the current state
+func (breaker *CircuitBreaker) State() State {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	breaker.refresh()
+
+	return breaker.state
+}
+
+// Execute calls fn if the breaker allows it, otherwise ErrOpen is returned. A panic
+// of fn counts as failure and is passed on
+func (breaker *CircuitBreaker) Execute(fn func() error) error {
+	if !breaker.allow() {
+		return ErrOpen
+	}
+
+	recorded := false
+
+	defer func() {
+		if !recorded {
+			// fn panicked, record it so a half-open trial is released
+			breaker.record(true)
+		}
+	}()
+
+	err := fn()
+
+	recorded = true
+	breaker.record(breaker.isFailure(err))
+
+	return err
+}
+
+// isFailure classifies err with IsFailure
+func (breaker *CircuitBreaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return breaker.IsFailure == nil || breaker.IsFailure(err)
+}
+
+// unlock releases the mutex and reports the state changes made while it was held
+func (breaker *CircuitBreaker) unlock() {
+	changes := breaker.changes
+	breaker.changes = nil
+
+	breaker.mutex.Unlock()
+
+	if breaker.OnStateChange == nil {
+		return
+	}
+
+	for _, change := range changes {
+		breaker.OnStateChange(change.from, change.to)
+	}
+}
+
+// Reset closes the breaker
+func (breaker *CircuitBreaker) Reset() {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	breaker.failures = 0
+	breaker.trial = false
+	breaker.setState(StateClosed)
+}
+
+// refresh moves from open to half-open after the cooldown, mutex must be held
+func (breaker *CircuitBreaker) refresh() {
+	if breaker.state == StateOpen && time.Since(breaker.openedAt) >= breaker.Cooldown {
+		breaker.setState(StateHalfOpen)
+	}
+}
+
+func (breaker *CircuitBreaker) allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	breaker.refresh()
+
+	switch breaker.state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		if breaker.trial {
+			return false
+		}
+
+		breaker.trial = true
+	}
+
+	return true
+}
+
+func (breaker *CircuitBreaker) record(failure bool) {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	if breaker.state == StateHalfOpen {
+		breaker.trial = false
+
+		if failure {
+			breaker.open()
+		} else {
+			breaker.failures = 0
+			breaker.setState(StateClosed)
+		}
+
+		return
+	}
+
+	if !failure {
+		breaker.failures = 0
+		return
+	}
+
+	breaker.failures++
+
+	if breaker.failures >= breaker.Threshold {
+		breaker.open()
+	}
+}
+
+// open the breaker, mutex must be held
+func (breaker *CircuitBreaker) open() {
+	breaker.openedAt = time.Now()
+	breaker.setState(StateOpen)
+}
+
+// setState changes the state, mutex must be held. The change is reported to
+// OnStateChange by unlock
+func (breaker *CircuitBreaker) setState(state State) {
+	if breaker.state == state {
+		return
+	}
+
+	breaker.changes = append(breaker.changes, stateChange{from: breaker.state, to: state})
+	breaker.state = state
+}
@@ -0,0 +1,150 @@
+// Package resilience contains retry with exponential backoff and a circuit breaker
+// for calls to external services
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy configures Retry
+type Policy struct {
+	// MaxAttempts is the maximum number of calls, values < 1 are treated as 1
+	MaxAttempts int
+
+	// InitialDelay is the delay before the second attempt
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay between attempts, 0 means no cap
+	MaxDelay time.Duration
+
+	// Multiplier is applied to the delay after each attempt, values < 1 are treated as 1
+	Multiplier float64
+
+	// Jitter is the fraction (0 - 1) of the delay which is randomized
+	Jitter float64
+
+	// Retryable classifies errors, if nil all errors except permanent errors are retried
+	Retryable func(err error) bool
+}
+
+// DefaultPolicy returns a policy with 3 attempts, starting at 100ms and doubling
+// up to 5s with 20% jitter
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxAttempts:  3,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+// Backoff returns the delay after attempt n (starting at 1)
+func (policy *Policy) Backoff(n int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(policy.InitialDelay) * math.Pow(multiplier, float64(n-1))
+
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+
+	if policy.Jitter > 0 {
+		jitter := math.Min(policy.Jitter, 1)
+		delay = delay*(1-jitter) + delay*jitter*rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+func (policy *Policy) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+
+	if policy.Retryable != nil {
+		return policy.Retryable(err)
+	}
+
+	return true
+}
+
+// permanentError marks an error as not retryable
+type permanentError struct {
+	err error
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *permanentError) Unwrap() error {
+	return err.err
+}
+
+// Permanent wraps err so Retry stops immediately, Retry returns the unwrapped error
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Retry calls fn until it succeeds, returns a non retryable error, the maximum
+// number of attempts is reached or ctx is done. The last error of fn is returned,
+// if ctx is done while waiting the context error is returned. A nil policy uses
+// DefaultPolicy
+func Retry(ctx context.Context, policy *Policy, fn func(ctx context.Context) error) error {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !policy.retryable(err) {
+			if permanent, ok := err.(*permanentError); ok {
+				return permanent.err
+			}
+
+			return err
+		}
+
+		if attempt >= attempts {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
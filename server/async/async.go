@@ -0,0 +1,325 @@
+// Package async standardizes asynchronous API operations such as large exports. Start
+// runs an operation in the background and writes a 202 with the status url of the
+// job, the status handle reports the job as pending, succeeded or failed with the
+// result payload. The status handle supports long polling with the wait parameter
+package async
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/cache/memory"
+	"github.com/almerlucke/go-utils/concurrency/pool"
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/almerlucke/go-utils/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Status of a job
+type Status string
+
+const (
+	// StatusPending is the status of jobs which did not finish yet
+	StatusPending Status = "pending"
+
+	// StatusSucceeded is the status of jobs which finished without error
+	StatusSucceeded Status = "succeeded"
+
+	// StatusFailed is the status of jobs which returned an error
+	StatusFailed Status = "failed"
+)
+
+// Job is the state of an asynchronous operation
+type Job struct {
+	ID         string      `json:"id"`
+	Status     Status      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+
+	// StatusURL is the url of the status handle of the job
+	StatusURL string `json:"statusUrl"`
+}
+
+// Finished returns true if the job succeeded or failed
+func (job *Job) Finished() bool {
+	return job.Status != StatusPending
+}
+
+// Operation is the work of a job, the result is returned as payload of the status
+// handle. Errors of the errors package expose their message, other errors are
+// reported as internal server error
+type Operation func(ctx context.Context) (interface{}, error)
+
+// Store stores jobs, implement it with a shared store (e.g. a database table) when
+// multiple instances serve the status handle
+type Store interface {
+	Put(job *Job) error
+	Get(id string) (*Job, bool, error)
+}
+
+// MemoryStore stores jobs in memory for a limited time
+type MemoryStore struct {
+	cache *memory.Cache[string, Job]
+}
+
+// NewMemoryStore creates a memory store which keeps jobs for ttl
+func NewMemoryStore(ttl time.Duration, maxJobs int) *MemoryStore {
+	return &MemoryStore{
+		cache: memory.New[string, Job](ttl, maxJobs),
+	}
+}
+
+// Put for Store, a copy of the job is stored
+func (store *MemoryStore) Put(job *Job) error {
+	store.cache.Set(job.ID, *job)
+	return nil
+}
+
+// Get for Store, a copy of the job is returned
+func (store *MemoryStore) Get(id string) (*Job, bool, error) {
+	job, ok := store.cache.Get(id)
+	if !ok {
+		return nil, false, nil
+	}
+
+	return &job, true, nil
+}
+
+// Runner starts jobs and serves their status
+type Runner struct {
+	Store Store
+
+	// StatusPath is the path of the status handle, the job id is appended
+	StatusPath string
+
+	// Pool runs the operations, if nil each operation runs in its own goroutine
+	Pool *pool.Pool
+
+	// Timeout limits the run time of operations, 0 means no limit
+	Timeout time.Duration
+
+	// MaxWait limits the wait parameter of the status handle
+	MaxWait time.Duration
+
+	// PollInterval is the interval the store is checked while long polling
+	PollInterval time.Duration
+
+	Logger logging.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewRunner creates a runner with a status path, e.g. "/api/v1/jobs"
+func NewRunner(store Store, statusPath string) *Runner {
+	return &Runner{
+		Store:        store,
+		StatusPath:   strings.TrimSuffix(statusPath, "/"),
+		MaxWait:      30 * time.Second,
+		PollInterval: 250 * time.Millisecond,
+	}
+}
+
+// Register the status handle on a router at the status path
+func (runner *Runner) Register(router *httprouter.Router) {
+	router.GET(runner.StatusPath+"/:id", runner.Handle)
+}
+
+// Run starts op in the background and returns the pending job. The operation does
+// not use the request context, it keeps running after the response is written
+func (runner *Runner) Run(op Operation) (*Job, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        id.String(),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		StatusURL: runner.StatusPath + "/" + id.String(),
+	}
+
+	err = runner.Store.Put(job)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := *job
+	task := func() error {
+		// Done when the operation finished, not when the pool accepted it
+		defer runner.wg.Done()
+
+		runner.run(&pending, op)
+		return nil
+	}
+
+	runner.wg.Add(1)
+
+	go func() {
+		if runner.Pool == nil {
+			task()
+			return
+		}
+
+		if err := runner.Pool.Submit(task); err != nil {
+			runner.finish(&pending, nil, err)
+			runner.wg.Done()
+		}
+	}()
+
+	return job, nil
+}
+
+// Start runs op in the background and writes a 202 with the job as payload and the
+// status url in the Location header
+func (runner *Runner) Start(rw http.ResponseWriter, op Operation) (*Job, error) {
+	job, err := runner.Run(op)
+	if err != nil {
+		logging.OrDefault(runner.Logger).Error("async job start failed", "error", err)
+		response.InternalServerError(rw, "job could not be started")
+		return nil, err
+	}
+
+	rw.Header().Set("Location", job.StatusURL)
+	response.Accepted(rw, job)
+
+	return job, nil
+}
+
+// Wait waits for all started operations to finish, e.g. on shutdown
+func (runner *Runner) Wait() {
+	runner.wg.Wait()
+}
+
+// run executes op and stores the outcome, panics fail the job
+func (runner *Runner) run(job *Job, op Operation) {
+	ctx := context.Background()
+
+	if runner.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runner.Timeout)
+		defer cancel()
+	}
+
+	var result interface{}
+	var err error
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		result, err = op(ctx)
+	}()
+
+	runner.finish(job, result, err)
+}
+
+// finish stores the outcome of a job
+func (runner *Runner) finish(job *Job, result interface{}, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+
+	if err != nil {
+		logging.OrDefault(runner.Logger).Error("async job failed", "id", job.ID, "error", err)
+
+		job.Status = StatusFailed
+		job.Error = errorMessage(err)
+	} else {
+		job.Status = StatusSucceeded
+		job.Result = result
+	}
+
+	if err := runner.Store.Put(job); err != nil {
+		logging.OrDefault(runner.Logger).Error("async job store failed", "id", job.ID, "error", err)
+	}
+}
+
+// errorMessage returns the client safe message of err
+func errorMessage(err error) string {
+	if response.StatusForError(err) == http.StatusInternalServerError {
+		return "internal server error"
+	}
+
+	return err.Error()
+}
+
+// Handle writes the job of the id route param. With the wait parameter (a duration
+// like "10s") the handle waits until the job is finished or the duration passed
+func (runner *Runner) Handle(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	id := pm.ByName("id")
+
+	job, ok, err := runner.Store.Get(id)
+	if err != nil {
+		logging.OrDefault(runner.Logger).Error("async job lookup failed", "id", id, "error", err)
+		response.InternalServerError(rw, "job lookup failed")
+		return
+	}
+
+	if !ok {
+		response.NotFound(rw)
+		return
+	}
+
+	if wait := runner.wait(r); wait > 0 && !job.Finished() {
+		job = runner.poll(r.Context(), job, wait)
+	}
+
+	response.OK(rw, job)
+}
+
+// wait returns the long polling duration of the request
+func (runner *Runner) wait(r *http.Request) time.Duration {
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil || wait <= 0 {
+		return 0
+	}
+
+	if runner.MaxWait > 0 && wait > runner.MaxWait {
+		wait = runner.MaxWait
+	}
+
+	return wait
+}
+
+// poll checks the store until the job is finished, wait passed or ctx is done
+func (runner *Runner) poll(ctx context.Context, job *Job, wait time.Duration) *Job {
+	interval := runner.PollInterval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return job
+		case <-timer.C:
+			return job
+		case <-ticker.C:
+			current, ok, err := runner.Store.Get(job.ID)
+			if err != nil || !ok {
+				return job
+			}
+
+			job = current
+			if job.Finished() {
+				return job
+			}
+		}
+	}
+}
@@ -2,7 +2,11 @@ package basic
 
 import (
 	"encoding/base64"
+	"net/http"
 	"strings"
+
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
 )
 
 // ValidateBasicAuthHeader validate a basic auth header string
@@ -28,3 +32,17 @@ func ValidateBasicAuthHeader(header string, user string, password string) bool {
 	// Compare user and password
 	return pair[0] == user && pair[1] == password
 }
+
+// Handle wraps a httprouter handle with basic authentication, requests without
+// valid credentials get a 401 with a WWW-Authenticate challenge
+func Handle(user string, password string, handle httprouter.Handle) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+		if !ValidateBasicAuthHeader(r.Header.Get("Authorization"), user, password) {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="restricted"`)
+			response.Unauthorized(rw, "invalid credentials")
+			return
+		}
+
+		handle(rw, r, pm)
+	}
+}
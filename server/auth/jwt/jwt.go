@@ -38,11 +38,23 @@ func GenerateToken(signingSecret string, issuedAt int64, expiresAfter int64, tok
 
 // UnpackToken validate and unpack JWT token data
 func UnpackToken(signedString string, signingSecret string, factory TokenDataFactory) (TokenData, error) {
+	return UnpackTokenWithOptions(signedString, signingSecret, factory, nil)
+}
+
+// UnpackTokenWithOptions validate and unpack JWT token data, the claims are validated
+// with options. If options is nil the exp, nbf and iat claims are validated without leeway
+func UnpackTokenWithOptions(signedString string, signingSecret string, factory TokenDataFactory, options *Options) (TokenData, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
 	// Generate new token data
 	tokenData := factory.New()
 
-	// Parse token
-	token, err := jwt.Parse(signedString, func(token *jwt.Token) (interface{}, error) {
+	// Parse token, claims are validated with the options
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+
+	token, err := parser.ParseWithClaims(signedString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Don't forget to validate the alg is what you expect:
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.NewValidationError("invalid JWT token", 0)
@@ -61,6 +73,11 @@ func UnpackToken(signedString string, signingSecret string, factory TokenDataFac
 		return nil, jwt.NewValidationError("invalid JWT token", 0)
 	}
 
+	err = options.Validate(claims)
+	if err != nil {
+		return nil, err
+	}
+
 	// Set claims from token
 	err = tokenData.SetClaims(claims)
 	if err != nil {
@@ -0,0 +1,202 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrTokenExpired is returned when the exp claim is in the past
+	ErrTokenExpired = errors.New("token is expired")
+
+	// ErrTokenNotValidYet is returned when the nbf claim is in the future
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+
+	// ErrTokenIssuedInFuture is returned when the iat claim is in the future
+	ErrTokenIssuedInFuture = errors.New("token is issued in the future")
+
+	// ErrTokenTooOld is returned when the token was issued longer than MaxAge ago
+	ErrTokenTooOld = errors.New("token is too old")
+
+	// ErrMissingClaim is returned when a required claim is missing
+	ErrMissingClaim = errors.New("token is missing a required claim")
+
+	// ErrInvalidIssuer is returned when the iss claim is not accepted
+	ErrInvalidIssuer = errors.New("token issuer is not accepted")
+
+	// ErrInvalidAudience is returned when none of the aud claims is accepted
+	ErrInvalidAudience = errors.New("token audience is not accepted")
+)
+
+// Options for validating the claims of a token in UnpackTokenWithOptions
+type Options struct {
+	// Leeway is the allowed clock skew when validating exp, nbf and iat
+	Leeway time.Duration
+
+	// RequiredClaims must be present in the token
+	RequiredClaims []string
+
+	// Issuers are the accepted iss claims, if empty the issuer is not checked
+	Issuers []string
+
+	// Audiences are the accepted aud claims, if empty the audience is not checked
+	Audiences []string
+
+	// MaxAge rejects tokens issued longer ago, regardless of exp. Tokens must have
+	// an iat claim when set, 0 disables the check
+	MaxAge time.Duration
+
+	// Now returns the current time, if nil time.Now is used
+	Now func() time.Time
+}
+
+func (options *Options) now() time.Time {
+	if options.Now != nil {
+		return options.Now()
+	}
+
+	return time.Now()
+}
+
+// Validate the claims against the options
+func (options *Options) Validate(claims jwt.MapClaims) error {
+	now := options.now()
+	leeway := options.Leeway
+
+	for _, claim := range options.RequiredClaims {
+		if _, ok := claims[claim]; !ok {
+			return fmt.Errorf("%w: %v", ErrMissingClaim, claim)
+		}
+	}
+
+	exp, ok, err := timeClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+
+	if ok && now.After(exp.Add(leeway)) {
+		return ErrTokenExpired
+	}
+
+	nbf, ok, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+
+	if ok && now.Add(leeway).Before(nbf) {
+		return ErrTokenNotValidYet
+	}
+
+	iat, ok, err := timeClaim(claims, "iat")
+	if err != nil {
+		return err
+	}
+
+	if ok && now.Add(leeway).Before(iat) {
+		return ErrTokenIssuedInFuture
+	}
+
+	if options.MaxAge > 0 {
+		if !ok {
+			return fmt.Errorf("%w: iat", ErrMissingClaim)
+		}
+
+		if now.Add(-leeway).After(iat.Add(options.MaxAge)) {
+			return ErrTokenTooOld
+		}
+	}
+
+	if len(options.Issuers) > 0 {
+		iss, _ := claims["iss"].(string)
+		if !contains(options.Issuers, iss) {
+			return ErrInvalidIssuer
+		}
+	}
+
+	if len(options.Audiences) > 0 {
+		accepted := false
+
+		for _, aud := range audienceClaim(claims) {
+			if contains(options.Audiences, aud) {
+				accepted = true
+				break
+			}
+		}
+
+		if !accepted {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// timeClaim returns a numeric date claim as time
+func timeClaim(claims jwt.MapClaims, key string) (time.Time, bool, error) {
+	value, ok := claims[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var seconds int64
+
+	switch v := value.(type) {
+	case float64:
+		seconds = int64(v)
+	case int64:
+		seconds = v
+	case int:
+		seconds = int64(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			f, err := v.Float64()
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("invalid %v claim", key)
+			}
+
+			n = int64(f)
+		}
+
+		seconds = n
+	default:
+		return time.Time{}, false, fmt.Errorf("invalid %v claim", key)
+	}
+
+	return time.Unix(seconds, 0), true, nil
+}
+
+// audienceClaim returns the aud claim which can be a string or a list of strings
+func audienceClaim(claims jwt.MapClaims) []string {
+	switch v := claims["aud"].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		audiences := []string{}
+		for _, aud := range v {
+			if s, ok := aud.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+
+		return audiences
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
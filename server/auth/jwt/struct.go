@@ -0,0 +1,58 @@
+package jwt
+
+import (
+	"github.com/almerlucke/go-utils/reflection/structural"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// ClaimTag is the struct tag used to map claims to fields, e.g. claim:"sub"
+const ClaimTag = "claim"
+
+// StructTokenData implements TokenData for a struct ptr, claims are mapped to the
+// exported fields with the claim tag (fields without tag use the field name). Nested
+// structs map to nested claims and claim values are coerced to the field types.
+// Fields mapped to exp or iat override the values given to GenerateToken
+type StructTokenData struct {
+	Data interface{}
+}
+
+// NewStructTokenData creates token data for a struct ptr
+func NewStructTokenData(data interface{}) *StructTokenData {
+	return &StructTokenData{
+		Data: data,
+	}
+}
+
+// GetClaims for TokenData
+func (tokenData *StructTokenData) GetClaims() jwt.MapClaims {
+	m, err := structural.ToMap(tokenData.Data, ClaimTag)
+	if err != nil {
+		return jwt.MapClaims{}
+	}
+
+	return jwt.MapClaims(m)
+}
+
+// SetClaims for TokenData
+func (tokenData *StructTokenData) SetClaims(claims jwt.MapClaims) error {
+	return structural.FromMap(map[string]interface{}(claims), tokenData.Data, ClaimTag)
+}
+
+// StructFactory creates StructTokenData for new instances of T, it can be used as
+// TokenDataFactory for the authtoken middleware
+type StructFactory[T any] struct{}
+
+// New for TokenDataFactory
+func (StructFactory[T]) New() TokenData {
+	return NewStructTokenData(new(T))
+}
+
+// Unpack validates the token and maps the claims to a new T, options can be nil
+func Unpack[T any](signedString string, signingSecret string, options *Options) (*T, error) {
+	tokenData, err := UnpackTokenWithOptions(signedString, signingSecret, StructFactory[T]{}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenData.(*StructTokenData).Data.(*T), nil
+}
@@ -1,8 +1,59 @@
 package context
 
+import (
+	"context"
+	"fmt"
+)
+
 // Key to use as context key
 type Key string
 
 func (c Key) String() string {
 	return "context key " + string(c)
 }
+
+// Context keys of the middlewares in this module, they are declared in one place so
+// keys never collide
+const (
+	// AuthTokenKey holds the jwt.TokenData of the authtoken middleware
+	AuthTokenKey = Key("auth-token")
+
+	// LocalizationKey holds the *localization.Localization of the localization middleware
+	LocalizationKey = Key("localization")
+
+	// FeatureFlagsKey holds the featureflags.Flags of the feature flag middleware
+	FeatureFlagsKey = Key("featureFlags")
+
+	// AuditInfoKey holds the *audit.Info of the audit middleware
+	AuditInfoKey = Key("auditInfo")
+
+	// BruteForceKey holds the login attempt of the bruteforce middleware
+	BruteForceKey = Key("bruteForce")
+
+	// ClientIPKey holds the client ip string resolved by the ipfilter middleware
+	ClientIPKey = Key("clientIP")
+
+	// GraphQLLoadersKey holds the graphql.Loaders of a graphql request
+	GraphQLLoadersKey = Key("graphQLLoaders")
+
+	// TraceHeadersKey holds the tracing headers propagated by the client package
+	TraceHeadersKey = Key("traceHeaders")
+)
+
+// Value returns the value of key in ctx, ok is false if the value is missing or
+// not of type T
+func Value[T any](ctx context.Context, key Key) (value T, ok bool) {
+	value, ok = ctx.Value(key).(T)
+	return
+}
+
+// MustValue returns the value of key in ctx and panics if the value is missing or
+// not of type T
+func MustValue[T any](ctx context.Context, key Key) T {
+	value, ok := Value[T](ctx, key)
+	if !ok {
+		panic(fmt.Sprintf("%v is missing from context", key))
+	}
+
+	return value
+}
@@ -0,0 +1,110 @@
+package graphql
+
+import (
+	"errors"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+	"github.com/almerlucke/go-utils/server/response"
+)
+
+var (
+	errInvalidVariables = errors.New("invalid variables")
+	errBodyTooLarge     = errors.New("request body too large")
+	errInvalidBody      = errors.New("invalid request body")
+	errMethodNotAllowed = errors.New("method not allowed")
+	errMissingQuery     = errors.New("missing query")
+)
+
+// Error codes set as "code" extension
+const (
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeInternal        = "INTERNAL_SERVER_ERROR"
+)
+
+// Location is a location in the GraphQL document
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Error for error interface
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// CodeForError returns the extension code for the kind of err, like
+// response.StatusForError does for HTTP
+func CodeForError(err error) string {
+	switch errorUtils.KindOf(err) {
+	case errorUtils.KindNotFound:
+		return CodeNotFound
+	case errorUtils.KindConflict:
+		return CodeConflict
+	case errorUtils.KindUnauthorized:
+		return CodeUnauthenticated
+	case errorUtils.KindForbidden:
+		return CodeForbidden
+	case errorUtils.KindValidation:
+		return CodeBadUserInput
+	}
+
+	var errorMap response.ErrorMap
+	if errorUtils.As(err, &errorMap) {
+		return CodeBadUserInput
+	}
+
+	return CodeInternal
+}
+
+// Extensions returns the error extensions of err: the code, the error code of the
+// errors package as "errorCode" and the sections of an ErrorMap as "fields"
+func Extensions(err error) map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code": CodeForError(err),
+	}
+
+	if code := errorUtils.CodeOf(err); code != "" {
+		extensions["errorCode"] = code
+	}
+
+	var errorMap response.ErrorMap
+	if errorUtils.As(err, &errorMap) {
+		extensions["fields"] = errorMap
+	}
+
+	return extensions
+}
+
+// FormatError converts err to a GraphQL error with extensions, the message of
+// internal errors is not exposed. Use it in the error presenter of the GraphQL
+// library
+func FormatError(err error, path []interface{}) *Error {
+	formatted := &Error{
+		Message:    "internal server error",
+		Path:       path,
+		Extensions: Extensions(err),
+	}
+
+	var errorMap response.ErrorMap
+	var typedErr *errorUtils.Error
+
+	if errorUtils.As(err, &errorMap) {
+		formatted.Message = "invalid input"
+	} else if errorUtils.As(err, &typedErr) && errorUtils.KindOf(err) != errorUtils.KindInternal {
+		formatted.Message = typedErr.Message
+	}
+
+	return formatted
+}
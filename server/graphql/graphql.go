@@ -0,0 +1,262 @@
+// Package graphql mounts a GraphQL endpoint next to the REST routes. The package does
+// not implement GraphQL itself, an Executor adapts a GraphQL library (e.g. graph-gophers
+// or gqlgen). It provides the HTTP transport, dataloaders backed by model tables and
+// maps errors of the errors package to GraphQL error extensions
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/almerlucke/go-utils/logging"
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DefaultMaxBodySize is the default maximum size of a request body
+const DefaultMaxBodySize = 1 << 20
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Result is a GraphQL response
+type Result struct {
+	Data       interface{}            `json:"data,omitempty"`
+	Errors     []*Error               `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Executor executes GraphQL requests, implement it to adapt a GraphQL library
+type Executor interface {
+	Execute(ctx context.Context, request *Request) *Result
+}
+
+// ExecutorFunc is a function which conforms to Executor
+type ExecutorFunc func(ctx context.Context, request *Request) *Result
+
+// Execute calls f
+func (f ExecutorFunc) Execute(ctx context.Context, request *Request) *Result {
+	return f(ctx, request)
+}
+
+// Handler serves GraphQL requests over HTTP, queries can be sent with GET (query,
+// operationName and variables parameters) and POST (JSON body). Mutations are only
+// executed for POST requests
+type Handler struct {
+	Executor    Executor
+	MaxBodySize int64
+	Logger      logging.Logger
+
+	// Loaders creates the dataloaders of a request, loaders cache per request so
+	// they must not be shared between requests. Can be nil
+	Loaders func(r *http.Request) Loaders
+}
+
+// NewHandler creates a new GraphQL handler
+func NewHandler(executor Executor) *Handler {
+	return &Handler{
+		Executor:    executor,
+		MaxBodySize: DefaultMaxBodySize,
+	}
+}
+
+// Register mounts the handler on a router for GET and POST at path (e.g. "/graphql")
+func (handler *Handler) Register(router *httprouter.Router, path string) {
+	router.GET(path, handler.Handle)
+	router.POST(path, handler.Handle)
+}
+
+// Handle is the httprouter handle of the handler
+func (handler *Handler) Handle(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	handler.ServeHTTP(rw, r)
+}
+
+func (handler *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	request, status, err := handler.parse(r)
+	if err != nil {
+		handler.write(rw, status, &Result{Errors: []*Error{{Message: err.Error()}}})
+		return
+	}
+
+	if r.Method == http.MethodGet && isMutation(request.Query) {
+		rw.Header().Set("Allow", http.MethodPost)
+		handler.write(rw, http.StatusMethodNotAllowed, &Result{Errors: []*Error{{Message: "mutations require POST"}}})
+		return
+	}
+
+	ctx := r.Context()
+	if handler.Loaders != nil {
+		ctx = context.WithValue(ctx, LoadersKey, handler.Loaders(r))
+	}
+
+	result := handler.Executor.Execute(ctx, request)
+	if result == nil {
+		result = &Result{}
+	}
+
+	handler.write(rw, http.StatusOK, result)
+}
+
+// parse reads the request from the query parameters or the JSON body
+func (handler *Handler) parse(r *http.Request) (*Request, int, error) {
+	request := &Request{}
+
+	switch r.Method {
+	case http.MethodGet:
+		values := r.URL.Query()
+		request.Query = values.Get("query")
+		request.OperationName = values.Get("operationName")
+
+		if variables := values.Get("variables"); variables != "" {
+			err := json.Unmarshal([]byte(variables), &request.Variables)
+			if err != nil {
+				return nil, http.StatusBadRequest, errInvalidVariables
+			}
+		}
+	case http.MethodPost:
+		maxBodySize := handler.MaxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = DefaultMaxBodySize
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		if int64(len(body)) > maxBodySize {
+			return nil, http.StatusRequestEntityTooLarge, errBodyTooLarge
+		}
+
+		err = json.Unmarshal(body, request)
+		if err != nil {
+			return nil, http.StatusBadRequest, errInvalidBody
+		}
+	default:
+		return nil, http.StatusMethodNotAllowed, errMethodNotAllowed
+	}
+
+	if strings.TrimSpace(request.Query) == "" {
+		return nil, http.StatusBadRequest, errMissingQuery
+	}
+
+	return request, http.StatusOK, nil
+}
+
+// write writes a result as JSON
+func (handler *Handler) write(rw http.ResponseWriter, status int, result *Result) {
+	js, err := json.Marshal(result)
+	if err != nil {
+		logging.OrDefault(handler.Logger).Error("graphql result serialization failed", "error", err)
+		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	rw.Write(js)
+}
+
+// isMutation returns true if any operation of the document is a mutation, so a GET
+// request can't select a mutation with operationName. Strings and comments are
+// skipped, only names outside selection sets and arguments are checked
+func isMutation(query string) bool {
+	depth := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			i = skipString(query, i)
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case isNameStart(c):
+			start := i
+			for i+1 < len(query) && isNameChar(query[i+1]) {
+				i++
+			}
+
+			if depth == 0 && query[start:i+1] == "mutation" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// skipString returns the index of the closing quote of the (block) string starting
+// at index start
+func skipString(query string, start int) int {
+	if strings.HasPrefix(query[start:], `"""`) {
+		end := strings.Index(query[start+3:], `"""`)
+		if end < 0 {
+			return len(query)
+		}
+
+		return start + 3 + end + 2
+	}
+
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"', '\n':
+			return i
+		}
+	}
+
+	return len(query)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+/*
+	Loaders in context
+*/
+
+const (
+	// LoadersKey to get the loaders of a request from the context
+	LoadersKey = contextUtils.GraphQLLoadersKey
+)
+
+// Loaders holds the dataloaders of a request by name
+type Loaders map[string]interface{}
+
+// GetLoaders from context, ok is false if the handler has no loaders
+func GetLoaders(ctx context.Context) (Loaders, bool) {
+	return contextUtils.Value[Loaders](ctx, LoadersKey)
+}
+
+// GetLoader returns the loader with name from the context, ok is false if there is
+// no loader with name or it is not a *Loader[K, V]
+func GetLoader[K comparable, V any](ctx context.Context, name string) (*Loader[K, V], bool) {
+	loaders, ok := GetLoaders(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	loader, ok := loaders[name].(*Loader[K, V])
+
+	return loader, ok
+}
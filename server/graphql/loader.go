@@ -0,0 +1,251 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// DefaultWait is the time a loader collects keys before it runs a batch
+const DefaultWait = 2 * time.Millisecond
+
+// BatchFunc loads the values of keys, keys without value are returned as not found
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader batches and caches loads of values by key, so resolvers can load related
+// objects one by one without N+1 queries. Create a loader per request, see
+// Handler.Loaders
+type Loader[K comparable, V any] struct {
+	Batch BatchFunc[K, V]
+
+	// Wait is the time keys are collected before a batch runs
+	Wait time.Duration
+
+	// MaxBatch is the maximum number of keys per batch, 0 means no limit
+	MaxBatch int
+
+	mutex sync.Mutex
+	cache map[K]*thunk[V]
+	batch *batch[K, V]
+}
+
+// thunk is the pending or loaded value of a key
+type thunk[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// batch is a set of keys which are loaded together
+type batch[K comparable, V any] struct {
+	keys   []K
+	thunks []*thunk[V]
+	closed bool
+}
+
+// NewLoader creates a new loader
+func NewLoader[K comparable, V any](batchFunc BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		Batch: batchFunc,
+		Wait:  DefaultWait,
+		cache: map[K]*thunk[V]{},
+	}
+}
+
+// Load the value of key, keys requested within Wait are loaded in one batch. The
+// batch runs with the context of the load that started it
+func (loader *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	t := loader.thunk(ctx, key)
+
+	select {
+	case <-t.done:
+		return t.value, t.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany loads the values of keys, the values and errors are in the order of keys
+func (loader *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, []error) {
+	thunks := make([]*thunk[V], len(keys))
+	for i, key := range keys {
+		thunks[i] = loader.thunk(ctx, key)
+	}
+
+	values := make([]V, len(keys))
+	errs := make([]error, len(keys))
+
+	for i, t := range thunks {
+		select {
+		case <-t.done:
+			values[i], errs[i] = t.value, t.err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+
+	return values, errs
+}
+
+// Prime adds a value to the cache, an existing value is not replaced
+func (loader *Loader[K, V]) Prime(key K, value V) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	if _, ok := loader.cache[key]; ok {
+		return
+	}
+
+	t := &thunk[V]{done: make(chan struct{}), value: value}
+	close(t.done)
+
+	loader.cache[key] = t
+}
+
+// Clear removes key from the cache, e.g. after a mutation
+func (loader *Loader[K, V]) Clear(key K) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	delete(loader.cache, key)
+}
+
+// thunk returns the cached thunk of key or adds key to the current batch
+func (loader *Loader[K, V]) thunk(ctx context.Context, key K) *thunk[V] {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	if loader.cache == nil {
+		loader.cache = map[K]*thunk[V]{}
+	}
+
+	if t, ok := loader.cache[key]; ok {
+		return t
+	}
+
+	t := &thunk[V]{done: make(chan struct{})}
+	loader.cache[key] = t
+
+	if loader.batch == nil {
+		b := &batch[K, V]{}
+		loader.batch = b
+
+		time.AfterFunc(loader.Wait, func() {
+			loader.dispatch(ctx, b)
+		})
+	}
+
+	b := loader.batch
+	b.keys = append(b.keys, key)
+	b.thunks = append(b.thunks, t)
+
+	if loader.MaxBatch > 0 && len(b.keys) >= loader.MaxBatch {
+		go loader.dispatch(ctx, b)
+	}
+
+	return t
+}
+
+// dispatch runs batch b once
+func (loader *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	loader.mutex.Lock()
+
+	if b.closed {
+		loader.mutex.Unlock()
+		return
+	}
+
+	b.closed = true
+
+	if loader.batch == b {
+		loader.batch = nil
+	}
+
+	loader.mutex.Unlock()
+
+	values, err := loader.Batch(ctx, b.keys)
+
+	for i, key := range b.keys {
+		t := b.thunks[i]
+
+		if err != nil {
+			t.err = err
+		} else if value, ok := values[key]; ok {
+			t.value = value
+		} else {
+			t.err = errorUtils.NotFound(fmt.Sprintf("%v not found", key))
+		}
+
+		close(t.done)
+	}
+
+	if err != nil {
+		// Do not cache failed loads so they can be retried
+		loader.mutex.Lock()
+		for i, key := range b.keys {
+			if loader.cache[key] == b.thunks[i] {
+				delete(loader.cache, key)
+			}
+		}
+		loader.mutex.Unlock()
+	}
+}
+
+/*
+	Table loaders
+*/
+
+// ManyGetter is a table which loads rows by primary key, implemented by *model.Table
+// and by *model.ScopedTable for tenant scoped loaders
+type ManyGetter interface {
+	model.Tabler
+	GetMany(keys []interface{}, queryer database.Queryer) (interface{}, error)
+}
+
+// TableBatch returns a batch function which loads rows of table by primary key with
+// GetMany. The primary key field must be convertible to K
+func TableBatch[K comparable](table ManyGetter, queryer database.Queryer) BatchFunc[K, interface{}] {
+	return func(ctx context.Context, keys []K) (map[K]interface{}, error) {
+		args := make([]interface{}, len(keys))
+		for i, key := range keys {
+			args[i] = key
+		}
+
+		rows, err := table.GetMany(args, queryer)
+		if err != nil {
+			return nil, err
+		}
+
+		keyType := reflect.TypeOf((*K)(nil)).Elem()
+		field := table.TableDescriptor().PrimaryColumn.ActualName
+
+		results := map[K]interface{}{}
+		rowsValue := reflect.ValueOf(rows)
+
+		for i := 0; i < rowsValue.Len(); i++ {
+			row := rowsValue.Index(i)
+
+			keyValue := row.Elem().FieldByName(field)
+			if !keyValue.IsValid() || !keyValue.Type().ConvertibleTo(keyType) {
+				return nil, fmt.Errorf("primary key %v of table %v can't be converted to %v", field, table.TableName(), keyType)
+			}
+
+			results[keyValue.Convert(keyType).Interface().(K)] = row.Interface()
+		}
+
+		return results, nil
+	}
+}
+
+// NewTableLoader creates a loader for rows of table by primary key, the values are
+// pointers to structs of the table type
+func NewTableLoader[K comparable](table ManyGetter, queryer database.Queryer) *Loader[K, interface{}] {
+	return NewLoader(TableBatch[K](table, queryer))
+}
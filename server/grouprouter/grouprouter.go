@@ -2,35 +2,223 @@
 // group can use it's own middleware. So we do not need to check on prefix path or any
 // such thing. If the lookup for a group returns a handle we call the middleware stack of the
 // matching httprouter. You can add a self created group or create a new group with router and
-// middleware added. We use httprouter for routing and negroni for middleware
+// middleware added. We use httprouter for routing and negroni for middleware, groups
+// created with NewChainGroup use a chain.Chain instead so negroni is optional. Groups can
+// be nested, e.g. public and private sub groups of an /api/v1 group that share its
+// middleware, and a group prefix scopes the not found and method not allowed responses
 package grouprouter
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 
+	"github.com/almerlucke/go-utils/server/middleware/chain"
+	"github.com/almerlucke/go-utils/server/response"
 	"github.com/julienschmidt/httprouter"
 	"github.com/urfave/negroni"
 )
 
-// Group a router and middleware together
+// Stack is a middleware stack with a final handler, implemented by
+// *negroni.Negroni and *chain.Chain
+type Stack interface {
+	http.Handler
+	UseHandler(handler http.Handler)
+}
+
+// Group a router and middleware together. A group can contain sub groups, requests
+// for a sub group run through the middleware of the group and then through the
+// middleware of the sub group. Routes are registered with their full path on the
+// router of the group that serves them
 type Group struct {
 	Router     *httprouter.Router
 	Middleware *negroni.Negroni
+	Groups     []*Group
+
+	// Chain is the middleware of the group when Middleware is nil, it allows using
+	// the group without negroni
+	Chain *chain.Chain
+
+	// Prefix is the path prefix of the group, requests with the prefix which match no
+	// route are served by the NotFound or MethodNotAllowed handler of the group router
+	// (after the group middleware ran). Without prefix unmatched requests go to the
+	// fallback of the GroupRouter
+	Prefix string
+
+	routes []Route
+}
+
+// Route is a route registered with Group.Handle
+type Route struct {
+	Method string
+	Path   string
 }
 
-// NewGroup creates a new group
+// NewGroup creates a new group, the NotFound and MethodNotAllowed handlers of the
+// router write the standard response envelope
 func NewGroup() *Group {
 	g := &Group{
 		Middleware: negroni.New(),
 		Router:     httprouter.New(),
+		Groups:     []*Group{},
 	}
 
+	g.Router.NotFound = http.HandlerFunc(notFound)
+	g.Router.MethodNotAllowed = http.HandlerFunc(methodNotAllowed)
+
 	return g
 }
 
-// Prepare group for final use by adding router as last handler
+// NewChainGroup creates a new group which uses a chain.Chain as middleware instead of
+// negroni, negroni style middlewares are added with Chain.Use
+func NewChainGroup() *Group {
+	g := NewGroup()
+	g.Middleware = nil
+	g.Chain = chain.New()
+
+	return g
+}
+
+// Stack returns the middleware stack of the group, Middleware or Chain if Middleware
+// is nil
+func (g *Group) Stack() Stack {
+	if g.Middleware != nil {
+		return g.Middleware
+	}
+
+	return g.Chain
+}
+
+// NewPrefixGroup creates a new group with a path prefix
+func NewPrefixGroup(prefix string) *Group {
+	g := NewGroup()
+	g.Prefix = prefix
+
+	return g
+}
+
+// AddNewGroup adds a new sub group with a path prefix, prefix can be empty
+func (g *Group) AddNewGroup(prefix string) *Group {
+	sub := NewPrefixGroup(prefix)
+	g.Groups = append(g.Groups, sub)
+
+	return sub
+}
+
+// AddGroup adds an existing sub group
+func (g *Group) AddGroup(sub *Group) {
+	g.Groups = append(g.Groups, sub)
+}
+
+// Handle registers a handle on the router of the group and records the route, so it
+// is listed by Routes. Routes registered directly on the router are not listed
+func (g *Group) Handle(method string, path string, handle httprouter.Handle) {
+	g.Router.Handle(method, path, handle)
+	g.routes = append(g.routes, Route{Method: method, Path: path})
+}
+
+// Routes returns the routes of the group and its sub groups registered with Handle,
+// sorted by path and method
+func (g *Group) Routes() []Route {
+	return sortedRoutes(g.collectRoutes([]Route{}))
+}
+
+func (g *Group) collectRoutes(routes []Route) []Route {
+	routes = append(routes, g.routes...)
+
+	for _, sub := range g.Groups {
+		routes = sub.collectRoutes(routes)
+	}
+
+	return routes
+}
+
+func sortedRoutes(routes []Route) []Route {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// Prepare group and its sub groups for final use by adding the dispatcher to sub
+// groups and router as last handler
 func (g *Group) Prepare() {
-	g.Middleware.UseHandler(g.Router)
+	for _, sub := range g.Groups {
+		sub.Prepare()
+	}
+
+	if len(g.Groups) == 0 {
+		g.Stack().UseHandler(g.Router)
+		return
+	}
+
+	g.Stack().UseHandler(http.HandlerFunc(g.dispatch))
+}
+
+// Handles returns true if the router of the group or one of its sub groups has a
+// route for method and path
+func (g *Group) Handles(method string, path string) bool {
+	for _, sub := range g.Groups {
+		if sub.Handles(method, path) {
+			return true
+		}
+	}
+
+	h, _, _ := g.Router.Lookup(method, path)
+
+	return h != nil
+}
+
+// Covers returns true if the group has a prefix and path is the prefix or a sub path
+// of the prefix
+func (g *Group) Covers(path string) bool {
+	if g.Prefix == "" || !strings.HasPrefix(path, g.Prefix) {
+		return false
+	}
+
+	return len(path) == len(g.Prefix) || strings.HasSuffix(g.Prefix, "/") || path[len(g.Prefix)] == '/'
+}
+
+// dispatch serves the request with the matching sub group, if no sub group matches
+// the router of the group serves it
+func (g *Group) dispatch(rw http.ResponseWriter, req *http.Request) {
+	if sub := match(g.Groups, req); sub != nil {
+		sub.Stack().ServeHTTP(rw, req)
+		return
+	}
+
+	g.Router.ServeHTTP(rw, req)
+}
+
+// match returns the first group which handles the request, if no group handles it
+// the first group which covers the path is returned
+func match(groups []*Group, req *http.Request) *Group {
+	for _, g := range groups {
+		if g.Handles(req.Method, req.URL.Path) {
+			return g
+		}
+	}
+
+	for _, g := range groups {
+		if g.Covers(req.URL.Path) {
+			return g
+		}
+	}
+
+	return nil
+}
+
+func notFound(rw http.ResponseWriter, r *http.Request) {
+	response.NotFound(rw)
+}
+
+func methodNotAllowed(rw http.ResponseWriter, r *http.Request) {
+	response.MethodNotAllowed(rw)
 }
 
 // GroupRouter is a wrapper around one or more middleware and httprouter groups
@@ -60,20 +248,27 @@ func (r *GroupRouter) AddGroup(g *Group) {
 	r.Groups = append(r.Groups, g)
 }
 
-// ServeHTTP serve the http
-func (r *GroupRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	method := req.Method
-	path := req.URL.Path
+// Routes returns the routes of all groups registered with Group.Handle, sorted by
+// path and method
+func (r *GroupRouter) Routes() []Route {
+	routes := []Route{}
 
-	// For each group check if the httprouter handles the path
-	// if the router lookup succeeds we call the ServeHTTP of the
-	// groups middleware
 	for _, g := range r.Groups {
-		h, _, _ := g.Router.Lookup(method, path)
-		if h != nil {
-			g.Middleware.ServeHTTP(rw, req)
-			return
-		}
+		routes = g.collectRoutes(routes)
+	}
+
+	return sortedRoutes(routes)
+}
+
+// ServeHTTP serve the http
+func (r *GroupRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// For each group check if the httprouter of the group or one of its sub
+	// groups handles the path, if so we call the ServeHTTP of the groups
+	// middleware. Otherwise a group with a matching prefix serves the request
+	// so it can write a not found or method not allowed response
+	if g := match(r.Groups, req); g != nil {
+		g.Stack().ServeHTTP(rw, req)
+		return
 	}
 
 	// Call router fallback handler if we didn't find the method/path combination in
@@ -114,18 +309,19 @@ func publicMiddleware(router *httprouter.Router) *negroni.Negroni {
 	return n
 }
 
-func privateMiddleware(router *httprouter.Router) *negroni.Negroni {
-	n := negroni.New()
+// Without negroni the same middlewares are added to a chain, used as Group.Chain
+func privateMiddleware(router *httprouter.Router) *chain.Chain {
+	c := chain.New()
 
-	n.Use(sharedLocalization())
-	n.Use(authtoken.New(&auth.TokenDataFactory{}, "test"))
-	n.Use(negroni.NewLogger())
-	n.Use(recovery.New())
-	n.Use(gzip.Gzip(gzip.DefaultCompression))
-	n.Use(sharedCors())
-	n.UseHandler(router)
+	c.Use(sharedLocalization())
+	c.Use(authtoken.New(&auth.TokenDataFactory{}, "test"))
+	c.Use(negroni.NewLogger())
+	c.Use(recovery.New())
+	c.Use(gzip.Gzip(gzip.DefaultCompression))
+	c.Use(sharedCors())
+	c.UseHandler(router)
 
-	return n
+	return c
 }
 
 
@@ -136,7 +332,7 @@ func main() {
 
 	publicRouter := httprouter.New()
 	publicRouter.POST("/api/v1/public/login", func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
-		loc, ok := localization.GetLocalization(r.Context())
+		loc, ok := localization.LookupLocalization(r.Context())
 		if ok {
 			log.Printf("public loc %v", loc)
 		}
@@ -146,7 +342,7 @@ func main() {
 
 	privateRouter := httprouter.New()
 	privateRouter.POST("/api/v1/private/check", func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
-		loc, ok := localization.GetLocalization(r.Context())
+		loc, ok := localization.LookupLocalization(r.Context())
 		if ok {
 			log.Printf("privateloc %v", loc)
 		}
@@ -162,8 +358,8 @@ func main() {
 	})
 
 	r.AddGroup(&Group{
-		Router:     privateRouter,
-		Middleware: r2,
+		Router: privateRouter,
+		Chain:  r2,
 	})
 
 	// Start server
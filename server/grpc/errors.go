@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+	"github.com/almerlucke/go-utils/server/response"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CodeForError returns the gRPC code for the kind of err, like response.StatusForError
+// does for HTTP
+func CodeForError(err error) codes.Code {
+	if err == context.Canceled {
+		return codes.Canceled
+	}
+
+	if err == context.DeadlineExceeded {
+		return codes.DeadlineExceeded
+	}
+
+	switch errorUtils.KindOf(err) {
+	case errorUtils.KindNotFound:
+		return codes.NotFound
+	case errorUtils.KindConflict:
+		return codes.AlreadyExists
+	case errorUtils.KindUnauthorized:
+		return codes.Unauthenticated
+	case errorUtils.KindForbidden:
+		return codes.PermissionDenied
+	case errorUtils.KindValidation:
+		return codes.InvalidArgument
+	}
+
+	return codes.Internal
+}
+
+// FromError converts err to a gRPC status error. Status errors are returned as is,
+// an ErrorMap is converted to InvalidArgument and errors from the errors package are
+// mapped by their kind with their message. The message of internal errors is not
+// exposed. Nil is returned if err is nil
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+
+	var errorMap response.ErrorMap
+	if errorUtils.As(err, &errorMap) {
+		return status.Error(codes.InvalidArgument, errorMapMessage(errorMap))
+	}
+
+	code := CodeForError(err)
+	message := "internal server error"
+
+	var typedErr *errorUtils.Error
+	if errorUtils.As(err, &typedErr) && code != codes.Internal {
+		message = typedErr.Message
+	} else if code == codes.Canceled || code == codes.DeadlineExceeded {
+		message = err.Error()
+	}
+
+	return status.Error(code, message)
+}
+
+// errorMapMessage returns a stable single line message for an error map
+func errorMapMessage(errorMap response.ErrorMap) string {
+	sections := make([]string, 0, len(errorMap))
+	for section, reasons := range errorMap {
+		sections = append(sections, fmt.Sprintf("%v: %v", section, strings.Join(reasons, ", ")))
+	}
+
+	sort.Strings(sections)
+
+	return strings.Join(sections, "; ")
+}
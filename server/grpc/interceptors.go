@@ -0,0 +1,279 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/server/auth/jwt"
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/almerlucke/go-utils/server/middleware/localization"
+	"github.com/almerlucke/go-utils/server/request/validate"
+	"github.com/almerlucke/go-utils/server/response"
+	"golang.org/x/text/language"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Interceptor is a pair of unary and stream interceptors with the same behavior
+type Interceptor struct {
+	Unary  googleGrpc.UnaryServerInterceptor
+	Stream googleGrpc.StreamServerInterceptor
+}
+
+// contextFunc derives the context of a call, it returns an error to reject the call
+type contextFunc func(ctx context.Context, method string) (context.Context, error)
+
+// newInterceptor creates unary and stream interceptors from a context function
+func newInterceptor(fn contextFunc) *Interceptor {
+	return &Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (interface{}, error) {
+			ctx, err := fn(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, ss googleGrpc.ServerStream, info *googleGrpc.StreamServerInfo, handler googleGrpc.StreamHandler) error {
+			ctx, err := fn(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		},
+	}
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	googleGrpc.ServerStream
+
+	ctx context.Context
+}
+
+// Context returns the derived context
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
+
+// metadataValue returns the first value of key in the incoming metadata
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+/*
+	Auth
+*/
+
+// Auth unpacks the bearer token of the authorization metadata like the authtoken
+// middleware, the token data is stored under the same context key so
+// authtoken.GetAuthToken works for gRPC calls. Methods in public (full method names,
+// e.g. "/api.Auth/Login") are called without token
+func Auth(factory jwt.TokenDataFactory, secret string, options *jwt.Options, public ...string) *Interceptor {
+	return newInterceptor(func(ctx context.Context, method string) (context.Context, error) {
+		for _, publicMethod := range public {
+			if method == publicMethod {
+				return ctx, nil
+			}
+		}
+
+		fields := strings.Fields(metadataValue(ctx, "authorization"))
+		if len(fields) != 2 || fields[0] != "Bearer" {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+		}
+
+		tokenData, err := jwt.UnpackTokenWithOptions(fields[1], secret, factory, options)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return context.WithValue(ctx, contextUtils.AuthTokenKey, tokenData), nil
+	})
+}
+
+/*
+	Localization
+*/
+
+// Localization matches the lang and accept-language metadata against m like the
+// localization middleware, localization.GetLocalization works for gRPC calls
+func Localization(m language.Matcher) *Interceptor {
+	return newInterceptor(func(ctx context.Context, method string) (context.Context, error) {
+		loc := localization.NewLocalization(m, metadataValue(ctx, "lang"), metadataValue(ctx, "accept-language"))
+		return context.WithValue(ctx, contextUtils.LocalizationKey, loc), nil
+	})
+}
+
+/*
+	Recovery
+*/
+
+// Recovery recovers panics of handlers, logs them with the stack and returns an
+// Internal status
+func Recovery(logger logging.Logger) *Interceptor {
+	recoverPanic := func(method string, err *error) {
+		if p := recover(); p != nil {
+			stack := make([]byte, 8*1024)
+			stack = stack[:runtime.Stack(stack, false)]
+
+			logging.OrDefault(logger).Error("panic recovered", "method", method, "error", fmt.Sprint(p), "stack", string(stack))
+
+			*err = status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return &Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (resp interface{}, err error) {
+			defer recoverPanic(info.FullMethod, &err)
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, ss googleGrpc.ServerStream, info *googleGrpc.StreamServerInfo, handler googleGrpc.StreamHandler) (err error) {
+			defer recoverPanic(info.FullMethod, &err)
+			return handler(srv, ss)
+		},
+	}
+}
+
+/*
+	Errors and logging
+*/
+
+// Errors converts handler errors to status errors with FromError, internal errors
+// are logged
+func Errors(logger logging.Logger) *Interceptor {
+	convert := func(method string, err error) error {
+		if err == nil {
+			return nil
+		}
+
+		converted := FromError(err)
+		if status.Code(converted) == codes.Internal {
+			logging.OrDefault(logger).Error("call failed", "method", method, "error", err)
+		}
+
+		return converted
+	}
+
+	return &Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (interface{}, error) {
+			resp, err := handler(ctx, req)
+			return resp, convert(info.FullMethod, err)
+		},
+		Stream: func(srv interface{}, ss googleGrpc.ServerStream, info *googleGrpc.StreamServerInfo, handler googleGrpc.StreamHandler) error {
+			return convert(info.FullMethod, handler(srv, ss))
+		},
+	}
+}
+
+// Logging logs each call with method, code and duration at info level
+func Logging(logger logging.Logger) *Interceptor {
+	log := func(method string, start time.Time, err error) {
+		logging.OrDefault(logger).Info("grpc call", "method", method, "code", status.Code(err).String(), "duration", time.Since(start))
+	}
+
+	return &Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (interface{}, error) {
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			log(info.FullMethod, start, err)
+
+			return resp, err
+		},
+		Stream: func(srv interface{}, ss googleGrpc.ServerStream, info *googleGrpc.StreamServerInfo, handler googleGrpc.StreamHandler) error {
+			start := time.Now()
+			err := handler(srv, ss)
+			log(info.FullMethod, start, err)
+
+			return err
+		},
+	}
+}
+
+/*
+	Validation
+*/
+
+// Validator can be implemented by request messages (e.g. generated by
+// protoc-gen-validate) to validate themselves
+type Validator interface {
+	Validate() error
+}
+
+// Validation validates request messages before the handler is called. Messages
+// which implement Validator are validated with Validate, other messages with the
+// validate tags of the structural package. Failures return InvalidArgument
+func Validation() *Interceptor {
+	check := func(req interface{}) error {
+		var err error
+
+		if validator, ok := req.(Validator); ok {
+			err = validator.Validate()
+		} else {
+			err = validate.Validate(req)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+			return err
+		}
+
+		message := err.Error()
+
+		var errorMap response.ErrorMap
+		if errorUtils.As(err, &errorMap) {
+			message = errorMapMessage(errorMap)
+		}
+
+		return status.Error(codes.InvalidArgument, message)
+	}
+
+	return &Interceptor{
+		Unary: func(ctx context.Context, req interface{}, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (interface{}, error) {
+			if err := check(req); err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, ss googleGrpc.ServerStream, info *googleGrpc.StreamServerInfo, handler googleGrpc.StreamHandler) error {
+			return handler(srv, &validatingStream{ServerStream: ss, check: check})
+		},
+	}
+}
+
+// validatingStream validates each received message
+type validatingStream struct {
+	googleGrpc.ServerStream
+
+	check func(req interface{}) error
+}
+
+// RecvMsg receives and validates a message
+func (stream *validatingStream) RecvMsg(m interface{}) error {
+	if err := stream.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return stream.check(m)
+}
@@ -0,0 +1,130 @@
+// Package grpc mirrors the HTTP server packages for gRPC services. It provides
+// interceptors for jwt authentication, localization, recovery, error mapping, logging
+// and request validation that store their values under the same context keys as the
+// HTTP middlewares, and a server wrapper with graceful shutdown
+package grpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	googleGrpc "google.golang.org/grpc"
+)
+
+// ServerOptions returns the server options that chain the unary and stream
+// interceptors, the first interceptor is the outermost
+func ServerOptions(interceptors ...*Interceptor) []googleGrpc.ServerOption {
+	unary := []googleGrpc.UnaryServerInterceptor{}
+	stream := []googleGrpc.StreamServerInterceptor{}
+
+	for _, interceptor := range interceptors {
+		if interceptor.Unary != nil {
+			unary = append(unary, interceptor.Unary)
+		}
+
+		if interceptor.Stream != nil {
+			stream = append(stream, interceptor.Stream)
+		}
+	}
+
+	return []googleGrpc.ServerOption{
+		googleGrpc.ChainUnaryInterceptor(unary...),
+		googleGrpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+// DefaultInterceptors returns the interceptors used by NewServer: recovery, logging,
+// error mapping and validation
+func DefaultInterceptors(logger logging.Logger) []*Interceptor {
+	return []*Interceptor{
+		Recovery(logger),
+		Logging(logger),
+		Errors(logger),
+		Validation(),
+	}
+}
+
+// Server wraps a gRPC server with graceful shutdown
+type Server struct {
+	GRPC   *googleGrpc.Server
+	Addr   string
+	Logger logging.Logger
+
+	// ShutdownTimeout is the time in flight calls get to finish on shutdown, after
+	// that the server is stopped hard
+	ShutdownTimeout time.Duration
+}
+
+// NewServer creates a server for addr (e.g. ":50051") with the default interceptors
+// followed by interceptors (e.g. Auth and Localization). Register services on the
+// GRPC field before calling Run
+func NewServer(addr string, logger logging.Logger, interceptors ...*Interceptor) *Server {
+	all := append(DefaultInterceptors(logger), interceptors...)
+
+	return &Server{
+		GRPC:            googleGrpc.NewServer(ServerOptions(all...)...),
+		Addr:            addr,
+		Logger:          logger,
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Run serves until ctx is done and then shuts down gracefully
+func (server *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is done and then shuts down gracefully
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- server.GRPC.Serve(listener)
+	}()
+
+	logging.OrDefault(server.Logger).Info("grpc server started", "addr", listener.Addr().String())
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.Shutdown()
+
+	return <-errChan
+}
+
+// Shutdown stops the server gracefully, when in flight calls do not finish within
+// ShutdownTimeout the server is stopped hard
+func (server *Server) Shutdown() {
+	done := make(chan struct{})
+
+	go func() {
+		server.GRPC.GracefulStop()
+		close(done)
+	}()
+
+	timeout := server.ShutdownTimeout
+	if timeout <= 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		logging.OrDefault(server.Logger).Warn("grpc graceful shutdown timed out", "timeout", timeout)
+		server.GRPC.Stop()
+	}
+}
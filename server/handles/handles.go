@@ -0,0 +1,138 @@
+// Package handles collapses the unmarshal, validate and respond boilerplate of JSON
+// handles. JSON creates a httprouter.Handle from a typed function:
+//
+//	router.POST("/api/v1/flags", handles.JSON(func(ctx *handles.Ctx, in *CreateFlag) (*Flag, error) {
+//		ctx.Status(http.StatusCreated)
+//		return store.Create(in)
+//	}))
+package handles
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/server/request/unmarshal"
+	"github.com/almerlucke/go-utils/server/request/validate"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Logger logs internal errors returned by handles, if nil the default logger is used
+var Logger logging.Logger
+
+// Empty can be used as input or output type of handles without input or payload
+type Empty struct{}
+
+// Ctx is the handle context, it embeds the request context so it can be passed to
+// functions which expect a context.Context
+type Ctx struct {
+	context.Context
+
+	Request        *http.Request
+	ResponseWriter http.ResponseWriter
+	Params         httprouter.Params
+
+	status int
+}
+
+// Param returns the value of a router param
+func (ctx *Ctx) Param(name string) string {
+	return ctx.Params.ByName(name)
+}
+
+// Header returns the response header so headers can be set before the response is
+// written
+func (ctx *Ctx) Header() http.Header {
+	return ctx.ResponseWriter.Header()
+}
+
+// Status sets the status code of a successful response, the default is 200
+func (ctx *Ctx) Status(statusCode int) {
+	ctx.status = statusCode
+}
+
+// JSON creates a handle which unmarshals the router params, query params and (for
+// POST, PUT and PATCH) the JSON body into I and validates it. Fn is called with the
+// result, its output is written as payload of a successful response and errors are
+// written with response.FromError, internal errors are logged. I can be a struct or
+// a pointer to a struct. If fn writes a response itself the output is ignored
+func JSON[I any, O any](fn func(ctx *Ctx, in I) (O, error)) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+		rw = response.WriteOnce(rw)
+
+		in, target := newInput[I]()
+
+		err := unmarshal.Unmarshal(r, pm, hasBody(r), target)
+		if err != nil {
+			response.BadRequest(rw, response.Reason(err.Error()))
+			return
+		}
+
+		err = validate.Validate(target)
+		if err != nil {
+			response.ValidationError(rw, err)
+			return
+		}
+
+		ctx := &Ctx{
+			Context:        r.Context(),
+			Request:        r,
+			ResponseWriter: rw,
+			Params:         pm,
+			status:         http.StatusOK,
+		}
+
+		out, err := fn(ctx, *in)
+		if err != nil {
+			if response.StatusForError(err) == http.StatusInternalServerError {
+				logging.OrDefault(Logger).Error("handle failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+
+			response.FromError(rw, err)
+			return
+		}
+
+		response.Respond(rw, &response.Response{
+			Success: true,
+			Payload: payload(out),
+		}, ctx.status)
+	}
+}
+
+// newInput returns a new input value and the pointer to unmarshal into, for pointer
+// types the element is allocated
+func newInput[I any]() (*I, interface{}) {
+	in := new(I)
+
+	t := reflect.TypeOf(in).Elem()
+	if t.Kind() == reflect.Ptr {
+		elem := reflect.New(t.Elem())
+		reflect.ValueOf(in).Elem().Set(elem)
+
+		return in, elem.Interface()
+	}
+
+	return in, in
+}
+
+// payload returns nil for Empty outputs so no payload is written
+func payload(out interface{}) interface{} {
+	switch out.(type) {
+	case Empty, *Empty:
+		return nil
+	}
+
+	return out
+}
+
+// hasBody reports if the request body should be decoded
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.Body != nil && r.ContentLength != 0
+	}
+
+	return false
+}
@@ -13,13 +13,16 @@ import (
 
 const (
 	// AuthTokenKey to get auth token
-	AuthTokenKey = contextUtils.Key("auth-token")
+	AuthTokenKey = contextUtils.AuthTokenKey
 )
 
 // Middleware middleware
 type Middleware struct {
 	Factory jwt.TokenDataFactory
 	Secret  string
+
+	// Options are used to validate the token claims, can be nil
+	Options *jwt.Options
 }
 
 // New auth token middleware
@@ -30,6 +33,15 @@ func New(factory jwt.TokenDataFactory, secret string) *Middleware {
 	}
 }
 
+// NewWithOptions auth token middleware which validates token claims with options
+func NewWithOptions(factory jwt.TokenDataFactory, secret string, options *jwt.Options) *Middleware {
+	return &Middleware{
+		Factory: factory,
+		Secret:  secret,
+		Options: options,
+	}
+}
+
 func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	// Get Authorization header
 	authHeader := r.Header.Get("Authorization")
@@ -47,7 +59,7 @@ func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 	}
 
 	// Unpack JWT token
-	tokenData, err := jwt.UnpackToken(authFields[1], ware.Secret, ware.Factory)
+	tokenData, err := jwt.UnpackTokenWithOptions(authFields[1], ware.Secret, ware.Factory, ware.Options)
 	if err != nil {
 		response.Unauthorized(rw, err.Error())
 		return
@@ -61,3 +73,13 @@ func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 func GetAuthToken(ctx context.Context) jwt.TokenData {
 	return ctx.Value(AuthTokenKey).(jwt.TokenData)
 }
+
+// LookupAuthToken get auth token from context, ok is false if the middleware did not run
+func LookupAuthToken(ctx context.Context) (jwt.TokenData, bool) {
+	return contextUtils.Value[jwt.TokenData](ctx, AuthTokenKey)
+}
+
+// MustGetAuthToken get auth token from context, panics if the middleware did not run
+func MustGetAuthToken(ctx context.Context) jwt.TokenData {
+	return contextUtils.MustValue[jwt.TokenData](ctx, AuthTokenKey)
+}
@@ -0,0 +1,194 @@
+package authtoken
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/almerlucke/go-utils/server/response"
+	gojwt "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ScopeClaims are the claims checked by RequireScope, a claim can hold a space
+// separated string or a list of scopes
+var ScopeClaims = []string{"scope", "scopes", "scp"}
+
+// Requirement checks the claims of the auth token, it returns the reason when the
+// claims are not sufficient
+type Requirement func(claims gojwt.MapClaims) (ok bool, reason string)
+
+// RequireMiddleware checks the auth token placed in the context by the auth token
+// middleware, requests are rejected with 403 when the requirement is not met
+type RequireMiddleware struct {
+	Requirement Requirement
+}
+
+// Require creates a middleware for a requirement
+func Require(requirement Requirement) *RequireMiddleware {
+	return &RequireMiddleware{
+		Requirement: requirement,
+	}
+}
+
+// RequireClaims requires the token to have the given claims with equal values, if a
+// claim holds a list the value must be in the list
+func RequireClaims(claims map[string]interface{}) *RequireMiddleware {
+	return Require(ClaimsRequirement(claims))
+}
+
+// RequireScope requires the token to have all given scopes
+func RequireScope(scopes ...string) *RequireMiddleware {
+	return Require(ScopeRequirement(scopes...))
+}
+
+func (ware *RequireMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if ware.check(rw, r) {
+		next(rw, r)
+	}
+}
+
+// Handle wraps a httprouter handle with the requirement
+func (ware *RequireMiddleware) Handle(handle httprouter.Handle) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+		if ware.check(rw, r) {
+			handle(rw, r, pm)
+		}
+	}
+}
+
+// RequireClaimsHandle wraps a httprouter handle with RequireClaims
+func RequireClaimsHandle(claims map[string]interface{}, handle httprouter.Handle) httprouter.Handle {
+	return RequireClaims(claims).Handle(handle)
+}
+
+// RequireScopeHandle wraps a httprouter handle with RequireScope
+func RequireScopeHandle(scope string, handle httprouter.Handle) httprouter.Handle {
+	return RequireScope(scope).Handle(handle)
+}
+
+// check writes an error response and returns false if the requirement is not met
+func (ware *RequireMiddleware) check(rw http.ResponseWriter, r *http.Request) bool {
+	tokenData, ok := LookupAuthToken(r.Context())
+	if !ok {
+		response.Unauthorized(rw, "missing auth token")
+		return false
+	}
+
+	ok, reason := ware.Requirement(tokenData.GetClaims())
+	if !ok {
+		response.Forbidden(rw, reason)
+		return false
+	}
+
+	return true
+}
+
+// ClaimsRequirement requires claims with equal values, see RequireClaims
+func ClaimsRequirement(claims map[string]interface{}) Requirement {
+	return func(tokenClaims gojwt.MapClaims) (bool, string) {
+		for key, expected := range claims {
+			value, ok := tokenClaims[key]
+			if !ok || !claimMatches(value, expected) {
+				return false, fmt.Sprintf("missing claim %v", key)
+			}
+		}
+
+		return true, ""
+	}
+}
+
+// ScopeRequirement requires all scopes, see RequireScope
+func ScopeRequirement(scopes ...string) Requirement {
+	return func(tokenClaims gojwt.MapClaims) (bool, string) {
+		granted := map[string]bool{}
+
+		for _, claim := range ScopeClaims {
+			for _, scope := range scopeList(tokenClaims[claim]) {
+				granted[scope] = true
+			}
+		}
+
+		for _, scope := range scopes {
+			if !granted[scope] {
+				return false, fmt.Sprintf("missing scope %v", scope)
+			}
+		}
+
+		return true, ""
+	}
+}
+
+// claimMatches compares a claim with an expected value. Numbers are compared by value
+// as claims are decoded as float64, other values must have the same type and value
+func claimMatches(value interface{}, expected interface{}) bool {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if claimMatches(item, expected) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if a, ok := number(value); ok {
+		if b, ok := number(expected); ok {
+			return a.Cmp(b) == 0
+		}
+
+		return false
+	}
+
+	return reflect.DeepEqual(value, expected)
+}
+
+// number converts numeric values to an exact big.Float
+func number(value interface{}) (*big.Float, bool) {
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetUint64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) {
+			return nil, false
+		}
+
+		return new(big.Float).SetFloat64(v.Float()), true
+	}
+
+	if n, ok := value.(json.Number); ok {
+		f, _, err := big.ParseFloat(string(n), 10, 64, big.ToNearestEven)
+		return f, err == nil
+	}
+
+	return nil, false
+}
+
+// scopeList returns the scopes of a space separated string or list claim
+func scopeList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		scopes := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+
+		return scopes
+	}
+
+	return nil
+}
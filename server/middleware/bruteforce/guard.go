@@ -0,0 +1,107 @@
+// Package bruteforce protects authentication endpoints by counting failed attempts
+// per IP and identity in a sliding window, keys with too many failures are banned
+// for a while. The Guard can be used directly from a login flow, the middleware
+// rejects banned clients with 429 and records the outcome of each attempt
+package bruteforce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/cache/memory"
+	"github.com/almerlucke/go-utils/logging"
+	timeUtils "github.com/almerlucke/go-utils/time"
+)
+
+// Guard tracks failures per key and bans keys with too many failures
+type Guard struct {
+	// MaxFailures within Window before a key is banned
+	MaxFailures int
+
+	// Window is the sliding window in which failures are counted
+	Window time.Duration
+
+	// BanDuration is the time a key is banned
+	BanDuration time.Duration
+
+	// Logger logs bans, if nil the default logger is used
+	Logger logging.Logger
+
+	mutex    sync.Mutex
+	failures *memory.Cache[string, *timeUtils.SlidingWindowCounter]
+	bans     *memory.Cache[string, time.Time]
+}
+
+// NewGuard creates a guard which bans a key for banDuration after maxFailures
+// failures within window. At most maxEntries keys are tracked, 0 means no limit
+func NewGuard(maxFailures int, window time.Duration, banDuration time.Duration, maxEntries int) *Guard {
+	return &Guard{
+		MaxFailures: maxFailures,
+		Window:      window,
+		BanDuration: banDuration,
+		failures:    memory.New[string, *timeUtils.SlidingWindowCounter](window, maxEntries),
+		bans:        memory.New[string, time.Time](banDuration, maxEntries),
+	}
+}
+
+// Key combines an ip and identity (e.g. the login name) to a guard key
+func Key(ip string, identity string) string {
+	return ip + "|" + identity
+}
+
+// Banned returns the remaining ban duration of key, ok is false if the key is not banned
+func (guard *Guard) Banned(key string) (time.Duration, bool) {
+	until, ok := guard.bans.Get(key)
+	if !ok {
+		return 0, false
+	}
+
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+// Failure records a failed attempt for key, returns true if the key is banned
+// because of this failure
+func (guard *Guard) Failure(key string) bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	failures, ok := guard.failures.Get(key)
+	if !ok {
+		failures = timeUtils.NewSlidingWindowCounter(guard.Window)
+	}
+
+	if failures.Add() < guard.MaxFailures {
+		// Set again to extend the ttl of the entry to a full window
+		guard.failures.Set(key, failures)
+		return false
+	}
+
+	guard.failures.Delete(key)
+	guard.bans.Set(key, time.Now().Add(guard.BanDuration))
+
+	logging.OrDefault(guard.Logger).Warn("too many failed attempts, key banned", "key", key, "duration", guard.BanDuration)
+
+	return true
+}
+
+// Success clears the failures of key
+func (guard *Guard) Success(key string) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.failures.Delete(key)
+}
+
+// Unban removes the ban and failures of key
+func (guard *Guard) Unban(key string) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.failures.Delete(key)
+	guard.bans.Delete(key)
+}
@@ -0,0 +1,127 @@
+package bruteforce
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/almerlucke/go-utils/server/middleware/chain"
+	"github.com/almerlucke/go-utils/server/response"
+
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/almerlucke/go-utils/server/middleware/ipfilter"
+)
+
+const (
+	// AttemptKey to get the attempt from the context
+	AttemptKey = contextUtils.BruteForceKey
+)
+
+// Attempt is the current login attempt, it is placed in the request context so the
+// login flow can report its outcome
+type Attempt struct {
+	Guard *Guard
+	Key   string
+
+	reported bool
+}
+
+// ReportFailure records a failed attempt, returns true if the key is now banned
+func (attempt *Attempt) ReportFailure() bool {
+	attempt.reported = true
+	return attempt.Guard.Failure(attempt.Key)
+}
+
+// ReportSuccess clears the failures of the attempt key
+func (attempt *Attempt) ReportSuccess() {
+	attempt.reported = true
+	attempt.Guard.Success(attempt.Key)
+}
+
+// GetAttempt from context, ok is false if the middleware did not run
+func GetAttempt(ctx context.Context) (*Attempt, bool) {
+	return contextUtils.Value[*Attempt](ctx, AttemptKey)
+}
+
+// ReportFailure records a failed attempt for the request context, it does nothing
+// if the middleware did not run
+func ReportFailure(ctx context.Context) {
+	if attempt, ok := GetAttempt(ctx); ok {
+		attempt.ReportFailure()
+	}
+}
+
+// ReportSuccess records a successful attempt for the request context, it does
+// nothing if the middleware did not run
+func ReportSuccess(ctx context.Context) {
+	if attempt, ok := GetAttempt(ctx); ok {
+		attempt.ReportSuccess()
+	}
+}
+
+// Middleware rejects banned clients with 429 and a Retry-After header. When the
+// handler does not report the outcome with ReportFailure or ReportSuccess, responses
+// with a status in FailureStatus count as failure and 2xx responses as success
+type Middleware struct {
+	Guard *Guard
+
+	// Identity returns the identity of the attempt (e.g. a login name from a header),
+	// if nil attempts are tracked per ip only
+	Identity func(r *http.Request) string
+
+	// FailureStatus are the response status codes counted as failure
+	FailureStatus []int
+
+	// Resolver resolves the client ip behind trusted proxies, if nil the remote
+	// address is used. The ip of the ipfilter middleware takes precedence
+	Resolver *ipfilter.Resolver
+}
+
+// New brute force middleware, 401 responses count as failure
+func New(guard *Guard) *Middleware {
+	return &Middleware{
+		Guard:         guard,
+		FailureStatus: []int{http.StatusUnauthorized},
+	}
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	identity := ""
+	if ware.Identity != nil {
+		identity = ware.Identity(r)
+	}
+
+	attempt := &Attempt{
+		Guard: ware.Guard,
+		Key:   Key(ware.ip(r), identity),
+	}
+
+	if remaining, banned := ware.Guard.Banned(attempt.Key); banned {
+		response.TooManyRequests(rw, "too many failed attempts", remaining)
+		return
+	}
+
+	sw := chain.NewStatusWriter(rw)
+
+	next(sw, r.WithContext(context.WithValue(r.Context(), AttemptKey, attempt)))
+
+	if attempt.reported {
+		return
+	}
+
+	status := sw.Status()
+
+	for _, failureStatus := range ware.FailureStatus {
+		if status == failureStatus {
+			attempt.ReportFailure()
+			return
+		}
+	}
+
+	if status >= 200 && status < 300 {
+		attempt.ReportSuccess()
+	}
+}
+
+func (ware *Middleware) ip(r *http.Request) string {
+	return ipfilter.RequestIP(r, ware.Resolver)
+}
@@ -0,0 +1,195 @@
+// Package chain composes http middlewares without negroni. Middlewares are
+// func(http.Handler) http.Handler functions, the negroni style middlewares of this
+// module (ServeHTTP with next) are adapted with Adapt or added with Use, so they can
+// be used with or without negroni
+package chain
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni"
+)
+
+// Middleware wraps a handler
+type Middleware func(http.Handler) http.Handler
+
+// Handler is a negroni style middleware, the middlewares of this module and negroni
+// middlewares conform to it
+type Handler interface {
+	ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+}
+
+// HandlerFunc is a function which conforms to Handler
+type HandlerFunc func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+
+// ServeHTTP calls f
+func (f HandlerFunc) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	f(rw, r, next)
+}
+
+// Adapt converts a negroni style middleware to a Middleware. The response writer is
+// wrapped in a negroni.ResponseWriter if it isn't one already, negroni middlewares
+// (e.g. the logger) depend on it
+func Adapt(handler Handler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			nrw, ok := rw.(negroni.ResponseWriter)
+			if !ok {
+				nrw = negroni.NewResponseWriter(rw)
+			}
+
+			handler.ServeHTTP(nrw, r, next.ServeHTTP)
+		})
+	}
+}
+
+// Chain is a list of middlewares followed by a final handler, the first middleware
+// is the outermost. It mirrors the negroni methods used by this module so it can
+// replace a negroni stack. Add all middlewares before serving, the chain is not
+// safe for concurrent changes
+type Chain struct {
+	middlewares []Middleware
+	handler     http.Handler
+	composed    http.Handler
+}
+
+// New creates a chain with middlewares
+func New(middlewares ...Middleware) *Chain {
+	chain := &Chain{
+		middlewares: middlewares,
+	}
+
+	chain.compose()
+
+	return chain
+}
+
+// UseMiddleware appends middlewares
+func (chain *Chain) UseMiddleware(middlewares ...Middleware) *Chain {
+	chain.middlewares = append(chain.middlewares, middlewares...)
+	chain.compose()
+
+	return chain
+}
+
+// Use appends a negroni style middleware
+func (chain *Chain) Use(handler Handler) *Chain {
+	return chain.UseMiddleware(Adapt(handler))
+}
+
+// UseFunc appends a negroni style middleware function
+func (chain *Chain) UseFunc(handlerFunc func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)) *Chain {
+	return chain.Use(HandlerFunc(handlerFunc))
+}
+
+// UseHandler sets the final handler, e.g. a router. An existing negroni stack can be
+// set as final handler to reuse it
+func (chain *Chain) UseHandler(handler http.Handler) {
+	chain.handler = handler
+	chain.compose()
+}
+
+// compose builds the handler served by ServeHTTP
+func (chain *Chain) compose() {
+	chain.composed = chain.Then(chain.handler)
+}
+
+// Then returns handler wrapped with the middlewares of the chain, the chain itself
+// is not changed
+func (chain *Chain) Then(handler http.Handler) http.Handler {
+	if handler == nil {
+		handler = http.NotFoundHandler()
+	}
+
+	for i := len(chain.middlewares) - 1; i >= 0; i-- {
+		handler = chain.middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// ThenFunc returns handlerFunc wrapped with the middlewares of the chain
+func (chain *Chain) ThenFunc(handlerFunc http.HandlerFunc) http.Handler {
+	return chain.Then(handlerFunc)
+}
+
+// ServeHTTP runs the middlewares and the final handler
+func (chain *Chain) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if chain.composed == nil {
+		// Zero value chain, compose per request instead of caching to avoid a race
+		chain.Then(chain.handler).ServeHTTP(rw, r)
+		return
+	}
+
+	chain.composed.ServeHTTP(rw, r)
+}
+
+/*
+	Status writer
+*/
+
+// StatusWriter is a response writer which reports the written status
+type StatusWriter interface {
+	http.ResponseWriter
+
+	// Status returns the written status code, 0 if nothing was written
+	Status() int
+
+	// Written reports if a status was written
+	Written() bool
+}
+
+// NewStatusWriter returns rw if it already reports its status (e.g. a negroni
+// response writer), otherwise rw is wrapped
+func NewStatusWriter(rw http.ResponseWriter) StatusWriter {
+	if sw, ok := rw.(StatusWriter); ok {
+		return sw
+	}
+
+	return &statusWriter{ResponseWriter: rw}
+}
+
+// statusWriter records the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	if sw.status == 0 {
+		sw.status = statusCode
+	}
+
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	return sw.ResponseWriter.Write(data)
+}
+
+// Status for StatusWriter
+func (sw *statusWriter) Status() int {
+	return sw.status
+}
+
+// Written for StatusWriter
+func (sw *statusWriter) Written() bool {
+	return sw.status != 0
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
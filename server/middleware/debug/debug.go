@@ -0,0 +1,244 @@
+// Package debug captures request and response bodies for production triage. The
+// middleware records exchanges in a Recorder which can be inspected on an
+// authenticated endpoint. Sensitive fields of JSON and form bodies, query parameters
+// and headers are redacted. Other bodies and bodies cut off at the size limit are not
+// stored, only their size. Enable it per environment only, e.g.
+//
+//	ware := debug.New(recorder)
+//	ware.Enabled = os.Getenv("DEBUG_CAPTURE") == "true"
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/server/response"
+)
+
+// DefaultMaxBodySize is the default size limit of captured bodies
+const DefaultMaxBodySize = 16 * 1024
+
+// DefaultRedactKeys are the JSON keys, parameters and headers redacted by default
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "token", "accessToken", "refreshToken", "apiKey",
+	"signature", "authorization", "cookie", "set-cookie", "x-api-key",
+}
+
+// Middleware captures exchanges when enabled
+type Middleware struct {
+	Recorder *Recorder
+	Enabled  bool
+
+	// MaxBodySize is the maximum number of bytes captured per body
+	MaxBodySize int
+
+	// RedactKeys are the JSON keys, query and form parameters and headers (case
+	// insensitive) that are redacted
+	RedactKeys []string
+
+	// Skip can be used to exclude requests (e.g. health checks), can be nil
+	Skip func(r *http.Request) bool
+}
+
+// New debug middleware, capturing is disabled until Enabled is set
+func New(recorder *Recorder) *Middleware {
+	return &Middleware{
+		Recorder:    recorder,
+		MaxBodySize: DefaultMaxBodySize,
+		RedactKeys:  DefaultRedactKeys,
+	}
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !ware.Enabled || (ware.Skip != nil && ware.Skip(r)) {
+		next(rw, r)
+		return
+	}
+
+	exchange := &Exchange{
+		Time:          time.Now(),
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Query:         ware.redactQuery(r.URL.RawQuery),
+		RequestHeader: ware.redactHeader(r.Header),
+	}
+
+	requestBody, requestTruncated := ware.captureRequestBody(r)
+
+	cw := &captureWriter{
+		ResponseWriter: rw,
+		limit:          ware.MaxBodySize,
+	}
+
+	next(cw, r)
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	exchange.Duration = time.Since(exchange.Time)
+	exchange.Status = cw.status
+	exchange.ResponseHeader = ware.redactHeader(rw.Header())
+	exchange.RequestBody = ware.body(requestBody, requestTruncated, r.Header.Get("Content-Type"))
+	exchange.ResponseBody = ware.body(cw.body.Bytes(), cw.truncated, rw.Header().Get("Content-Type"))
+	exchange.Truncated = requestTruncated || cw.truncated
+
+	ware.Recorder.Add(exchange)
+}
+
+// captureRequestBody reads up to the size limit of the request body, the body is
+// restored so the handler reads all of it
+func (ware *Middleware) captureRequestBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false
+	}
+
+	captured, _ := io.ReadAll(io.LimitReader(r.Body, int64(ware.MaxBodySize)+1))
+
+	r.Body = &restoredBody{
+		Reader: io.MultiReader(bytes.NewReader(captured), r.Body),
+		Closer: r.Body,
+	}
+
+	if len(captured) > ware.MaxBodySize {
+		return captured[:ware.MaxBodySize], true
+	}
+
+	return captured, false
+}
+
+// body returns the redacted JSON value or form of data, other and truncated bodies
+// are replaced with their size because they can't be redacted
+func (ware *Middleware) body(data []byte, truncated bool, contentType string) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if !truncated {
+		var value interface{}
+		if json.Unmarshal(data, &value) == nil {
+			return structural.RedactJSON(value, ware.RedactKeys...)
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+			return ware.redactQuery(string(data))
+		}
+	}
+
+	return fmt.Sprintf("[%d bytes omitted]", len(data))
+}
+
+// redactQuery replaces the values of redacted parameters of a query or form
+func (ware *Middleware) redactQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	pairs := strings.Split(query, "&")
+
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+
+		unescaped, err := url.QueryUnescape(key)
+		if err != nil {
+			unescaped = key
+		}
+
+		if ware.redacted(unescaped) {
+			pairs[i] = key + "=" + structural.RedactedString
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// redacted returns true if key is one of the redact keys
+func (ware *Middleware) redacted(key string) bool {
+	for _, redactKey := range ware.RedactKeys {
+		if strings.EqualFold(key, redactKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ware *Middleware) redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+
+	for key := range redacted {
+		if ware.redacted(key) {
+			redacted[key] = []string{structural.RedactedString}
+		}
+	}
+
+	return redacted
+}
+
+// restoredBody replays the captured part of a body before the rest
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter copies the response body up to a limit
+type captureWriter struct {
+	http.ResponseWriter
+
+	limit     int
+	body      bytes.Buffer
+	status    int
+	truncated bool
+}
+
+func (cw *captureWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *captureWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if remaining := cw.limit - cw.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			cw.body.Write(data[:remaining])
+			cw.truncated = true
+		} else {
+			cw.body.Write(data)
+		}
+	} else if len(data) > 0 {
+		cw.truncated = true
+	}
+
+	return cw.ResponseWriter.Write(data)
+}
+
+// Written reports if a response was started, see response.WriteOnce
+func (cw *captureWriter) Written() bool {
+	return cw.status != 0
+}
+
+// Responder returns the responder of the underlying writer
+func (cw *captureWriter) Responder() response.Responder {
+	return response.ResponderOf(cw.ResponseWriter)
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (cw *captureWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,108 @@
+package debug
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/server/auth/basic"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Exchange is a captured request and response
+type Exchange struct {
+	Time           time.Time     `json:"time"`
+	Duration       time.Duration `json:"duration"`
+	Method         string        `json:"method"`
+	Path           string        `json:"path"`
+	Query          string        `json:"query,omitempty"`
+	Status         int           `json:"status"`
+	RequestHeader  http.Header   `json:"requestHeader,omitempty"`
+	RequestBody    interface{}   `json:"requestBody,omitempty"`
+	ResponseHeader http.Header   `json:"responseHeader,omitempty"`
+	ResponseBody   interface{}   `json:"responseBody,omitempty"`
+
+	// Truncated is true if one of the bodies was larger than the size limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Recorder keeps the last N exchanges in a ring buffer, it is safe for concurrent use
+type Recorder struct {
+	mutex     sync.Mutex
+	exchanges []*Exchange
+	next      int
+	full      bool
+}
+
+// NewRecorder creates a recorder for the last size exchanges
+func NewRecorder(size int) *Recorder {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Recorder{
+		exchanges: make([]*Exchange, size),
+	}
+}
+
+// Add an exchange, the oldest exchange is dropped when the recorder is full
+func (recorder *Recorder) Add(exchange *Exchange) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.exchanges[recorder.next] = exchange
+	recorder.next = (recorder.next + 1) % len(recorder.exchanges)
+
+	if recorder.next == 0 {
+		recorder.full = true
+	}
+}
+
+// Exchanges returns the recorded exchanges, newest first
+func (recorder *Recorder) Exchanges() []*Exchange {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	count := recorder.next
+	if recorder.full {
+		count = len(recorder.exchanges)
+	}
+
+	exchanges := make([]*Exchange, 0, count)
+
+	for i := 1; i <= count; i++ {
+		index := (recorder.next - i + len(recorder.exchanges)) % len(recorder.exchanges)
+		exchanges = append(exchanges, recorder.exchanges[index])
+	}
+
+	return exchanges
+}
+
+// Clear removes all exchanges
+func (recorder *Recorder) Clear() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.exchanges = make([]*Exchange, len(recorder.exchanges))
+	recorder.next = 0
+	recorder.full = false
+}
+
+// List writes the recorded exchanges, newest first
+func (recorder *Recorder) List(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	response.OK(rw, recorder.Exchanges())
+}
+
+// Delete clears the recorded exchanges
+func (recorder *Recorder) Delete(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	recorder.Clear()
+	response.OK(rw, nil)
+}
+
+// Register the debug endpoint on a router at path (e.g. "/debug/exchanges") protected
+// with basic authentication. Do not place the route behind the debug middleware
+func (recorder *Recorder) Register(router *httprouter.Router, path string, user string, password string) {
+	router.GET(path, basic.Handle(user, password, recorder.List))
+	router.DELETE(path, basic.Handle(user, password, recorder.Delete))
+}
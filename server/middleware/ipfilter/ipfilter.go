@@ -0,0 +1,121 @@
+// Package ipfilter resolves the client ip of requests behind trusted proxies and
+// rejects clients with CIDR based allow and deny lists. The resolved ip is stored in
+// the request context, use GetClientIP to read it in rate limiting or audit code
+package ipfilter
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/almerlucke/go-utils/logging"
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/almerlucke/go-utils/server/response"
+)
+
+const (
+	// ClientIPKey to get the client ip from the context
+	ClientIPKey = contextUtils.ClientIPKey
+)
+
+// GetClientIP from context, ok is false if the middleware did not run
+func GetClientIP(ctx context.Context) (string, bool) {
+	return contextUtils.Value[string](ctx, ClientIPKey)
+}
+
+// MustGetClientIP from context, panics if the middleware did not run
+func MustGetClientIP(ctx context.Context) string {
+	return contextUtils.MustValue[string](ctx, ClientIPKey)
+}
+
+// RequestIP returns the client ip stored by the middleware, if it did not run the ip
+// is resolved with resolver. A nil resolver returns the remote address
+func RequestIP(r *http.Request, resolver *Resolver) string {
+	if ip, ok := GetClientIP(r.Context()); ok {
+		return ip
+	}
+
+	if resolver == nil {
+		resolver = &Resolver{}
+	}
+
+	if ip := resolver.ClientIP(r); ip != nil {
+		return ip.String()
+	}
+
+	return r.RemoteAddr
+}
+
+// Middleware stores the client ip in the request context and rejects clients with
+// 403. Clients in Deny are always rejected, when Allow is not empty only clients in
+// Allow are accepted
+type Middleware struct {
+	Resolver *Resolver
+	Allow    []*net.IPNet
+	Deny     []*net.IPNet
+	Logger   logging.Logger
+}
+
+// New ip filter middleware, if resolver is nil the remote address is the client ip
+func New(resolver *Resolver) *Middleware {
+	if resolver == nil {
+		resolver = &Resolver{}
+	}
+
+	return &Middleware{
+		Resolver: resolver,
+	}
+}
+
+// AllowCIDRs adds ranges to the allow list
+func (ware *Middleware) AllowCIDRs(cidrs ...string) error {
+	nets, err := ParseCIDRs(cidrs...)
+	if err != nil {
+		return err
+	}
+
+	ware.Allow = append(ware.Allow, nets...)
+
+	return nil
+}
+
+// DenyCIDRs adds ranges to the deny list
+func (ware *Middleware) DenyCIDRs(cidrs ...string) error {
+	nets, err := ParseCIDRs(cidrs...)
+	if err != nil {
+		return err
+	}
+
+	ware.Deny = append(ware.Deny, nets...)
+
+	return nil
+}
+
+// Allowed returns true if ip passes the allow and deny lists
+func (ware *Middleware) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return len(ware.Allow) == 0 && len(ware.Deny) == 0
+	}
+
+	if Contains(ware.Deny, ip) {
+		return false
+	}
+
+	return len(ware.Allow) == 0 || Contains(ware.Allow, ip)
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ip := ware.Resolver.ClientIP(r)
+
+	if !ware.Allowed(ip) {
+		logging.OrDefault(ware.Logger).Info("client ip rejected", "ip", ip.String(), "path", r.URL.Path)
+		response.Forbidden(rw, "access denied")
+		return
+	}
+
+	if ip != nil {
+		r = r.WithContext(context.WithValue(r.Context(), ClientIPKey, ip.String()))
+	}
+
+	next(rw, r)
+}
@@ -0,0 +1,188 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDRs parses a list of CIDR ranges, single ips are parsed as /32 or /128 range
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", cidr)
+			}
+
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// MustParseCIDRs parses a list of CIDR ranges and panics on error
+func MustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets, err := ParseCIDRs(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+
+	return nets
+}
+
+// Contains returns true if one of nets contains ip
+func Contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Proxy headers a Resolver can read the client ip from
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// Resolver resolves the client ip of a request. The proxy header is only used when
+// the request comes from a trusted proxy. Only the configured header is read, the
+// others can be sent by the client and are ignored. The Forwarded (RFC 7239) and
+// X-Forwarded-For chains are walked from right to left and the first address that
+// is not a trusted proxy is the client
+type Resolver struct {
+	TrustedProxies []*net.IPNet
+
+	// Header is the header set by the trusted proxies: HeaderXForwardedFor (default),
+	// HeaderForwarded or HeaderXRealIP
+	Header string
+}
+
+// NewResolver creates a resolver which trusts the given proxy ranges to set the
+// X-Forwarded-For header, without ranges the remote address is always the client ip
+func NewResolver(trustedProxies ...string) (*Resolver, error) {
+	nets, err := ParseCIDRs(trustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{TrustedProxies: nets}, nil
+}
+
+// ClientIP returns the client ip of the request
+func (resolver *Resolver) ClientIP(r *http.Request) net.IP {
+	remote := parseAddress(r.RemoteAddr)
+
+	if remote == nil || !resolver.trusted(remote) {
+		return remote
+	}
+
+	var chain []string
+
+	switch resolver.Header {
+	case "", HeaderXForwardedFor:
+		chain = xForwardedFor(r.Header.Values(HeaderXForwardedFor))
+	case HeaderForwarded:
+		chain = forwardedFor(r.Header.Values(HeaderForwarded))
+	case HeaderXRealIP:
+		if ip := parseAddress(r.Header.Get(HeaderXRealIP)); ip != nil {
+			return ip
+		}
+
+		return remote
+	default:
+		return remote
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseAddress(chain[i])
+		if ip == nil {
+			// Obfuscated or unknown identifiers can't be resolved further
+			break
+		}
+
+		if !resolver.trusted(ip) {
+			return ip
+		}
+
+		remote = ip
+	}
+
+	return remote
+}
+
+func (resolver *Resolver) trusted(ip net.IP) bool {
+	return Contains(resolver.TrustedProxies, ip)
+}
+
+// xForwardedFor returns the addresses of X-Forwarded-For headers in order
+func xForwardedFor(headers []string) []string {
+	chain := []string{}
+
+	for _, header := range headers {
+		for _, address := range strings.Split(header, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				chain = append(chain, address)
+			}
+		}
+	}
+
+	return chain
+}
+
+// forwardedFor returns the for= addresses of Forwarded headers in order
+func forwardedFor(headers []string) []string {
+	chain := []string{}
+
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					chain = append(chain, strings.Trim(kv[1], `"`))
+				}
+			}
+		}
+	}
+
+	return chain
+}
+
+// parseAddress parses an ip with optional port, IPv6 addresses can be in brackets
+func parseAddress(address string) net.IP {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil
+	}
+
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	return net.ParseIP(strings.Trim(address, "[]"))
+}
@@ -12,7 +12,7 @@ import (
 
 const (
 	// LocalizationKey to get localization tag
-	LocalizationKey = contextUtils.Key("localization")
+	LocalizationKey = contextUtils.LocalizationKey
 )
 
 // Localization localization data
@@ -47,12 +47,18 @@ func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 
 	accept := r.Header.Get("Accept-Language")
 
-	tag, _ := language.MatchStrings(ware.Matcher, cookieLang, accept)
+	next(rw, r.WithContext(context.WithValue(r.Context(), LocalizationKey, NewLocalization(ware.Matcher, cookieLang, accept))))
+}
+
+// NewLocalization matches the language strings (e.g. Accept-Language values) in
+// order of preference against m and returns the localization of the best match
+func NewLocalization(m language.Matcher, langs ...string) *Localization {
+	tag, _ := language.MatchStrings(m, langs...)
 
-	next(rw, r.WithContext(context.WithValue(r.Context(), LocalizationKey, &Localization{
+	return &Localization{
 		Tag:       tag,
 		Translate: translateFunc(tag.String()),
-	})))
+	}
 }
 
 // New language middleware
@@ -66,3 +72,13 @@ func New(m language.Matcher) *Middleware {
 func GetLocalization(ctx context.Context) *Localization {
 	return ctx.Value(LocalizationKey).(*Localization)
 }
+
+// LookupLocalization from context, ok is false if the middleware did not run
+func LookupLocalization(ctx context.Context) (*Localization, bool) {
+	return contextUtils.Value[*Localization](ctx, LocalizationKey)
+}
+
+// MustGetLocalization from context, panics if the middleware did not run
+func MustGetLocalization(ctx context.Context) *Localization {
+	return contextUtils.MustValue[*Localization](ctx, LocalizationKey)
+}
@@ -0,0 +1,136 @@
+// Package maintenance rejects requests with 503 while maintenance mode is switched on.
+// The switch can be flipped at runtime, with an in memory switch or a feature flag
+package maintenance
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/almerlucke/go-utils/featureflags"
+	"github.com/almerlucke/go-utils/server/middleware/localization"
+	"github.com/almerlucke/go-utils/server/response"
+)
+
+// DefaultMessageID is the translation id of the maintenance message
+const DefaultMessageID = "maintenance_mode"
+
+// DefaultMessage is used when the message id has no translation
+const DefaultMessage = "service is down for maintenance"
+
+// Switch reports if maintenance mode is on
+type Switch interface {
+	Enabled() bool
+}
+
+// AtomicSwitch is an in memory switch which is safe for concurrent use
+type AtomicSwitch struct {
+	enabled atomic.Bool
+}
+
+// NewSwitch creates a new switch which is off
+func NewSwitch() *AtomicSwitch {
+	return &AtomicSwitch{}
+}
+
+// Enable switches maintenance mode on
+func (sw *AtomicSwitch) Enable() {
+	sw.enabled.Store(true)
+}
+
+// Disable switches maintenance mode off
+func (sw *AtomicSwitch) Disable() {
+	sw.enabled.Store(false)
+}
+
+// Enabled for Switch
+func (sw *AtomicSwitch) Enabled() bool {
+	return sw.enabled.Load()
+}
+
+// FlagSwitch is a switch backed by a flag of a feature flag store, maintenance mode
+// is on when the flag is enabled. Targeting and rollout of the flag are ignored
+type FlagSwitch struct {
+	Store *featureflags.Store
+	Key   string
+}
+
+// NewFlagSwitch creates a switch for the flag key in store
+func NewFlagSwitch(store *featureflags.Store, key string) *FlagSwitch {
+	return &FlagSwitch{
+		Store: store,
+		Key:   key,
+	}
+}
+
+// Enabled for Switch
+func (sw *FlagSwitch) Enabled() bool {
+	flag, ok := sw.Store.Flag(sw.Key)
+	return ok && flag.Enabled
+}
+
+// Middleware writes a 503 with Retry-After for all requests while the switch is on,
+// except for paths in the allow list
+type Middleware struct {
+	Switch Switch
+
+	// Allow is a list of path prefixes that are served in maintenance mode, e.g.
+	// "/health" or "/api/v1/admin/"
+	Allow []string
+
+	// RetryAfter is the Retry-After duration, no header is set if it is zero
+	RetryAfter time.Duration
+
+	// MessageID is translated with the localization of the request, Message is used
+	// when there is no localization or translation
+	MessageID string
+	Message   string
+}
+
+// New maintenance middleware
+func New(sw Switch, allow ...string) *Middleware {
+	return &Middleware{
+		Switch:    sw,
+		Allow:     allow,
+		MessageID: DefaultMessageID,
+		Message:   DefaultMessage,
+	}
+}
+
+// Allowed returns true if path is served in maintenance mode
+func (ware *Middleware) Allowed(path string) bool {
+	for _, prefix := range ware.Allow {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !ware.Switch.Enabled() || ware.Allowed(r.URL.Path) {
+		next(rw, r)
+		return
+	}
+
+	response.ServiceUnavailable(rw, ware.message(r), ware.RetryAfter)
+}
+
+// message returns the translated maintenance message
+func (ware *Middleware) message(r *http.Request) string {
+	if ware.MessageID != "" {
+		if loc, ok := localization.LookupLocalization(r.Context()); ok {
+			if message := loc.Translate(ware.MessageID); message != ware.MessageID {
+				return message
+			}
+		}
+	}
+
+	if ware.Message == "" {
+		return DefaultMessage
+	}
+
+	return ware.Message
+}
@@ -3,18 +3,17 @@ package recovery
 // Recovery is a Negroni middleware that recovers from any panics and writes a 500 if there was one.
 import (
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"runtime"
 	"runtime/debug"
 
+	"github.com/almerlucke/go-utils/logging"
 	"github.com/almerlucke/go-utils/server/response"
 )
 
 // Middleware middleware
 type Middleware struct {
-	Logger           *log.Logger
+	Logger           logging.Logger
 	PrintStack       bool
 	ErrorHandlerFunc func(interface{})
 	StackAll         bool
@@ -24,7 +23,7 @@ type Middleware struct {
 // New returns a new instance of recovery middlewar
 func New() *Middleware {
 	return &Middleware{
-		Logger:     log.New(os.Stdout, "[recovery] ", 0),
+		Logger:     logging.Default().With("component", "recovery"),
 		PrintStack: true,
 		StackAll:   false,
 		StackSize:  1024 * 8,
@@ -39,7 +38,7 @@ func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 			stack = stack[:runtime.Stack(stack, ware.StackAll)]
 
 			f := "PANIC: %s\n%s"
-			ware.Logger.Printf(f, err, stack)
+			logging.OrDefault(ware.Logger).Error("panic recovered", "error", err, "stack", string(stack))
 
 			if ware.PrintStack {
 				response.InternalServerError(rw, fmt.Sprintf(f, err, stack))
@@ -51,8 +50,7 @@ func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 				func() {
 					defer func() {
 						if innerErr := recover(); innerErr != nil {
-							ware.Logger.Printf("provided ErrorHandlerFunc panic'd: %s, trace:\n%s", innerErr, debug.Stack())
-							ware.Logger.Printf("%s\n", debug.Stack())
+							logging.OrDefault(ware.Logger).Error("provided ErrorHandlerFunc panic'd", "error", innerErr, "stack", string(debug.Stack()))
 						}
 					}()
 					ware.ErrorHandlerFunc(err)
@@ -0,0 +1,197 @@
+// Package timeout limits the time a handler can take. The request context gets a
+// deadline and the handler output is buffered, if the deadline is exceeded before the
+// handler returns a 504 response is written and later writes of the handler fail with
+// http.ErrHandlerTimeout. Use the middleware for a group and Handle for a single route,
+// the http.Server timeouts still apply on top of these
+package timeout
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DefaultReason is the reason of the 504 response
+const DefaultReason = "request timed out"
+
+// Middleware limits the time of the next handlers
+type Middleware struct {
+	Timeout time.Duration
+	Reason  string
+	Logger  logging.Logger
+}
+
+// New timeout middleware
+func New(timeout time.Duration) *Middleware {
+	return &Middleware{
+		Timeout: timeout,
+		Reason:  DefaultReason,
+	}
+}
+
+func (ware *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ware.serve(rw, r, next)
+}
+
+// Handler wraps an http.Handler with the timeout of the middleware
+func (ware *Middleware) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ware.serve(rw, r, handler.ServeHTTP)
+	})
+}
+
+// Handle wraps a httprouter handle with the timeout of the middleware
+func (ware *Middleware) Handle(handle httprouter.Handle) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+		ware.serve(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+			handle(rw, r, pm)
+		})
+	}
+}
+
+// Handle wraps a httprouter handle with a timeout, for per route timeouts
+func Handle(timeout time.Duration, handle httprouter.Handle) httprouter.Handle {
+	return New(timeout).Handle(handle)
+}
+
+// serve runs next in a goroutine with a deadline on the request context, panics of
+// next are propagated to the calling goroutine
+func (ware *Middleware) serve(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if ware.Timeout <= 0 {
+		next(rw, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ware.Timeout)
+	defer cancel()
+
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{
+		rw:     rw,
+		header: http.Header{},
+	}
+
+	done := make(chan struct{})
+	panicChan := make(chan interface{}, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+
+		next(tw, r)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		tw.mutex.Lock()
+		defer tw.mutex.Unlock()
+
+		tw.flush()
+	case <-ctx.Done():
+		tw.mutex.Lock()
+		defer tw.mutex.Unlock()
+
+		tw.timedOut = true
+
+		if ctx.Err() != context.DeadlineExceeded {
+			// The client went away, there is nobody to write to
+			return
+		}
+
+		logging.OrDefault(ware.Logger).Warn("request timed out", "method", r.Method, "path", r.URL.Path, "timeout", ware.Timeout)
+
+		reason := ware.Reason
+		if reason == "" {
+			reason = DefaultReason
+		}
+
+		response.GatewayTimeout(rw, reason)
+	}
+}
+
+// timeoutWriter buffers the handler output until the handler is done
+type timeoutWriter struct {
+	rw     http.ResponseWriter
+	header http.Header
+	body   bytes.Buffer
+	status int
+
+	mutex    sync.Mutex
+	timedOut bool
+}
+
+// Header returns the buffered header
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write buffers data, returns http.ErrHandlerTimeout after the timeout
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+
+	return tw.body.Write(data)
+}
+
+// WriteHeader buffers the status code, only the first status code is kept
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+
+	tw.status = statusCode
+}
+
+// Written reports if the handler started a response, see response.WriteOnce
+func (tw *timeoutWriter) Written() bool {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	return tw.status != 0
+}
+
+// Responder returns the responder of the underlying writer, so the handler writes
+// responses in the same format
+func (tw *timeoutWriter) Responder() response.Responder {
+	return response.ResponderOf(tw.rw)
+}
+
+// flush copies the buffered response to the underlying writer, must be called with
+// the mutex locked
+func (tw *timeoutWriter) flush() {
+	header := tw.rw.Header()
+	for key, values := range tw.header {
+		header[key] = values
+	}
+
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+
+	tw.rw.WriteHeader(tw.status)
+	tw.rw.Write(tw.body.Bytes())
+}
@@ -5,12 +5,9 @@ package unmarshal
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"reflect"
-	"strconv"
 	"strings"
 
 	"github.com/almerlucke/go-utils/reflection/structural"
@@ -32,110 +29,6 @@ func addQueryParamsToMap(values url.Values, mp map[string]string) {
 	}
 }
 
-// setFieldValue - convert param value to reflect.Value
-func setFieldValue(paramValue string, field reflect.Value) error {
-	switch field.Kind() {
-	case reflect.Int:
-		intValue, err := strconv.ParseInt(paramValue, 10, strconv.IntSize)
-		if err != nil {
-			return err
-		}
-
-		field.SetInt(intValue)
-	case reflect.Int8:
-		intValue, err := strconv.ParseInt(paramValue, 10, 8)
-		if err != nil {
-			return err
-		}
-
-		field.SetInt(intValue)
-	case reflect.Int16:
-		intValue, err := strconv.ParseInt(paramValue, 10, 16)
-		if err != nil {
-			return err
-		}
-
-		field.SetInt(intValue)
-	case reflect.Int32:
-		intValue, err := strconv.ParseInt(paramValue, 10, 32)
-		if err != nil {
-			return err
-		}
-
-		field.SetInt(intValue)
-	case reflect.Int64:
-		intValue, err := strconv.ParseInt(paramValue, 10, 64)
-		if err != nil {
-			return err
-		}
-
-		field.SetInt(intValue)
-	case reflect.Uint:
-		uintValue, err := strconv.ParseUint(paramValue, 10, strconv.IntSize)
-		if err != nil {
-			return err
-		}
-
-		field.SetUint(uintValue)
-	case reflect.Uint8:
-		uintValue, err := strconv.ParseUint(paramValue, 10, 8)
-		if err != nil {
-			return err
-		}
-
-		field.SetUint(uintValue)
-	case reflect.Uint16:
-		uintValue, err := strconv.ParseUint(paramValue, 10, 16)
-		if err != nil {
-			return err
-		}
-
-		field.SetUint(uintValue)
-	case reflect.Uint32:
-		uintValue, err := strconv.ParseUint(paramValue, 10, 32)
-		if err != nil {
-			return err
-		}
-
-		field.SetUint(uintValue)
-	case reflect.Uint64:
-		uintValue, err := strconv.ParseUint(paramValue, 10, 64)
-		if err != nil {
-			return err
-		}
-
-		field.SetUint(uintValue)
-	case reflect.Float32:
-		floatValue, err := strconv.ParseFloat(paramValue, 32)
-		if err != nil {
-			return err
-		}
-
-		field.SetFloat(floatValue)
-	case reflect.Float64:
-		floatValue, err := strconv.ParseFloat(paramValue, 64)
-		if err != nil {
-			return err
-		}
-
-		field.SetFloat(floatValue)
-	case reflect.Bool:
-		boolValue, err := strconv.ParseBool(paramValue)
-		if err != nil {
-			return err
-		}
-
-		field.SetBool(boolValue)
-	case reflect.String:
-		field.SetString(paramValue)
-
-	default:
-		return fmt.Errorf("Unsupported request value type %v", field.Type())
-	}
-
-	return nil
-}
-
 // unmarshalParamsMap unmarshals params map to object structure fields
 func unmarshalParamsMap(paramsMap map[string]string, obj interface{}) error {
 	desc, ok := structural.NewStructDescriptor(obj)
@@ -154,7 +47,7 @@ func unmarshalParamsMap(paramsMap map[string]string, obj interface{}) error {
 
 		for key, value := range paramsMap {
 			if strings.ToLower(key) == lowercaseFieldName || key == fieldTag {
-				err := setFieldValue(value, field.Value())
+				err := structural.SetValueFromString(field.Value(), value)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,37 @@
+// Package validate validates request structs with the validate tag engine of the
+// structural package and converts validation errors to a response error map
+package validate
+
+import (
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/server/response"
+)
+
+// Validate validates obj, if one or more fields are invalid a response.ErrorMap
+// is returned with a section per field key, so the error can be written with
+// response.ValidationError. Other errors (e.g. unknown rules) are returned as is
+func Validate(obj interface{}) error {
+	err := structural.Validate(obj)
+	if err == nil {
+		return nil
+	}
+
+	fieldErrors, ok := err.(structural.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	return ToErrorMap(fieldErrors)
+}
+
+// ToErrorMap converts structural validation errors to a response error map
+func ToErrorMap(fieldErrors structural.ValidationErrors) response.ErrorMap {
+	errorMap := response.ErrorMap{}
+
+	for _, fieldError := range fieldErrors {
+		section := response.ErrorSection(fieldError.Key)
+		errorMap[section] = append(errorMap[section], fieldError.Message)
+	}
+
+	return errorMap
+}
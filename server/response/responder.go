@@ -0,0 +1,229 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Responder writes a response in a specific output format
+type Responder interface {
+	Respond(rw http.ResponseWriter, r *Response, statusCode int) error
+}
+
+// ResponderFunc is a function which conforms to Responder
+type ResponderFunc func(rw http.ResponseWriter, r *Response, statusCode int) error
+
+// Respond calls f
+func (f ResponderFunc) Respond(rw http.ResponseWriter, r *Response, statusCode int) error {
+	return f(rw, r, statusCode)
+}
+
+var (
+	// Envelope writes responses with success flag, payload and errors
+	Envelope Responder = ResponderFunc(envelope)
+
+	// Bare writes the payload of successful responses as body without wrapper, error
+	// responses are written as an object with the error map under "errors"
+	Bare Responder = ResponderFunc(bare)
+
+	// JSONAPI writes responses as JSON:API documents, the payload is written as
+	// "data" and each error reason as an error object under "errors"
+	JSONAPI Responder = ResponderFunc(jsonAPI)
+)
+
+// DefaultResponder is used by the convenience methods when the response writer has
+// no responder of its own, see WithResponder
+var DefaultResponder = Envelope
+
+// Respond writes r with the responder of rw, or DefaultResponder if rw has none
+func Respond(rw http.ResponseWriter, r *Response, statusCode int) error {
+	return ResponderOf(rw).Respond(rw, r, statusCode)
+}
+
+/*
+	Per router responders
+*/
+
+// responderWriter carries a responder with the response writer, so the convenience
+// methods can use it without changing their signatures
+type responderWriter struct {
+	http.ResponseWriter
+
+	responder Responder
+}
+
+// Responder returns the responder of the writer
+func (rw *responderWriter) Responder() Responder {
+	return rw.responder
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (rw *responderWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (rw *responderWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// WithResponder returns a response writer for which the convenience methods write
+// with responder
+func WithResponder(rw http.ResponseWriter, responder Responder) http.ResponseWriter {
+	return &responderWriter{
+		ResponseWriter: rw,
+		responder:      responder,
+	}
+}
+
+// ResponderOf returns the responder of rw, DefaultResponder if rw has none
+func ResponderOf(rw http.ResponseWriter) Responder {
+	if carrier, ok := unwrapTo[interface{ Responder() Responder }](rw); ok {
+		return carrier.Responder()
+	}
+
+	return DefaultResponder
+}
+
+// ResponderMiddleware sets the responder for all routes behind it, it can be added
+// to the middleware chain of a router group
+type ResponderMiddleware struct {
+	Responder Responder
+}
+
+// NewResponderMiddleware creates a new responder middleware
+func NewResponderMiddleware(responder Responder) *ResponderMiddleware {
+	return &ResponderMiddleware{
+		Responder: responder,
+	}
+}
+
+func (ware *ResponderMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	next(WithResponder(rw, ware.Responder), r)
+}
+
+/*
+	Output formats
+*/
+
+func envelope(rw http.ResponseWriter, r *Response, statusCode int) error {
+	return r.Write(rw, statusCode)
+}
+
+func bare(rw http.ResponseWriter, r *Response, statusCode int) error {
+	if !r.Success || r.Errors != nil {
+		return writeJSON(rw, map[string]interface{}{"errors": r.Errors}, statusCode, "application/json")
+	}
+
+	return writeJSON(rw, r.Payload, statusCode, "application/json")
+}
+
+// JSONAPIContentType is the media type of JSON:API documents
+const JSONAPIContentType = "application/vnd.api+json"
+
+// JSONAPIError is a JSON:API error object
+type JSONAPIError struct {
+	Status string              `json:"status"`
+	Code   string              `json:"code,omitempty"`
+	Title  string              `json:"title,omitempty"`
+	Detail string              `json:"detail,omitempty"`
+	Source *JSONAPIErrorSource `json:"source,omitempty"`
+}
+
+// JSONAPIErrorSource refers to the request parameter which caused the error
+type JSONAPIErrorSource struct {
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// JSONAPIDocument is a JSON:API top level document
+type JSONAPIDocument struct {
+	Data   interface{}            `json:"data,omitempty"`
+	Errors []*JSONAPIError        `json:"errors,omitempty"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// JSONAPIErrors converts an error map to JSON:API error objects. Reasons of the
+// generic reason section have no source, reasons of other sections have the section
+// as source parameter. The code section is set as code on all errors
+func JSONAPIErrors(errs ErrorMap, statusCode int) []*JSONAPIError {
+	code := ""
+	if codes := errs["code"]; len(codes) > 0 {
+		code = codes[0]
+	}
+
+	sections := make([]string, 0, len(errs))
+	for section := range errs {
+		if section != "code" {
+			sections = append(sections, string(section))
+		}
+	}
+
+	sort.Strings(sections)
+
+	objects := []*JSONAPIError{}
+
+	for _, section := range sections {
+		for _, reason := range errs[ErrorSection(section)] {
+			object := &JSONAPIError{
+				Status: strconv.Itoa(statusCode),
+				Code:   code,
+				Title:  http.StatusText(statusCode),
+				Detail: reason,
+			}
+
+			if section != "reason" {
+				object.Source = &JSONAPIErrorSource{Parameter: section}
+			}
+
+			objects = append(objects, object)
+		}
+	}
+
+	if len(objects) == 0 {
+		objects = append(objects, &JSONAPIError{
+			Status: strconv.Itoa(statusCode),
+			Code:   code,
+			Title:  http.StatusText(statusCode),
+		})
+	}
+
+	return objects
+}
+
+func jsonAPI(rw http.ResponseWriter, r *Response, statusCode int) error {
+	doc := &JSONAPIDocument{}
+
+	if !r.Success || r.Errors != nil {
+		doc.Errors = JSONAPIErrors(r.Errors, statusCode)
+	} else if r.Payload != nil {
+		doc.Data = r.Payload
+	} else {
+		// A document needs data, errors or meta, so write an explicit null
+		doc.Data = json.RawMessage("null")
+	}
+
+	return writeJSON(rw, doc, statusCode, JSONAPIContentType)
+}
+
+// unwrapTo returns the first writer in the Unwrap chain of rw which is of type T
+func unwrapTo[T any](rw http.ResponseWriter) (T, bool) {
+	for rw != nil {
+		if found, ok := rw.(T); ok {
+			return found, true
+		}
+
+		wrapper, ok := rw.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+
+		rw = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
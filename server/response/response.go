@@ -1,11 +1,19 @@
 // Package response defines a default JSON response format with success flag, payload
-// and errors. Convenience response methods are provided.
+// and errors. Convenience response methods are provided. The output format of the
+// convenience methods can be changed to bare JSON or JSON:API with a Responder.
 package response
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	errorUtils "github.com/almerlucke/go-utils/errors"
+	"github.com/almerlucke/go-utils/logging"
 )
 
 // ErrorSection is a section for specific errors
@@ -45,18 +53,108 @@ type Response struct {
 	Errors  ErrorMap    `json:"errors,omitempty"`
 }
 
-// Write a response
-func (r *Response) Write(rw http.ResponseWriter, statusCode int) {
-	js, err := json.Marshal(r)
+// Logger logs serialization and write failures, if nil the default logger is used.
+// Set it to logging.Nop() to silence them
+var Logger logging.Logger
+
+// ErrAlreadyWritten is returned by Write when a response was already written to the
+// response writer
+var ErrAlreadyWritten = errors.New("response already written")
+
+// Write a response in the envelope format, if the payload can not be serialized an
+// internal server error is written instead. When the response writer reports that a
+// response was already written (see WriteOnce) nothing is written and
+// ErrAlreadyWritten is returned. The convenience methods write with the Responder of
+// the response writer instead, see Respond
+func (r *Response) Write(rw http.ResponseWriter, statusCode int) error {
+	return writeJSON(rw, r, statusCode, "application/json")
+}
+
+// writeJSON serializes v and writes it with status code and content type
+func writeJSON(rw http.ResponseWriter, v interface{}, statusCode int, contentType string) error {
+	if written, ok := unwrapTo[interface{ Written() bool }](rw); ok && written.Written() {
+		logging.OrDefault(Logger).Warn("response already written", "status", statusCode)
+		return ErrAlreadyWritten
+	}
 
+	js, err := json.Marshal(v)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
+		logging.OrDefault(Logger).Error("response serialization failed", "error", err)
+		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		return err
 	}
 
-	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Type", contentType)
 	rw.WriteHeader(statusCode)
-	rw.Write(js)
+
+	_, err = rw.Write(js)
+	if err != nil {
+		logging.OrDefault(Logger).Debug("response write failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// OnceWriter is a response writer which only writes the first status code, later
+// calls to WriteHeader are ignored. Written reports if a response was started so
+// Response.Write does not write a second response
+type OnceWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+// WriteOnce wraps a response writer with once semantics, writers which already
+// report Written (e.g. negroni response writers) are returned as is
+func WriteOnce(rw http.ResponseWriter) http.ResponseWriter {
+	if _, ok := unwrapTo[interface{ Written() bool }](rw); ok {
+		return rw
+	}
+
+	return &OnceWriter{ResponseWriter: rw}
+}
+
+// WriteHeader writes the status code the first time it is called
+func (rw *OnceWriter) WriteHeader(statusCode int) {
+	if rw.status != 0 {
+		logging.OrDefault(Logger).Warn("superfluous WriteHeader call ignored", "status", statusCode, "written", rw.status)
+		return
+	}
+
+	rw.status = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the body, the status is set to 200 if no status was written
+func (rw *OnceWriter) Write(data []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	return rw.ResponseWriter.Write(data)
+}
+
+// Written reports if a status was written
+func (rw *OnceWriter) Written() bool {
+	return rw.status != 0
+}
+
+// Status returns the written status code, 0 if nothing was written
+func (rw *OnceWriter) Status() int {
+	return rw.status
+}
+
+// Flush flushes the underlying writer if it supports flushing
+func (rw *OnceWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (rw *OnceWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
 /*
@@ -71,7 +169,7 @@ func InternalServerError(rw http.ResponseWriter, reason string) {
 		Errors:  Reason(reason),
 	}
 
-	r.Write(rw, http.StatusInternalServerError)
+	Respond(rw, r, http.StatusInternalServerError)
 }
 
 // ValidationError writes a (possible) validation error. If error is of type
@@ -92,7 +190,7 @@ func Unauthorized(rw http.ResponseWriter, reason string) {
 		Errors:  Reason(reason),
 	}
 
-	r.Write(rw, http.StatusUnauthorized)
+	Respond(rw, r, http.StatusUnauthorized)
 }
 
 // Forbidden writes a forbidden response with a reason
@@ -103,7 +201,7 @@ func Forbidden(rw http.ResponseWriter, reason string) {
 		Errors:  Reason(reason),
 	}
 
-	r.Write(rw, http.StatusForbidden)
+	Respond(rw, r, http.StatusForbidden)
 }
 
 // Accepted writes an accepted response
@@ -114,7 +212,7 @@ func Accepted(rw http.ResponseWriter, payload interface{}) {
 		Errors:  nil,
 	}
 
-	r.Write(rw, http.StatusAccepted)
+	Respond(rw, r, http.StatusAccepted)
 }
 
 // Created writes a created response
@@ -125,7 +223,7 @@ func Created(rw http.ResponseWriter, payload interface{}) {
 		Errors:  nil,
 	}
 
-	r.Write(rw, http.StatusCreated)
+	Respond(rw, r, http.StatusCreated)
 }
 
 // OK writes a successful response
@@ -136,7 +234,7 @@ func OK(rw http.ResponseWriter, payload interface{}) {
 		Errors:  nil,
 	}
 
-	r.Write(rw, http.StatusOK)
+	Respond(rw, r, http.StatusOK)
 }
 
 // BadRequest writes a bad request
@@ -147,7 +245,7 @@ func BadRequest(rw http.ResponseWriter, errs ErrorMap) {
 		Errors:  errs,
 	}
 
-	r.Write(rw, http.StatusBadRequest)
+	Respond(rw, r, http.StatusBadRequest)
 }
 
 // NotFound writes a not found request
@@ -158,7 +256,52 @@ func NotFound(rw http.ResponseWriter) {
 		Errors:  Reason("404 page not found"),
 	}
 
-	r.Write(rw, http.StatusNotFound)
+	Respond(rw, r, http.StatusNotFound)
+}
+
+// TooManyRequests writes a too many requests response with a reason, if retryAfter
+// is larger than zero the Retry-After header is set in seconds
+func TooManyRequests(rw http.ResponseWriter, reason string, retryAfter time.Duration) {
+	setRetryAfter(rw, retryAfter)
+
+	r := &Response{
+		Success: false,
+		Payload: nil,
+		Errors:  Reason(reason),
+	}
+
+	Respond(rw, r, http.StatusTooManyRequests)
+}
+
+// ServiceUnavailable writes a service unavailable response with a reason, if
+// retryAfter is larger than zero the Retry-After header is set in seconds
+func ServiceUnavailable(rw http.ResponseWriter, reason string, retryAfter time.Duration) {
+	setRetryAfter(rw, retryAfter)
+
+	r := &Response{
+		Success: false,
+		Payload: nil,
+		Errors:  Reason(reason),
+	}
+
+	Respond(rw, r, http.StatusServiceUnavailable)
+}
+
+func setRetryAfter(rw http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+}
+
+// GatewayTimeout writes a gateway timeout response with a reason
+func GatewayTimeout(rw http.ResponseWriter, reason string) {
+	r := &Response{
+		Success: false,
+		Payload: nil,
+		Errors:  Reason(reason),
+	}
+
+	Respond(rw, r, http.StatusGatewayTimeout)
 }
 
 // MethodNotAllowed writes a method not allowed response
@@ -169,5 +312,63 @@ func MethodNotAllowed(rw http.ResponseWriter) {
 		Errors:  Reason("405 method not allowed"),
 	}
 
-	r.Write(rw, http.StatusMethodNotAllowed)
+	Respond(rw, r, http.StatusMethodNotAllowed)
+}
+
+/*
+	Error mapping
+*/
+
+// StatusForError returns the HTTP status code for the kind of err
+func StatusForError(err error) int {
+	switch errorUtils.KindOf(err) {
+	case errorUtils.KindNotFound:
+		return http.StatusNotFound
+	case errorUtils.KindConflict:
+		return http.StatusConflict
+	case errorUtils.KindUnauthorized:
+		return http.StatusUnauthorized
+	case errorUtils.KindForbidden:
+		return http.StatusForbidden
+	case errorUtils.KindValidation:
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// FromError writes an error response for err. An ErrorMap is written as bad
+// request, errors from the errors package are mapped to a status by their kind
+// with their message as reason and their code in a code section. The message of
+// internal errors is not exposed. Nothing is written if err is nil
+func FromError(rw http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+
+	var errorMap ErrorMap
+	if errorUtils.As(err, &errorMap) {
+		BadRequest(rw, errorMap)
+		return
+	}
+
+	status := StatusForError(err)
+	errs := Reason("internal server error")
+
+	var typedErr *errorUtils.Error
+	if errorUtils.As(err, &typedErr) && status != http.StatusInternalServerError {
+		errs = Reason(typedErr.Message)
+	}
+
+	if code := errorUtils.CodeOf(err); code != "" {
+		errs["code"] = ErrorReasons{code}
+	}
+
+	r := &Response{
+		Success: false,
+		Payload: nil,
+		Errors:  errs,
+	}
+
+	Respond(rw, r, status)
 }
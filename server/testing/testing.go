@@ -0,0 +1,230 @@
+// Package testing records handle responses for handler unit tests. A Recorder builds
+// the request and httprouter params, runs a handle and parses the response envelope,
+// the assertion methods report failures to a *testing.T
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	contextUtils "github.com/almerlucke/go-utils/server/context"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// T is the part of *testing.T used by the assertions, so this package does not shadow
+// the standard testing package in test files
+type T interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// Envelope is the parsed response envelope, the payload is kept raw so it can be
+// decoded into any type
+type Envelope struct {
+	Success bool              `json:"success"`
+	Payload json.RawMessage   `json:"payload,omitempty"`
+	Errors  response.ErrorMap `json:"errors,omitempty"`
+}
+
+// Recorder builds a request, runs a handle and records the response
+type Recorder struct {
+	Request  *http.Request
+	Params   httprouter.Params
+	Response *httptest.ResponseRecorder
+}
+
+// NewRecorder creates a recorder for a request, body can be nil, an io.Reader, a
+// string, a byte slice or a value which is encoded as JSON
+func NewRecorder(method string, target string, body interface{}) *Recorder {
+	var reader io.Reader
+	contentType := ""
+
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		js, err := json.Marshal(b)
+		if err != nil {
+			panic(fmt.Sprintf("can't encode request body: %v", err))
+		}
+
+		reader = bytes.NewReader(js)
+		contentType = "application/json"
+	}
+
+	r := httptest.NewRequest(method, target, reader)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	return &Recorder{
+		Request:  r,
+		Params:   httprouter.Params{},
+		Response: httptest.NewRecorder(),
+	}
+}
+
+// Param adds a router param
+func (rec *Recorder) Param(key string, value string) *Recorder {
+	rec.Params = append(rec.Params, httprouter.Param{Key: key, Value: value})
+	return rec
+}
+
+// Header sets a request header
+func (rec *Recorder) Header(key string, value string) *Recorder {
+	rec.Request.Header.Set(key, value)
+	return rec
+}
+
+// WithValue adds a value to the request context
+func (rec *Recorder) WithValue(key interface{}, value interface{}) *Recorder {
+	rec.Request = rec.Request.WithContext(context.WithValue(rec.Request.Context(), key, value))
+	return rec
+}
+
+// WithAuthToken adds token data to the request context like the authtoken middleware
+func (rec *Recorder) WithAuthToken(tokenData interface{}) *Recorder {
+	return rec.WithValue(contextUtils.AuthTokenKey, tokenData)
+}
+
+// Run runs handle with the recorded request. Handle can be a httprouter.Handle, an
+// http.Handler, an http.HandlerFunc, a handler function or a negroni style middleware
+// with next, in that case next writes nothing. Params are also stored in the request
+// context for http.Handler variants like httprouter does
+func (rec *Recorder) Run(handle interface{}) *Recorder {
+	rw := rec.Response
+	r := rec.Request.WithContext(context.WithValue(rec.Request.Context(), httprouter.ParamsKey, rec.Params))
+
+	switch h := handle.(type) {
+	case httprouter.Handle:
+		h(rw, r, rec.Params)
+	case func(http.ResponseWriter, *http.Request, httprouter.Params):
+		h(rw, r, rec.Params)
+	case http.Handler:
+		h.ServeHTTP(rw, r)
+	case func(http.ResponseWriter, *http.Request):
+		h(rw, r)
+	case interface {
+		ServeHTTP(http.ResponseWriter, *http.Request, http.HandlerFunc)
+	}:
+		h.ServeHTTP(rw, r, func(http.ResponseWriter, *http.Request) {})
+	case func(http.ResponseWriter, *http.Request, http.HandlerFunc):
+		h(rw, r, func(http.ResponseWriter, *http.Request) {})
+	default:
+		panic(fmt.Sprintf("unsupported handle type %T", handle))
+	}
+
+	return rec
+}
+
+// Status returns the recorded status code
+func (rec *Recorder) Status() int {
+	return rec.Response.Code
+}
+
+// Body returns the recorded body
+func (rec *Recorder) Body() []byte {
+	return rec.Response.Body.Bytes()
+}
+
+// Envelope parses the recorded body as response envelope
+func (rec *Recorder) Envelope() (*Envelope, error) {
+	envelope := &Envelope{}
+
+	err := json.Unmarshal(rec.Body(), envelope)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse response envelope: %v (body: %q)", err, rec.Body())
+	}
+
+	return envelope, nil
+}
+
+/*
+	Assertions
+*/
+
+// MustEnvelope returns the parsed envelope, the test fails if the body can't be parsed
+func (rec *Recorder) MustEnvelope(t T) *Envelope {
+	t.Helper()
+
+	envelope, err := rec.Envelope()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return envelope
+}
+
+// AssertStatus fails the test if the status code is not status
+func (rec *Recorder) AssertStatus(t T, status int) *Recorder {
+	t.Helper()
+
+	if rec.Status() != status {
+		t.Fatalf("expected status %v, got %v (body: %s)", status, rec.Status(), rec.Body())
+	}
+
+	return rec
+}
+
+// AssertSuccess fails the test if the envelope success flag is not success
+func (rec *Recorder) AssertSuccess(t T, success bool) *Recorder {
+	t.Helper()
+
+	envelope := rec.MustEnvelope(t)
+	if envelope.Success != success {
+		t.Fatalf("expected success %v, got %v (body: %s)", success, envelope.Success, rec.Body())
+	}
+
+	return rec
+}
+
+// AssertErrorSection fails the test if the response has no errors for section
+func (rec *Recorder) AssertErrorSection(t T, section response.ErrorSection) *Recorder {
+	t.Helper()
+
+	envelope := rec.MustEnvelope(t)
+	if _, ok := envelope.Errors[section]; !ok {
+		t.Fatalf("expected error section %q, got %v", section, envelope.Errors)
+	}
+
+	return rec
+}
+
+// AssertNoErrors fails the test if the response has errors
+func (rec *Recorder) AssertNoErrors(t T) *Recorder {
+	t.Helper()
+
+	envelope := rec.MustEnvelope(t)
+	if len(envelope.Errors) > 0 {
+		t.Fatalf("expected no errors, got %v", envelope.Errors)
+	}
+
+	return rec
+}
+
+// DecodePayload decodes the envelope payload into obj, the test fails if the payload
+// can't be decoded
+func (rec *Recorder) DecodePayload(t T, obj interface{}) *Recorder {
+	t.Helper()
+
+	envelope := rec.MustEnvelope(t)
+
+	err := json.Unmarshal(envelope.Payload, obj)
+	if err != nil {
+		t.Fatalf("can't decode payload into %T: %v (payload: %s)", obj, err, envelope.Payload)
+	}
+
+	return rec
+}
@@ -0,0 +1,267 @@
+// Package dkim signs raw email messages with DKIM (RFC 6376) using rsa-sha256 and
+// relaxed/relaxed canonicalization, so messages sent outside SES pass authentication
+// checks. The public key must be published as TXT record at
+// <selector>._domainkey.<domain>
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/services/email"
+)
+
+// DefaultHeaders are signed when present in the message
+var DefaultHeaders = []string{"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"}
+
+// Signer signs messages for a domain and selector
+type Signer struct {
+	Domain     string
+	Selector   string
+	PrivateKey *rsa.PrivateKey
+
+	// Headers to sign, if empty DefaultHeaders are used. From is always signed
+	Headers []string
+
+	// Now returns the signature time, if nil time.Now is used
+	Now func() time.Time
+}
+
+// NewSigner creates a signer
+func NewSigner(domain string, selector string, privateKey *rsa.PrivateKey) *Signer {
+	return &Signer{
+		Domain:     domain,
+		Selector:   selector,
+		PrivateKey: privateKey,
+	}
+}
+
+// ParsePrivateKey parses a PEM encoded RSA private key in PKCS1 or PKCS8 format
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// LoadPrivateKey reads a PEM encoded RSA private key from a file
+func LoadPrivateKey(filePath string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePrivateKey(data)
+}
+
+// header is a header field of the message
+type header struct {
+	name string
+	raw  string
+}
+
+// Sign returns the message with a DKIM-Signature header prepended, line endings
+// are normalized to CRLF
+func (signer *Signer) Sign(message []byte) ([]byte, error) {
+	message = normalizeLineEndings(message)
+
+	headerData, body := message, []byte{}
+	if index := bytes.Index(message, []byte("\r\n\r\n")); index >= 0 {
+		headerData = message[:index+2]
+		body = message[index+4:]
+	}
+
+	headers := parseHeaders(string(headerData))
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+
+	signedHeaders := []header{}
+	names := []string{}
+	used := map[int]bool{}
+
+	for _, name := range signer.headerNames() {
+		// Sign the last unused instance of a header first (RFC 6376 5.4.2)
+		for i := len(headers) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(headers[i].name, name) {
+				used[i] = true
+				signedHeaders = append(signedHeaders, headers[i])
+				names = append(names, strings.ToLower(name))
+				break
+			}
+		}
+	}
+
+	if len(names) == 0 || names[0] != "from" {
+		return nil, errors.New("message has no From header")
+	}
+
+	now := time.Now
+	if signer.Now != nil {
+		now = signer.Now
+	}
+
+	value := fmt.Sprintf("v=1; a=rsa-sha256; c=relaxed/relaxed; d=%v; s=%v; t=%v; h=%v; bh=%v; b=",
+		signer.Domain, signer.Selector, now().Unix(), strings.Join(names, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+
+	var data bytes.Buffer
+	for _, h := range signedHeaders {
+		data.WriteString(canonicalHeader(h.name, h.raw))
+	}
+
+	data.WriteString(strings.TrimSuffix(canonicalHeader("DKIM-Signature", " "+value), "\r\n"))
+
+	hash := sha256.Sum256(data.Bytes())
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer.PrivateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var signed bytes.Buffer
+	signed.WriteString("DKIM-Signature: " + value + fold(base64.StdEncoding.EncodeToString(signature)) + "\r\n")
+	signed.Write(message)
+
+	return signed.Bytes(), nil
+}
+
+// Middleware signs raw emails before they are sent by the next mailer, use it with
+// email.Wrap. Emails sent with SendEmail are passed unchanged
+func (signer *Signer) Middleware() email.Middleware {
+	return func(mailer email.Mailer) email.Mailer {
+		return &email.MailerFuncs{
+			Next: mailer,
+			SendRawFunc: func(input *email.SendRawEmailInput) (*email.SendEmailOutput, error) {
+				signed, err := signer.Sign(input.RawMessage)
+				if err != nil {
+					return nil, err
+				}
+
+				signedInput := *input
+				signedInput.RawMessage = signed
+
+				return mailer.SendRawEmail(&signedInput)
+			},
+		}
+	}
+}
+
+func (signer *Signer) headerNames() []string {
+	names := signer.Headers
+	if len(names) == 0 {
+		names = DefaultHeaders
+	}
+
+	result := []string{"From"}
+	for _, name := range names {
+		if !strings.EqualFold(name, "From") {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+/*
+	Canonicalization
+*/
+
+var whitespace = regexp.MustCompile(`[ \t]+`)
+
+func normalizeLineEndings(message []byte) []byte {
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(message, []byte("\n"), []byte("\r\n"))
+}
+
+// parseHeaders splits the header block into fields, folded lines are kept in raw
+func parseHeaders(data string) []header {
+	headers := []header{}
+
+	for _, line := range strings.SplitAfter(data, "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1].raw += line
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		headers = append(headers, header{name: parts[0], raw: parts[1]})
+	}
+
+	return headers
+}
+
+// canonicalHeader applies relaxed header canonicalization
+func canonicalHeader(name string, value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = whitespace.ReplaceAllString(value, " ")
+
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(value) + "\r\n"
+}
+
+// canonicalBody applies relaxed body canonicalization
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(whitespace.ReplaceAllString(line, " "), " ")
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return []byte{}
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// fold folds a base64 value over multiple lines
+func fold(s string) string {
+	var builder strings.Builder
+
+	for len(s) > 72 {
+		builder.WriteString(s[:72] + "\r\n\t")
+		s = s[72:]
+	}
+
+	builder.WriteString(s)
+
+	return builder.String()
+}
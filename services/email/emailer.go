@@ -37,15 +37,32 @@ type SendEmailInput struct {
 	ReplyToAddresses []string
 	ReturnPath       string
 	Source           string
+
+	// Tags are added to the message for providers that support message tags
+	Tags map[string]string
 }
 
 // SendRawEmailInput input for sending raw email
 type SendRawEmailInput struct {
 	RawMessage []byte
+
+	// Destinations are the envelope recipients, if empty the message is sent to the
+	// To, Cc and Bcc addresses of the message headers
+	Destinations []string
+
+	// Tags are added to the message for providers that support message tags
+	Tags map[string]string
+}
+
+// SendEmailOutput output of a sent email
+type SendEmailOutput struct {
+	// MessageID is the id assigned by the provider, it can be used to correlate
+	// delivery notifications
+	MessageID string
 }
 
 // Mailer interface
 type Mailer interface {
-	SendEmail(*SendEmailInput) error
-	SendRawEmail(*SendRawEmailInput) error
+	SendEmail(*SendEmailInput) (*SendEmailOutput, error)
+	SendRawEmail(*SendRawEmailInput) (*SendEmailOutput, error)
 }
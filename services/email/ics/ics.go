@@ -0,0 +1,348 @@
+// Package ics builds RFC 5545 calendar events and attaches them to emails, so
+// invitations sent by services show up in calendar clients
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Method of the calendar, REQUEST for invitations, CANCEL to cancel an event
+type Method string
+
+const (
+	// MethodPublish publishes an event without asking for a reply
+	MethodPublish Method = "PUBLISH"
+
+	// MethodRequest invites the attendees
+	MethodRequest Method = "REQUEST"
+
+	// MethodCancel cancels a previously sent event, the UID must match and the
+	// sequence must be increased
+	MethodCancel Method = "CANCEL"
+)
+
+// Role of an attendee
+type Role string
+
+const (
+	// RoleRequired attendee is required
+	RoleRequired Role = "REQ-PARTICIPANT"
+
+	// RoleOptional attendee is optional
+	RoleOptional Role = "OPT-PARTICIPANT"
+)
+
+// Person is an organizer or attendee
+type Person struct {
+	Name  string
+	Email string
+}
+
+// Attendee of an event
+type Attendee struct {
+	Person
+
+	// Role of the attendee, if empty RoleRequired is used
+	Role Role
+
+	// RSVP asks the attendee to reply
+	RSVP bool
+}
+
+// Recurrence rule of an event
+type Recurrence struct {
+	// Frequency is DAILY, WEEKLY, MONTHLY or YEARLY
+	Frequency string
+
+	// Interval between occurrences, 0 means 1
+	Interval int
+
+	// Count limits the number of occurrences, 0 means no limit
+	Count int
+
+	// Until is the last possible occurrence, zero means no end
+	Until time.Time
+
+	// ByDay limits occurrences to week days, e.g. MO, TU
+	ByDay []string
+}
+
+// String returns the RRULE value
+func (recurrence *Recurrence) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(recurrence.Frequency)}
+
+	if recurrence.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%v", recurrence.Interval))
+	}
+
+	if recurrence.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%v", recurrence.Count))
+	}
+
+	if !recurrence.Until.IsZero() {
+		parts = append(parts, "UNTIL="+formatTime(recurrence.Until))
+	}
+
+	if len(recurrence.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.ToUpper(strings.Join(recurrence.ByDay, ",")))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Alarm is a reminder before the start of an event
+type Alarm struct {
+	Before      time.Duration
+	Description string
+}
+
+// Event is a calendar event
+type Event struct {
+	// UID identifies the event, updates and cancellations must use the same UID
+	UID string
+
+	// Sequence must be increased for each update of the event
+	Sequence int
+
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+
+	Start time.Time
+	End   time.Time
+
+	// AllDay events only use the dates of Start and End, End is exclusive
+	AllDay bool
+
+	Organizer  *Person
+	Attendees  []*Attendee
+	Recurrence *Recurrence
+	Alarms     []*Alarm
+
+	// Created is the time stamp of the event, if zero the current time is used
+	Created time.Time
+}
+
+// Calendar holds events
+type Calendar struct {
+	// ProdID identifies the product that created the calendar
+	ProdID string
+
+	// Method of the calendar, if empty MethodPublish is used
+	Method Method
+	Events []*Event
+}
+
+// NewCalendar creates a calendar with a method and events
+func NewCalendar(method Method, events ...*Event) *Calendar {
+	return &Calendar{
+		ProdID: "-//go-utils//ics//EN",
+		Method: method,
+		Events: events,
+	}
+}
+
+// Bytes returns the calendar in iCalendar format
+func (calendar *Calendar) Bytes() []byte {
+	w := &writer{}
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", calendar.ProdID)
+	w.line("CALSCALE", "GREGORIAN")
+
+	method := calendar.Method
+	if method == "" {
+		method = MethodPublish
+	}
+
+	w.line("METHOD", string(method))
+
+	for _, event := range calendar.Events {
+		event.write(w, method)
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.buffer.Bytes()
+}
+
+// String returns the calendar in iCalendar format
+func (calendar *Calendar) String() string {
+	return string(calendar.Bytes())
+}
+
+func (event *Event) write(w *writer, method Method) {
+	created := event.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", event.UID)
+	w.line("SEQUENCE", fmt.Sprintf("%v", event.Sequence))
+	w.line("DTSTAMP", formatTime(created))
+
+	if event.AllDay {
+		w.line("DTSTART;VALUE=DATE", event.Start.Format("20060102"))
+		if !event.End.IsZero() {
+			w.line("DTEND;VALUE=DATE", event.End.Format("20060102"))
+		}
+	} else {
+		w.line("DTSTART", formatTime(event.Start))
+		if !event.End.IsZero() {
+			w.line("DTEND", formatTime(event.End))
+		}
+	}
+
+	w.text("SUMMARY", event.Summary)
+	w.text("DESCRIPTION", event.Description)
+	w.text("LOCATION", event.Location)
+
+	if event.URL != "" {
+		w.line("URL", event.URL)
+	}
+
+	if method == MethodCancel {
+		w.line("STATUS", "CANCELLED")
+	} else {
+		w.line("STATUS", "CONFIRMED")
+	}
+
+	if event.Organizer != nil {
+		w.line("ORGANIZER"+commonName(event.Organizer.Name), "mailto:"+event.Organizer.Email)
+	}
+
+	for _, attendee := range event.Attendees {
+		role := attendee.Role
+		if role == "" {
+			role = RoleRequired
+		}
+
+		params := fmt.Sprintf(";ROLE=%v;PARTSTAT=NEEDS-ACTION;RSVP=%v", role, strings.ToUpper(fmt.Sprintf("%v", attendee.RSVP)))
+		w.line("ATTENDEE"+params+commonName(attendee.Name), "mailto:"+attendee.Email)
+	}
+
+	if event.Recurrence != nil {
+		w.line("RRULE", event.Recurrence.String())
+	}
+
+	for _, alarm := range event.Alarms {
+		description := alarm.Description
+		if description == "" {
+			description = event.Summary
+		}
+
+		w.line("BEGIN", "VALARM")
+		w.line("ACTION", "DISPLAY")
+		w.text("DESCRIPTION", description)
+		w.line("TRIGGER", "-"+formatDuration(alarm.Before))
+		w.line("END", "VALARM")
+	}
+
+	w.line("END", "VEVENT")
+}
+
+/*
+	Formatting
+*/
+
+// writer writes content lines folded at 75 octets with CRLF line endings
+type writer struct {
+	buffer bytes.Buffer
+}
+
+// line writes a content line, line breaks are removed from name and value so values
+// can't add properties
+func (w *writer) line(name string, value string) {
+	line := lineBreakRemover.Replace(name + ":" + value)
+
+	// Continuation lines start with a space which counts for the limit
+	limit := 75
+
+	for len(line) > limit {
+		cut := limit
+		// Don't split UTF-8 sequences
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+
+		w.buffer.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+
+	w.buffer.WriteString(line + "\r\n")
+}
+
+// text writes an escaped text property, empty values are skipped
+func (w *writer) text(name string, value string) {
+	if value == "" {
+		return
+	}
+
+	w.line(name, escapeText(value))
+}
+
+var lineBreakRemover = strings.NewReplacer("\r", "", "\n", "")
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// commonName returns the CN parameter for a name
+func commonName(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return `;CN="` + strings.ReplaceAll(name, `"`, "'") + `"`
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// formatDuration formats a duration as RFC 5545 duration, e.g. PT15M
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+
+	s := "P"
+	if days > 0 {
+		s += fmt.Sprintf("%vD", int64(days))
+	}
+
+	if hours > 0 || minutes > 0 || seconds > 0 || days == 0 {
+		s += "T"
+
+		if hours > 0 {
+			s += fmt.Sprintf("%vH", int64(hours))
+		}
+
+		if minutes > 0 {
+			s += fmt.Sprintf("%vM", int64(minutes))
+		}
+
+		if seconds > 0 || (hours == 0 && minutes == 0) {
+			s += fmt.Sprintf("%vS", int64(seconds))
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,198 @@
+package ics
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/services/email"
+)
+
+// Invitation is an email with a calendar attached
+type Invitation struct {
+	From    string
+	To      []string
+	Cc      []string
+	Subject string
+	Text    string
+	HTML    string
+
+	Calendar *Calendar
+
+	// Filename of the calendar attachment, if empty invite.ics is used
+	Filename string
+}
+
+// Bytes builds the MIME message. The calendar is added as text/calendar alternative
+// with the method parameter, which calendar clients use to show the invitation, and
+// as .ics attachment for clients which don't
+func (invitation *Invitation) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	mixed := multipart.NewWriter(&buffer)
+	alternativeBoundary := multipart.NewWriter(nil).Boundary()
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", invitation.From)
+	header.Set("To", strings.Join(invitation.To, ", "))
+
+	if len(invitation.Cc) > 0 {
+		header.Set("Cc", strings.Join(invitation.Cc, ", "))
+	}
+
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", invitation.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", mixed.Boundary()))
+
+	err := writeHeader(&buffer, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Alternative bodies
+	alternativePart, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", alternativeBoundary)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	alternative := multipart.NewWriter(alternativePart)
+
+	err = alternative.SetBoundary(alternativeBoundary)
+	if err != nil {
+		return nil, err
+	}
+
+	method := invitation.Calendar.Method
+	if method == "" {
+		method = MethodPublish
+	}
+
+	calendar := invitation.Calendar.Bytes()
+
+	if invitation.Text != "" {
+		err = writePart(alternative, "text/plain; charset=utf-8", []byte(invitation.Text), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if invitation.HTML != "" {
+		err = writePart(alternative, "text/html; charset=utf-8", []byte(invitation.HTML), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writePart(alternative, fmt.Sprintf("text/calendar; charset=utf-8; method=%v", method), calendar, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = alternative.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Attachment
+	filename := invitation.Filename
+	if filename == "" {
+		filename = "invite.ics"
+	}
+
+	err = writePart(mixed, fmt.Sprintf("application/ics; name=%q", filename), calendar, textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf("attachment; filename=%q", filename)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = mixed.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// RawEmailInput builds the message as raw email input for a mailer
+func (invitation *Invitation) RawEmailInput() (*email.SendRawEmailInput, error) {
+	data, err := invitation.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &email.SendRawEmailInput{
+		RawMessage: data,
+	}, nil
+}
+
+// Send the invitation with a mailer
+func (invitation *Invitation) Send(mailer email.Mailer) (*email.SendEmailOutput, error) {
+	input, err := invitation.RawEmailInput()
+	if err != nil {
+		return nil, err
+	}
+
+	return mailer.SendRawEmail(input)
+}
+
+// writeHeader writes the message header, values with line breaks are rejected so
+// they can't add headers
+func writeHeader(buffer *bytes.Buffer, header textproto.MIMEHeader) error {
+	for _, name := range []string{"From", "To", "Cc", "Subject", "Date", "MIME-Version", "Content-Type"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%v header contains a line break", name)
+		}
+
+		fmt.Fprintf(buffer, "%v: %v\r\n", name, value)
+	}
+
+	buffer.WriteString("\r\n")
+
+	return nil
+}
+
+// writePart writes a base64 encoded part
+func writePart(writer *multipart.Writer, contentType string, data []byte, extra textproto.MIMEHeader) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+
+	for name, values := range extra {
+		header[name] = values
+	}
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	for len(encoded) > 76 {
+		_, err = part.Write([]byte(encoded[:76] + "\r\n"))
+		if err != nil {
+			return err
+		}
+
+		encoded = encoded[76:]
+	}
+
+	_, err = part.Write([]byte(encoded + "\r\n"))
+
+	return err
+}
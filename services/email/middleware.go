@@ -0,0 +1,341 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+)
+
+// ErrAllSuppressed is returned when all recipients of an email are suppressed
+var ErrAllSuppressed = errors.New("all recipients are suppressed")
+
+// Middleware decorates a mailer
+type Middleware func(next Mailer) Mailer
+
+// Wrap decorates a mailer with middlewares, the first middleware is the outermost
+func Wrap(mailer Mailer, middlewares ...Middleware) Mailer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		mailer = middlewares[i](mailer)
+	}
+
+	return mailer
+}
+
+// MailerFuncs implements Mailer with functions, nil functions call Next
+type MailerFuncs struct {
+	Next        Mailer
+	SendFunc    func(input *SendEmailInput) (*SendEmailOutput, error)
+	SendRawFunc func(input *SendRawEmailInput) (*SendEmailOutput, error)
+}
+
+// SendEmail for Mailer
+func (funcs *MailerFuncs) SendEmail(input *SendEmailInput) (*SendEmailOutput, error) {
+	if funcs.SendFunc == nil {
+		return funcs.Next.SendEmail(input)
+	}
+
+	return funcs.SendFunc(input)
+}
+
+// SendRawEmail for Mailer
+func (funcs *MailerFuncs) SendRawEmail(input *SendRawEmailInput) (*SendEmailOutput, error) {
+	if funcs.SendRawFunc == nil {
+		return funcs.Next.SendRawEmail(input)
+	}
+
+	return funcs.SendRawFunc(input)
+}
+
+/*
+	Rate limiting
+*/
+
+// RateLimit spaces sends so at most perSecond emails are sent per second, callers
+// block until they can send. Use it to stay below provider sending caps, values
+// < 1 disable the limit
+func RateLimit(perSecond int) Middleware {
+	if perSecond < 1 {
+		return func(mailer Mailer) Mailer {
+			return mailer
+		}
+	}
+
+	interval := time.Second / time.Duration(perSecond)
+
+	var mutex sync.Mutex
+	var next time.Time
+
+	wait := func() {
+		mutex.Lock()
+		now := time.Now()
+		if next.Before(now) {
+			next = now
+		}
+
+		delay := next.Sub(now)
+		next = next.Add(interval)
+		mutex.Unlock()
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+
+	return func(mailer Mailer) Mailer {
+		return &MailerFuncs{
+			Next: mailer,
+			SendFunc: func(input *SendEmailInput) (*SendEmailOutput, error) {
+				wait()
+				return mailer.SendEmail(input)
+			},
+			SendRawFunc: func(input *SendRawEmailInput) (*SendEmailOutput, error) {
+				wait()
+				return mailer.SendRawEmail(input)
+			},
+		}
+	}
+}
+
+/*
+	Suppression
+*/
+
+// SuppressionList checks if an address should not receive email, e.g. after a
+// bounce or complaint
+type SuppressionList interface {
+	IsSuppressed(address string) (bool, error)
+}
+
+// SuppressionSet is an in memory suppression list, addresses are compared case
+// insensitively
+type SuppressionSet map[string]bool
+
+// NewSuppressionSet creates a suppression set from addresses
+func NewSuppressionSet(addresses ...string) SuppressionSet {
+	set := SuppressionSet{}
+	for _, address := range addresses {
+		set[strings.ToLower(address)] = true
+	}
+
+	return set
+}
+
+// IsSuppressed for SuppressionList
+func (set SuppressionSet) IsSuppressed(address string) (bool, error) {
+	return set[strings.ToLower(address)], nil
+}
+
+// rawRecipients returns the Destinations of a raw email, or the To, Cc and Bcc
+// addresses of the message headers if Destinations is empty
+func rawRecipients(input *SendRawEmailInput) ([]string, error) {
+	if len(input.Destinations) > 0 {
+		return input.Destinations, nil
+	}
+
+	message, err := mail.ReadMessage(bytes.NewReader(input.RawMessage))
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []string{}
+
+	for _, name := range []string{"To", "Cc", "Bcc"} {
+		addresses, err := message.Header.AddressList(name)
+		if err == mail.ErrHeaderNotPresent {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, address := range addresses {
+			recipients = append(recipients, address.Address)
+		}
+	}
+
+	return recipients, nil
+}
+
+// Suppress removes suppressed recipients from emails, ErrAllSuppressed is returned
+// when no recipient is left. Raw emails are sent to the allowed recipients of their
+// headers by setting Destinations, the headers are not changed so a DKIM signature
+// stays valid
+func Suppress(list SuppressionList) Middleware {
+	filter := func(addresses []string) ([]string, error) {
+		if addresses == nil {
+			return nil, nil
+		}
+
+		allowed := []string{}
+
+		for _, address := range addresses {
+			suppressed, err := list.IsSuppressed(address)
+			if err != nil {
+				return nil, err
+			}
+
+			if !suppressed {
+				allowed = append(allowed, address)
+			}
+		}
+
+		return allowed, nil
+	}
+
+	return func(mailer Mailer) Mailer {
+		return &MailerFuncs{
+			Next: mailer,
+			SendFunc: func(input *SendEmailInput) (*SendEmailOutput, error) {
+				if input.Destination == nil {
+					return mailer.SendEmail(input)
+				}
+
+				destination := &Destination{}
+
+				var err error
+
+				if destination.ToAddresses, err = filter(input.Destination.ToAddresses); err != nil {
+					return nil, err
+				}
+
+				if destination.CcAddresses, err = filter(input.Destination.CcAddresses); err != nil {
+					return nil, err
+				}
+
+				if destination.BccAddresses, err = filter(input.Destination.BccAddresses); err != nil {
+					return nil, err
+				}
+
+				if len(destination.ToAddresses)+len(destination.CcAddresses)+len(destination.BccAddresses) == 0 {
+					return nil, ErrAllSuppressed
+				}
+
+				filtered := *input
+				filtered.Destination = destination
+
+				return mailer.SendEmail(&filtered)
+			},
+			SendRawFunc: func(input *SendRawEmailInput) (*SendEmailOutput, error) {
+				recipients, err := rawRecipients(input)
+				if err != nil {
+					return nil, err
+				}
+
+				allowed, err := filter(recipients)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(allowed) == 0 {
+					return nil, ErrAllSuppressed
+				}
+
+				filtered := *input
+				filtered.Destinations = allowed
+
+				return mailer.SendRawEmail(&filtered)
+			},
+		}
+	}
+}
+
+/*
+	Archiving
+*/
+
+// ArchiveBcc adds address as BCC recipient to every email, for raw emails it is
+// added to the Destinations so it does not show up in the message headers
+func ArchiveBcc(address string) Middleware {
+	return func(mailer Mailer) Mailer {
+		return &MailerFuncs{
+			Next: mailer,
+			SendFunc: func(input *SendEmailInput) (*SendEmailOutput, error) {
+				archived := *input
+				archived.Destination = &Destination{}
+
+				if input.Destination != nil {
+					*archived.Destination = *input.Destination
+				}
+
+				bcc := archived.Destination.BccAddresses
+				archived.Destination.BccAddresses = append(bcc[:len(bcc):len(bcc)], address)
+
+				return mailer.SendEmail(&archived)
+			},
+			SendRawFunc: func(input *SendRawEmailInput) (*SendEmailOutput, error) {
+				recipients, err := rawRecipients(input)
+				if err != nil {
+					return nil, err
+				}
+
+				archived := *input
+				archived.Destinations = append(recipients[:len(recipients):len(recipients)], address)
+
+				return mailer.SendRawEmail(&archived)
+			},
+		}
+	}
+}
+
+/*
+	Logging
+*/
+
+// Log logs every send with the number of recipients, subject, message id and
+// duration. Addresses are not logged. If logger is nil the default logger is used
+func Log(logger logging.Logger) Middleware {
+	return func(mailer Mailer) Mailer {
+		return &MailerFuncs{
+			Next: mailer,
+			SendFunc: func(input *SendEmailInput) (*SendEmailOutput, error) {
+				start := time.Now()
+				output, err := mailer.SendEmail(input)
+
+				keyvals := []interface{}{"recipients", recipientCount(input.Destination), "duration", time.Since(start)}
+
+				if input.Message != nil && input.Message.Subject != nil {
+					keyvals = append(keyvals, "subject", input.Message.Subject.Data)
+				}
+
+				logSend(logging.OrDefault(logger), output, err, keyvals)
+
+				return output, err
+			},
+			SendRawFunc: func(input *SendRawEmailInput) (*SendEmailOutput, error) {
+				start := time.Now()
+				output, err := mailer.SendRawEmail(input)
+
+				logSend(logging.OrDefault(logger), output, err, []interface{}{"raw", true, "size", len(input.RawMessage), "duration", time.Since(start)})
+
+				return output, err
+			},
+		}
+	}
+}
+
+func logSend(logger logging.Logger, output *SendEmailOutput, err error, keyvals []interface{}) {
+	if err != nil {
+		logger.Error("email send failed", append(keyvals, "error", err)...)
+		return
+	}
+
+	if output != nil {
+		keyvals = append(keyvals, "messageId", output.MessageID)
+	}
+
+	logger.Info("email sent", keyvals...)
+}
+
+func recipientCount(destination *Destination) int {
+	if destination == nil {
+		return 0
+	}
+
+	return len(destination.ToAddresses) + len(destination.CcAddresses) + len(destination.BccAddresses)
+}
@@ -0,0 +1,128 @@
+package preview
+
+import (
+	htmlTemplate "html/template"
+	"net/http"
+
+	"github.com/almerlucke/go-utils/server/auth/basic"
+	"github.com/almerlucke/go-utils/server/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Handles renders the template list and previews as HTML pages
+type Handles struct {
+	Registry *Registry
+
+	// Locales are offered in the locale selector, the first locale is the default
+	Locales []string
+
+	prefix string
+}
+
+// NewHandles creates the preview handles for a registry
+func NewHandles(registry *Registry, locales ...string) *Handles {
+	if len(locales) == 0 {
+		locales = []string{"en"}
+	}
+
+	return &Handles{
+		Registry: registry,
+		Locales:  locales,
+	}
+}
+
+// Register the handles on a router under prefix (e.g. "/dev/emails"), the routes are
+// protected with basic auth using user and password
+func (handles *Handles) Register(router *httprouter.Router, prefix string, user string, password string) {
+	handles.prefix = prefix
+
+	router.GET(prefix, basic.Handle(user, password, handles.List))
+	router.GET(prefix+"/:name", basic.Handle(user, password, handles.Preview))
+}
+
+// List renders the registered template names
+func (handles *Handles) List(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	handles.render(rw, listPage, map[string]interface{}{
+		"Prefix": handles.prefix,
+		"Names":  handles.Registry.Names(),
+	})
+}
+
+// Preview renders a template with its sample data, the query parameters format
+// (html or text) and locale select the body and locale
+func (handles *Handles) Preview(rw http.ResponseWriter, r *http.Request, pm httprouter.Params) {
+	template, ok := handles.Registry.Get(pm.ByName("name"))
+	if !ok {
+		response.NotFound(rw)
+		return
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = handles.Locales[0]
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "text" {
+		format = "html"
+	}
+
+	message, err := template.RenderSample(locale)
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+		return
+	}
+
+	data := map[string]interface{}{
+		"Prefix":  handles.prefix,
+		"Name":    template.Name,
+		"Locale":  locale,
+		"Locales": handles.Locales,
+		"Format":  format,
+	}
+
+	if message.Subject != nil {
+		data["Subject"] = message.Subject.Data
+	}
+
+	if message.Body != nil {
+		if message.Body.HTML != nil {
+			data["HTML"] = message.Body.HTML.Data
+		}
+
+		if message.Body.Text != nil {
+			data["Text"] = message.Body.Text.Data
+		}
+	}
+
+	handles.render(rw, previewPage, data)
+}
+
+func (handles *Handles) render(rw http.ResponseWriter, page *htmlTemplate.Template, data interface{}) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	err := page.Execute(rw, data)
+	if err != nil {
+		response.InternalServerError(rw, err.Error())
+	}
+}
+
+var listPage = htmlTemplate.Must(htmlTemplate.New("list").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Email templates</title></head>
+<body style="font-family: sans-serif">
+<h1>Email templates</h1>
+<ul>{{range .Names}}<li><a href="{{$.Prefix}}/{{.}}">{{.}}</a></li>{{else}}<li>No templates registered</li>{{end}}</ul>
+</body></html>`))
+
+var previewPage = htmlTemplate.Must(htmlTemplate.New("preview").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Name}}</title></head>
+<body style="font-family: sans-serif">
+<p><a href="{{.Prefix}}">All templates</a></p>
+<h1>{{.Name}}</h1>
+<form method="get">
+<select name="locale" onchange="this.form.submit()">{{range .Locales}}<option value="{{.}}"{{if eq . $.Locale}} selected{{end}}>{{.}}</option>{{end}}</select>
+<select name="format" onchange="this.form.submit()"><option value="html"{{if eq .Format "html"}} selected{{end}}>HTML</option><option value="text"{{if eq .Format "text"}} selected{{end}}>Text</option></select>
+</form>
+<h2>{{.Subject}}</h2>
+{{if eq .Format "text"}}<pre style="border: 1px solid #ccc; padding: 1em; white-space: pre-wrap">{{.Text}}</pre>{{else}}<iframe srcdoc="{{.HTML}}" style="width: 100%; height: 80vh; border: 1px solid #ccc"></iframe>{{end}}
+</body></html>`))
@@ -0,0 +1,135 @@
+// Package preview renders registered email templates with sample data in the browser,
+// mount the handles on a development router to iterate on templates quickly
+package preview
+
+import (
+	"bytes"
+	htmlTemplate "html/template"
+	"sort"
+	"sync"
+	textTemplate "text/template"
+
+	"github.com/almerlucke/go-utils/services/email"
+)
+
+// Template is an email template which can be previewed
+type Template struct {
+	Name string
+
+	// Render renders the message for a locale with data
+	Render func(locale string, data interface{}) (*email.Message, error)
+
+	// Sample returns the sample data for a locale
+	Sample func(locale string) interface{}
+}
+
+// NewTemplate creates a template from subject, html and text template sources, the
+// sources are executed with the data. Sample can be nil
+func NewTemplate(name string, subject string, html string, text string, sample func(locale string) interface{}) (*Template, error) {
+	subjectTemplate, err := textTemplate.New(name + ".subject").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlBody, err := htmlTemplate.New(name + ".html").Parse(html)
+	if err != nil {
+		return nil, err
+	}
+
+	textBody, err := textTemplate.New(name + ".text").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		Name:   name,
+		Sample: sample,
+		Render: func(locale string, data interface{}) (*email.Message, error) {
+			var subjectBuffer, htmlBuffer, textBuffer bytes.Buffer
+
+			if err := subjectTemplate.Execute(&subjectBuffer, data); err != nil {
+				return nil, err
+			}
+
+			if err := htmlBody.Execute(&htmlBuffer, data); err != nil {
+				return nil, err
+			}
+
+			if err := textBody.Execute(&textBuffer, data); err != nil {
+				return nil, err
+			}
+
+			return &email.Message{
+				Subject: &email.Content{Charset: "UTF-8", Data: subjectBuffer.String()},
+				Body: &email.Body{
+					HTML: &email.Content{Charset: "UTF-8", Data: htmlBuffer.String()},
+					Text: &email.Content{Charset: "UTF-8", Data: textBuffer.String()},
+				},
+			}, nil
+		},
+	}, nil
+}
+
+// Registry holds templates by name
+type Registry struct {
+	mutex     sync.RWMutex
+	templates map[string]*Template
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		templates: map[string]*Template{},
+	}
+}
+
+// DefaultRegistry is used by Register
+var DefaultRegistry = NewRegistry()
+
+// Register a template with the default registry
+func Register(template *Template) {
+	DefaultRegistry.Register(template)
+}
+
+// Register a template, a template with the same name is replaced
+func (registry *Registry) Register(template *Template) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.templates[template.Name] = template
+}
+
+// Get a template by name
+func (registry *Registry) Get(name string) (*Template, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	template, ok := registry.templates[name]
+
+	return template, ok
+}
+
+// Names returns the sorted template names
+func (registry *Registry) Names() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	names := make([]string, 0, len(registry.templates))
+	for name := range registry.templates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// RenderSample renders a template with its sample data for a locale
+func (template *Template) RenderSample(locale string) (*email.Message, error) {
+	var data interface{}
+	if template.Sample != nil {
+		data = template.Sample(locale)
+	}
+
+	return template.Render(locale, data)
+}
@@ -1,6 +1,10 @@
 package ses
 
 import (
+	"context"
+	"sort"
+
+	"github.com/almerlucke/go-utils/resilience"
 	"github.com/almerlucke/go-utils/services/email"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -10,16 +14,124 @@ import (
 
 // Mailer wrapper around SES
 type Mailer struct {
+	// RetryPolicy is used to retry failed calls, if nil calls are not retried
+	RetryPolicy *resilience.Policy
+
+	// Breaker stops calling SES after consecutive failures, can be nil
+	Breaker *resilience.CircuitBreaker
+
+	// Options are applied to every sent email
+	Options *Options
+
 	ses *ses.SES
 }
 
+// Options for sending with SES
+type Options struct {
+	// ConfigurationSetName is the configuration set used for event publishing
+	ConfigurationSetName string
+
+	// Tags are added to every message, tags of the input take precedence
+	Tags map[string]string
+
+	// FromArn, SourceArn and ReturnPathArn are used for sending authorization
+	FromArn       string
+	SourceArn     string
+	ReturnPathArn string
+
+	// Region and Endpoint override the session configuration
+	Region   string
+	Endpoint string
+}
+
 // New AWS SES wrapper for emailer interface
 func New(session *session.Session) *Mailer {
+	return NewWithOptions(session, nil)
+}
+
+// NewWithOptions AWS SES wrapper for emailer interface with options, options can be nil
+func NewWithOptions(session *session.Session, options *Options) *Mailer {
+	if options == nil {
+		options = &Options{}
+	}
+
+	config := aws.NewConfig()
+
+	if options.Region != "" {
+		config = config.WithRegion(options.Region)
+	}
+
+	if options.Endpoint != "" {
+		config = config.WithEndpoint(options.Endpoint)
+	}
+
 	return &Mailer{
-		ses: ses.New(session),
+		Options: options,
+		ses:     ses.New(session, config),
 	}
 }
 
+// optionalString returns nil for empty strings
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
+
+// messageTags merges the option tags with the input tags, sorted by name
+func (email *Mailer) messageTags(tags map[string]string) []*ses.MessageTag {
+	merged := map[string]string{}
+
+	if email.Options != nil {
+		for name, value := range email.Options.Tags {
+			merged[name] = value
+		}
+	}
+
+	for name, value := range tags {
+		merged[name] = value
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	messageTags := make([]*ses.MessageTag, len(names))
+	for i, name := range names {
+		messageTags[i] = &ses.MessageTag{
+			Name:  aws.String(name),
+			Value: aws.String(merged[name]),
+		}
+	}
+
+	return messageTags
+}
+
+// sendEmailOutput creates the output for a SES message id
+func sendEmailOutput(messageID *string) *email.SendEmailOutput {
+	return &email.SendEmailOutput{
+		MessageID: aws.StringValue(messageID),
+	}
+}
+
+// options returns the options or empty options
+func (email *Mailer) options() *Options {
+	if email.Options == nil {
+		return &Options{}
+	}
+
+	return email.Options
+}
+
 func contentToAWSEmailContent(content *email.Content) *ses.Content {
 	var charset *string = nil
 
@@ -115,18 +227,86 @@ func sendEmailInputToAWSSendEmailInput(input *email.SendEmailInput) *ses.SendEma
 	return i
 }
 
-// SendEmail send email
-func (email *Mailer) SendEmail(input *email.SendEmailInput) error {
-	_, err := email.ses.SendEmail(sendEmailInputToAWSSendEmailInput(input))
-	return err
+// call fn through the retry policy if set
+func (email *Mailer) call(fn func() error) error {
+	if email.RetryPolicy == nil {
+		return email.execute(fn)
+	}
+
+	return resilience.Retry(context.Background(), email.RetryPolicy, func(ctx context.Context) error {
+		err := email.execute(fn)
+		if err == resilience.ErrOpen {
+			return resilience.Permanent(err)
+		}
+
+		return err
+	})
+}
+
+// execute fn through the circuit breaker if set
+func (email *Mailer) execute(fn func() error) error {
+	if email.Breaker == nil {
+		return fn()
+	}
+
+	return email.Breaker.Execute(fn)
+}
+
+// SendEmail send email, returns the SES message id
+func (email *Mailer) SendEmail(input *email.SendEmailInput) (*email.SendEmailOutput, error) {
+	options := email.options()
+
+	awsInput := sendEmailInputToAWSSendEmailInput(input)
+	awsInput.ConfigurationSetName = optionalString(options.ConfigurationSetName)
+	awsInput.SourceArn = optionalString(options.SourceArn)
+	awsInput.ReturnPathArn = optionalString(options.ReturnPathArn)
+	awsInput.Tags = email.messageTags(input.Tags)
+
+	var output *ses.SendEmailOutput
+
+	err := email.call(func() error {
+		var err error
+		output, err = email.ses.SendEmail(awsInput)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sendEmailOutput(output.MessageId), nil
 }
 
-// SendRawEmail send raw email
-func (email *Mailer) SendRawEmail(input *email.SendRawEmailInput) error {
-	_, err := email.ses.SendRawEmail(&ses.SendRawEmailInput{
+// SendRawEmail send raw email, returns the SES message id
+func (email *Mailer) SendRawEmail(input *email.SendRawEmailInput) (*email.SendEmailOutput, error) {
+	options := email.options()
+
+	awsInput := &ses.SendRawEmailInput{
+		ConfigurationSetName: optionalString(options.ConfigurationSetName),
+		FromArn:              optionalString(options.FromArn),
+		SourceArn:            optionalString(options.SourceArn),
+		ReturnPathArn:        optionalString(options.ReturnPathArn),
+		Tags:                 email.messageTags(input.Tags),
 		RawMessage: &ses.RawMessage{
 			Data: input.RawMessage,
 		},
+	}
+
+	if len(input.Destinations) > 0 {
+		awsInput.Destinations = stringSliceToAWSStringSlice(input.Destinations)
+	}
+
+	var output *ses.SendRawEmailOutput
+
+	err := email.call(func() error {
+		var err error
+		output, err = email.ses.SendRawEmail(awsInput)
+		return err
 	})
-	return err
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sendEmailOutput(output.MessageId), nil
 }
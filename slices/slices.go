@@ -0,0 +1,126 @@
+// Package slices contains generic slice helpers that are reimplemented in
+// almost every service
+package slices
+
+// Map returns a new slice with fn applied to every element
+func Map[T any, R any](s []T, fn func(T) R) []R {
+	result := make([]R, len(s))
+
+	for i, v := range s {
+		result[i] = fn(v)
+	}
+
+	return result
+}
+
+// Filter returns a new slice with the elements for which fn returns true
+func Filter[T any](s []T, fn func(T) bool) []T {
+	result := []T{}
+
+	for _, v := range s {
+		if fn(v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// Reduce reduces a slice to a single value, starting with initial
+func Reduce[T any, R any](s []T, initial R, fn func(R, T) R) R {
+	result := initial
+
+	for _, v := range s {
+		result = fn(result, v)
+	}
+
+	return result
+}
+
+// Unique returns a new slice without duplicates, the order of first occurrence is kept
+func Unique[T comparable](s []T) []T {
+	seen := make(map[T]struct{}, len(s))
+	result := []T{}
+
+	for _, v := range s {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+
+	return result
+}
+
+// Chunk splits a slice into chunks of at most size elements, the chunks share
+// the backing array of s. A size < 1 returns a single chunk
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 {
+		size = len(s)
+	}
+
+	chunks := [][]T{}
+
+	for start := 0; start < len(s); start += size {
+		end := start + size
+		if end > len(s) {
+			end = len(s)
+		}
+
+		chunks = append(chunks, s[start:end:end])
+	}
+
+	return chunks
+}
+
+// GroupBy groups elements by the key returned by fn, the order of elements
+// within a group is kept
+func GroupBy[T any, K comparable](s []T, fn func(T) K) map[K][]T {
+	groups := map[K][]T{}
+
+	for _, v := range s {
+		key := fn(v)
+		groups[key] = append(groups[key], v)
+	}
+
+	return groups
+}
+
+// IndexBy returns a map of elements keyed by the key returned by fn, for
+// duplicate keys the last element wins
+func IndexBy[T any, K comparable](s []T, fn func(T) K) map[K]T {
+	index := make(map[K]T, len(s))
+
+	for _, v := range s {
+		index[fn(v)] = v
+	}
+
+	return index
+}
+
+// Difference returns the elements of a that are not in b
+func Difference[T comparable](a []T, b []T) []T {
+	exclude := make(map[T]struct{}, len(b))
+
+	for _, v := range b {
+		exclude[v] = struct{}{}
+	}
+
+	return Filter(a, func(v T) bool {
+		_, ok := exclude[v]
+		return !ok
+	})
+}
+
+// Contains true if s contains v
+func Contains[T comparable](s []T, v T) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+// Package cdc captures changes to tables and delivers them as events on a channel,
+// for example to invalidate caches or emit webhooks. Changes are read from a Source,
+// either a change log table filled by triggers (TriggerSource) or by polling the
+// modified timestamp of a table (WatermarkSource)
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// Operation is the kind of change
+type Operation string
+
+const (
+	// Insert a row was inserted
+	Insert Operation = "insert"
+
+	// Update a row was updated
+	Update Operation = "update"
+
+	// Delete a row was deleted, or soft deleted when the table has a Deleted field
+	Delete Operation = "delete"
+)
+
+// Event is a change of a single row
+type Event struct {
+	// Table is the name of the changed table
+	Table string
+
+	// Operation is the kind of change
+	Operation Operation
+
+	// RowID is the primary key of the changed row
+	RowID string
+
+	// Row is a snapshot of the row after the change (before for deletes) keyed by
+	// column name
+	Row map[string]interface{}
+
+	// Object is the scanned row struct, only set by WatermarkSource
+	Object interface{}
+
+	// Time of the change
+	Time time.Time
+}
+
+// DefaultLag is the lag used by NewTriggerSource and NewWatermarkSource
+const DefaultLag = 5 * time.Second
+
+// Source delivers changes since the previous poll, at most limit events are
+// returned per call. The position of the source advances with each successful poll,
+// events are delivered at most once. Ids and timestamps are assigned when a row is
+// written but become visible on commit, so the sources wait a lag before moving past
+// them: changes of transactions which commit within the lag are delivered, changes
+// of transactions which take longer can be missed
+type Source interface {
+	Poll(queryer database.Queryer, limit int) ([]*Event, error)
+}
+
+// Watcher polls a source on an interval and sends the events on a channel
+type Watcher struct {
+	// Source of the changes
+	Source Source
+
+	// Queryer is used to poll the source
+	Queryer database.Queryer
+
+	// Interval between polls
+	Interval time.Duration
+
+	// BatchSize is the maximum number of events per poll, a full batch is
+	// followed immediately by the next poll
+	BatchSize int
+
+	// Logger logs poll errors, if nil the default logger is used
+	Logger logging.Logger
+
+	events chan *Event
+}
+
+// NewWatcher creates a new watcher, events are buffered up to bufferSize
+func NewWatcher(source Source, queryer database.Queryer, interval time.Duration, bufferSize int) *Watcher {
+	return &Watcher{
+		Source:    source,
+		Queryer:   queryer,
+		Interval:  interval,
+		BatchSize: 100,
+		events:    make(chan *Event, bufferSize),
+	}
+}
+
+// Events returns the event channel, it is closed when Run returns
+func (watcher *Watcher) Events() <-chan *Event {
+	return watcher.events
+}
+
+// Run polls the source until ctx is cancelled, a watcher can only be run once
+func (watcher *Watcher) Run(ctx context.Context) {
+	defer close(watcher.events)
+
+	ticker := time.NewTicker(watcher.Interval)
+	defer ticker.Stop()
+
+	for {
+		if !watcher.poll(ctx) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start runs the watcher in the background
+func (watcher *Watcher) Start(ctx context.Context) {
+	go watcher.Run(ctx)
+}
+
+// poll delivers events until the source returns less than a full batch, returns
+// false if ctx is done
+func (watcher *Watcher) poll(ctx context.Context) bool {
+	for {
+		events, err := watcher.Source.Poll(watcher.Queryer, watcher.BatchSize)
+		if err != nil {
+			logging.OrDefault(watcher.Logger).Error("cdc poll failed", "error", err)
+			return ctx.Err() == nil
+		}
+
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return false
+			case watcher.events <- event:
+			}
+		}
+
+		if len(events) < watcher.BatchSize {
+			return ctx.Err() == nil
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package cdc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+	"github.com/almerlucke/go-utils/sql/types"
+)
+
+// ChangeTableName is the default name of the change log table
+const ChangeTableName = "cdc_change"
+
+// Change is a row of the change log table, it is written by the triggers
+type Change struct {
+	ID        uint64         `json:"id" db:"id" sql:"no update,NOT NULL AUTO_INCREMENT"`
+	CreatedAt types.DateTime `json:"createdAt" db:"created_at" sql:"no update,DEFAULT CURRENT_TIMESTAMP"`
+	TableName string         `json:"tableName" db:"table_name" sql:"override,VARCHAR(64) NOT NULL"`
+	Operation string         `json:"operation" db:"operation" sql:"override,VARCHAR(16) NOT NULL"`
+	RowID     string         `json:"rowId" db:"row_id" sql:"override,VARCHAR(64) NOT NULL"`
+	RowData   string         `json:"rowData" db:"row_data" sql:"override,JSON"`
+}
+
+// NewChangeTable creates the change log table definition
+func NewChangeTable(name string) (*model.Table, error) {
+	table, err := model.NewTable(name, &Change{})
+	if err != nil {
+		return nil, err
+	}
+
+	table.KeysAndConstraints = []string{"KEY `created_index` (`created_at`)"}
+
+	return table, nil
+}
+
+// TriggerQueries returns the queries that (re)create AFTER INSERT, UPDATE and DELETE
+// triggers on table which write a JSON snapshot of the row to the change log table
+// (requires MySQL 5.7)
+func TriggerQueries(table *model.Table, changeTable *model.Table) []string {
+	queries := []string{}
+
+	for _, operation := range []Operation{Insert, Update, Delete} {
+		row := "NEW"
+		if operation == Delete {
+			row = "OLD"
+		}
+
+		pairs := []string{}
+		for _, column := range table.Descriptor.Columns {
+			pairs = append(pairs, fmt.Sprintf("'%v', %v.`%v`", column.Name, row, column.Name))
+		}
+
+		name := triggerName(table, operation)
+
+		queries = append(queries,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS `%v`", name),
+			fmt.Sprintf(
+				"CREATE TRIGGER `%v` AFTER %v ON `%v` FOR EACH ROW INSERT INTO `%v` (`table_name`,`operation`,`row_id`,`row_data`) VALUES ('%v','%v',%v.`%v`,JSON_OBJECT(%v))",
+				name, strings.ToUpper(string(operation)), table.Name, changeTable.Name,
+				table.Name, operation, row, table.Descriptor.PrimaryColumn.Name, strings.Join(pairs, ","),
+			),
+		)
+	}
+
+	return queries
+}
+
+// DropTriggerQueries returns the queries that drop the triggers of table
+func DropTriggerQueries(table *model.Table) []string {
+	queries := []string{}
+
+	for _, operation := range []Operation{Insert, Update, Delete} {
+		queries = append(queries, fmt.Sprintf("DROP TRIGGER IF EXISTS `%v`", triggerName(table, operation)))
+	}
+
+	return queries
+}
+
+// InstallTriggers creates the change log table if it does not exist and (re)creates
+// the triggers for the given tables
+func InstallTriggers(queryer database.Queryer, changeTable *model.Table, tables ...*model.Table) error {
+	_, err := queryer.Exec(changeTable.TableQuery())
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		for _, query := range TriggerQueries(table, changeTable) {
+			_, err = queryer.Exec(query)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func triggerName(table *model.Table, operation Operation) string {
+	return fmt.Sprintf("cdc_%v_%v", table.Name, operation)
+}
+
+// TriggerSource reads events from the change log table written by the triggers
+type TriggerSource struct {
+	// Table is the change log table
+	Table *model.Table
+
+	// LastID is the id of the last delivered change, set it to resume after a restart
+	LastID uint64
+
+	// Lag is how long a gap in the change ids is waited for before it is skipped, a
+	// gap is either a change of a transaction which has not committed yet or of one
+	// which was rolled back. Ids must be consecutive (auto_increment_increment 1),
+	// otherwise every change is delayed by the lag
+	Lag time.Duration
+
+	gapID    uint64
+	gapSince time.Time
+}
+
+// NewTriggerSource creates a source for the change log table with DefaultLag
+func NewTriggerSource(changeTable *model.Table) *TriggerSource {
+	return &TriggerSource{
+		Table: changeTable,
+		Lag:   DefaultLag,
+	}
+}
+
+// Poll for Source
+func (source *TriggerSource) Poll(queryer database.Queryer, limit int) ([]*Event, error) {
+	result, err := source.Table.Select("*").
+		Where("{{ID}} > ?").
+		OrderBy("{{ID}}").
+		Limit(0, int64(limit)).
+		Run(queryer, source.LastID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := result.([]*Change)
+	events := make([]*Event, 0, len(changes))
+	next := source.LastID + 1
+
+	for _, change := range changes {
+		// Stop at a gap until it is older than the lag
+		if change.ID != next && !source.gapPassed(next) {
+			break
+		}
+
+		next = change.ID + 1
+
+		row := map[string]interface{}{}
+
+		err = json.Unmarshal([]byte(change.RowData), &row)
+		if err != nil {
+			return nil, fmt.Errorf("can't decode change %v: %v", change.ID, err)
+		}
+
+		events = append(events, &Event{
+			Table:     change.TableName,
+			Operation: Operation(change.Operation),
+			RowID:     change.RowID,
+			Row:       row,
+			Time:      time.Time(change.CreatedAt),
+		})
+	}
+
+	if len(events) > 0 {
+		source.LastID = next - 1
+	}
+
+	return events, nil
+}
+
+// gapPassed returns true if the gap at id was first seen at least the lag ago
+func (source *TriggerSource) gapPassed(id uint64) bool {
+	if source.gapID != id {
+		source.gapID = id
+		source.gapSince = time.Now()
+	}
+
+	return time.Since(source.gapSince) >= source.Lag
+}
+
+// Purge deletes changes created before t from the change log table
+func (source *TriggerSource) Purge(queryer database.Queryer, t time.Time) error {
+	_, err := queryer.Exec(fmt.Sprintf("DELETE FROM `%v` WHERE `created_at` < ?", source.Table.Name), t.UTC())
+	return err
+}
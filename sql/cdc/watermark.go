@@ -0,0 +1,153 @@
+package cdc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+	"github.com/almerlucke/go-utils/sql/types"
+)
+
+// WatermarkSource polls a table for rows of which the modified column (see the
+// modified sql tag) is past the watermark. Rows of which the created and modified
+// time are equal are reported as insert, rows with a true Deleted field as delete
+// and all others as update. Hard deletes can not be detected, use TriggerSource
+// for tables which are not soft deleted. Only the latest state of a row is seen.
+// Rows are delivered once their modified time is older than the lag, the clocks of
+// the database and the application must agree
+type WatermarkSource struct {
+	// Table to poll
+	Table *model.Table
+
+	// Watermark is the modified time of the last delivered row
+	Watermark time.Time
+
+	// LastKey is the primary key of the last delivered row, rows with a modified
+	// time equal to the watermark are only delivered if their key is greater
+	LastKey interface{}
+
+	// Lag is how long rows are held back after their modified time so transactions
+	// that are still open when the row is written are not skipped
+	Lag time.Duration
+
+	modified *model.ColumnDescriptor
+	created  *model.ColumnDescriptor
+	deleted  *model.ColumnDescriptor
+}
+
+// NewWatermarkSource creates a source for table starting at watermark with
+// DefaultLag, the table must have a modified column
+func NewWatermarkSource(table *model.Table, watermark time.Time) (*WatermarkSource, error) {
+	source := &WatermarkSource{
+		Table:     table,
+		Watermark: watermark,
+		Lag:       DefaultLag,
+	}
+
+	for _, column := range table.Descriptor.Columns {
+		if column.IsModified {
+			source.modified = column
+		} else if column.IsCreated {
+			source.created = column
+		}
+	}
+
+	if source.modified == nil {
+		return nil, errors.New("table has no modified column")
+	}
+
+	if column, ok := table.Descriptor.ColumnMap["Deleted"]; ok && column.Type == "tinyint(1)" {
+		source.deleted = column
+	}
+
+	return source, nil
+}
+
+// Poll for Source
+func (source *WatermarkSource) Poll(queryer database.Queryer, limit int) ([]*Event, error) {
+	modified := fmt.Sprintf("`%v`", source.modified.Name)
+	primary := fmt.Sprintf("`%v`", source.Table.Descriptor.PrimaryColumn.Name)
+
+	sel := source.Table.Select("*").OrderBy(modified+","+primary).Limit(0, int64(limit))
+	args := []interface{}{}
+	where := ""
+
+	if source.LastKey == nil {
+		where = modified + " >= ?"
+		args = append(args, source.Watermark.UTC())
+	} else {
+		where = fmt.Sprintf("(%v > ? OR (%v = ? AND %v > ?))", modified, modified, primary)
+		args = append(args, source.Watermark.UTC(), source.Watermark.UTC(), source.LastKey)
+	}
+
+	if source.Lag > 0 {
+		where += fmt.Sprintf(" AND %v <= ?", modified)
+		args = append(args, time.Now().Add(-source.Lag).UTC())
+	}
+
+	sel.Where(where)
+
+	result, err := sel.Run(queryer, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(result)
+	events := make([]*Event, rows.Len())
+
+	for i := 0; i < rows.Len(); i++ {
+		obj := rows.Index(i)
+		v := obj.Elem()
+
+		event := &Event{
+			Table:     source.Table.Name,
+			Operation: Update,
+			Row:       map[string]interface{}{},
+			Object:    obj.Interface(),
+			Time:      timeValue(v.FieldByName(source.modified.ActualName)),
+		}
+
+		for _, column := range source.Table.Descriptor.Columns {
+			event.Row[column.Name] = v.FieldByName(column.ActualName).Interface()
+		}
+
+		key := v.FieldByName(source.Table.Descriptor.PrimaryColumn.ActualName).Interface()
+		event.RowID = fmt.Sprintf("%v", key)
+
+		if source.deleted != nil && v.FieldByName(source.deleted.ActualName).Bool() {
+			event.Operation = Delete
+		} else if source.created != nil && timeValue(v.FieldByName(source.created.ActualName)).Equal(event.Time) {
+			event.Operation = Insert
+		}
+
+		events[i] = event
+
+		source.Watermark = event.Time
+		source.LastKey = key
+	}
+
+	return events, nil
+}
+
+// timeValue returns the time of a time.Time or types.DateTime field
+func timeValue(v reflect.Value) time.Time {
+	switch t := v.Interface().(type) {
+	case time.Time:
+		return t
+	case types.DateTime:
+		return time.Time(t)
+	case *time.Time:
+		if t != nil {
+			return *t
+		}
+	case *types.DateTime:
+		if t != nil {
+			return time.Time(*t)
+		}
+	}
+
+	return time.Time{}
+}
@@ -1,15 +1,26 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 
+	"github.com/almerlucke/go-utils/logging"
 	"github.com/jmoiron/sqlx"
 )
 
 // DB wrapper around *sqlx.DB
 type DB struct {
 	*sqlx.DB
+
+	// Logger is used to log connection and transaction events, if nil the
+	// default logger is used
+	Logger logging.Logger
+
+	monitorMutex  sync.Mutex
+	monitorCancel context.CancelFunc
+	monitorDone   chan struct{}
 }
 
 // Queryer is an interface to abstract Tx or DB
@@ -20,6 +31,12 @@ type Queryer interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
+// ContextQueryer is implemented by queryers which can run a query with a context,
+// both *DB and *sqlx.Tx implement it
+type ContextQueryer interface {
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+}
+
 // New database connection
 func New(config *Configuration) (*DB, error) {
 	db, err := sqlx.Open(config.SQLType, config.ConnectionString())
@@ -30,6 +47,7 @@ func New(config *Configuration) (*DB, error) {
 	// Ping the DB first
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -38,9 +56,16 @@ func New(config *Configuration) (*DB, error) {
 	// db.SetMaxIdleConns
 	// db.SetMaxOpenConns
 
+	logging.Default().Info("database connected", "host", config.Host, "database", config.Database)
+
 	return &DB{DB: db}, nil
 }
 
+// logger returns the db logger or the default logger
+func (db *DB) logger() logging.Logger {
+	return logging.OrDefault(db.Logger)
+}
+
 // Transactional performs a given function wrapped inside a transaction, if the function
 // returns false or an error we perform a rollback
 func (db *DB) Transactional(fn func(queryer Queryer) (bool, error)) error {
@@ -56,6 +81,7 @@ func (db *DB) Transactional(fn func(queryer Queryer) (bool, error)) error {
 		// Try to rollback all changes after an error
 		rollbackErr := tx.Rollback()
 		if rollbackErr != nil {
+			db.logger().Error("transaction rollback failed", "error", rollbackErr, "cause", err)
 			return fmt.Errorf("rolback error: %v - when trying to rollback from error: %v", rollbackErr, err)
 		}
 
@@ -64,6 +90,7 @@ func (db *DB) Transactional(fn func(queryer Queryer) (bool, error)) error {
 
 	if !commit {
 		// Try to rollback all changes
+		db.logger().Debug("transaction rolled back")
 		return tx.Rollback()
 	}
 
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	timeUtils "github.com/almerlucke/go-utils/time"
+	"github.com/jmoiron/sqlx"
+)
+
+// Health is the result of a health check
+type Health struct {
+	// Latency of the ping
+	Latency time.Duration
+
+	// Stats of the connection pool
+	Stats sql.DBStats
+}
+
+// HealthCheck pings the database and measures the latency
+func (db *DB) HealthCheck(ctx context.Context) (*Health, error) {
+	start := time.Now()
+
+	err := db.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Health{
+		Latency: time.Since(start),
+		Stats:   db.Stats(),
+	}, nil
+}
+
+// StartHealthMonitor pings the database every interval until ctx is cancelled or
+// StopHealthMonitor is called, onChange is called when connectivity is lost
+// (healthy is false and err is the ping error) or restored. Each ping times out
+// after the interval
+func (db *DB) StartHealthMonitor(ctx context.Context, interval time.Duration, onChange func(healthy bool, err error)) {
+	db.monitorMutex.Lock()
+	defer db.monitorMutex.Unlock()
+
+	if db.monitorCancel != nil {
+		return
+	}
+
+	ctx, db.monitorCancel = context.WithCancel(ctx)
+	db.monitorDone = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		healthy := true
+
+		timeUtils.Every(ctx, interval, func(ctx context.Context) {
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			_, err := db.HealthCheck(pingCtx)
+			cancel()
+
+			if ctx.Err() != nil || (err == nil) == healthy {
+				return
+			}
+
+			healthy = err == nil
+
+			if healthy {
+				db.logger().Info("database connectivity restored")
+			} else {
+				db.logger().Error("database connectivity lost", "error", err)
+			}
+
+			if onChange != nil {
+				onChange(healthy, err)
+			}
+		})
+	}(db.monitorDone)
+}
+
+// StopHealthMonitor stops the health monitor
+func (db *DB) StopHealthMonitor() {
+	db.monitorMutex.Lock()
+	cancel := db.monitorCancel
+	done := db.monitorDone
+	db.monitorCancel = nil
+	db.monitorDone = nil
+	db.monitorMutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+/*
+	Bad connection retry
+
+	The pool can hand out connections which were closed by the server (e.g. after
+	wait_timeout), the driver reports these as bad or closed connections. Reads are
+	retried once on a new connection, writes only when the driver guarantees the
+	statement was not sent (driver.ErrBadConn)
+*/
+
+// IsBadConnection checks if err is caused by a broken or closed connection
+func IsBadConnection(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	msg := err.Error()
+
+	return strings.Contains(msg, "invalid connection") || strings.Contains(msg, "connection is already closed")
+}
+
+// retry calls fn a second time if the first call failed because of a bad connection
+// and retryable returns true for the error
+func (db *DB) retry(retryable func(err error) bool, fn func() error) error {
+	err := fn()
+	if err == nil || !retryable(err) {
+		return err
+	}
+
+	db.logger().Warn("retrying query after bad connection", "error", err)
+
+	return fn()
+}
+
+// isBadConnectionBeforeWrite checks if a write failed before the statement was sent
+func isBadConnectionBeforeWrite(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// Get for Queryer, retried on a bad connection
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.retry(IsBadConnection, func() error {
+		return db.DB.Get(dest, query, args...)
+	})
+}
+
+// Select for Queryer, retried on a bad connection
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.retry(IsBadConnection, func() error {
+		return db.DB.Select(dest, query, args...)
+	})
+}
+
+// QueryxContext for ContextQueryer, retried on a bad connection
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+
+	err := db.retry(IsBadConnection, func() error {
+		var err error
+		rows, err = db.DB.QueryxContext(ctx, query, args...)
+		return err
+	})
+
+	return rows, err
+}
+
+// Exec for Queryer, retried when the connection was bad before the statement was sent
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+
+	err := db.retry(isBadConnectionBeforeWrite, func() error {
+		var err error
+		result, err = db.DB.Exec(query, args...)
+		return err
+	})
+
+	return result, err
+}
+
+// NamedExec for Queryer, retried when the connection was bad before the statement
+// was sent
+func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	var result sql.Result
+
+	err := db.retry(isBadConnectionBeforeWrite, func() error {
+		var err error
+		result, err = db.DB.NamedExec(query, arg)
+		return err
+	})
+
+	return result, err
+}
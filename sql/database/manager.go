@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Manager holds named database handles which are connected lazily on first use
+type Manager struct {
+	configs    map[string]*Configuration
+	dbs        map[string]*DB
+	connecting map[string]*connectCall
+	mutex      sync.Mutex
+}
+
+// connectCall is an in flight connection of a named database
+type connectCall struct {
+	wg  sync.WaitGroup
+	db  *DB
+	err error
+}
+
+// NewManager creates a manager for the given named configurations
+func NewManager(configs map[string]*Configuration) *Manager {
+	manager := &Manager{
+		configs:    map[string]*Configuration{},
+		dbs:        map[string]*DB{},
+		connecting: map[string]*connectCall{},
+	}
+
+	for name, config := range configs {
+		manager.configs[name] = config
+	}
+
+	return manager
+}
+
+// Add adds a named configuration, an existing connection with the same name is
+// kept until Close is called
+func (manager *Manager) Add(name string, config *Configuration) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.configs[name] = config
+}
+
+// Names returns the names of all configurations
+func (manager *Manager) Names() []string {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	names := make([]string, 0, len(manager.configs))
+	for name := range manager.configs {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// Get returns the database with the given name, it is connected on first use.
+// Concurrent calls for the same name wait for a single connection, other names are
+// not blocked. Failed connections are retried on the next call
+func (manager *Manager) Get(name string) (*DB, error) {
+	manager.mutex.Lock()
+
+	if db, ok := manager.dbs[name]; ok {
+		manager.mutex.Unlock()
+		return db, nil
+	}
+
+	if c, ok := manager.connecting[name]; ok {
+		manager.mutex.Unlock()
+		c.wg.Wait()
+
+		return c.db, c.err
+	}
+
+	config, ok := manager.configs[name]
+	if !ok {
+		manager.mutex.Unlock()
+		return nil, fmt.Errorf("unknown database %v", name)
+	}
+
+	c := &connectCall{}
+	c.wg.Add(1)
+	manager.connecting[name] = c
+
+	manager.mutex.Unlock()
+
+	defer c.wg.Done()
+
+	c.db, c.err = New(config)
+	if c.err != nil {
+		c.err = fmt.Errorf("can't connect to database %v: %v", name, c.err)
+	}
+
+	manager.mutex.Lock()
+	delete(manager.connecting, name)
+
+	if c.err == nil {
+		manager.dbs[name] = c.db
+	}
+
+	manager.mutex.Unlock()
+
+	return c.db, c.err
+}
+
+// MustGet returns the database with the given name and panics if it can not be
+// connected
+func (manager *Manager) MustGet(name string) *DB {
+	db, err := manager.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// Health checks all connected databases, databases which are not connected yet are
+// not included
+func (manager *Manager) Health(ctx context.Context) map[string]error {
+	manager.mutex.Lock()
+	dbs := map[string]*DB{}
+	for name, db := range manager.dbs {
+		dbs[name] = db
+	}
+	manager.mutex.Unlock()
+
+	status := map[string]error{}
+
+	for name, db := range dbs {
+		_, err := db.HealthCheck(ctx)
+		status[name] = err
+	}
+
+	return status
+}
+
+// Close closes all connected databases, they are reconnected on the next Get
+func (manager *Manager) Close() error {
+	manager.mutex.Lock()
+	dbs := manager.dbs
+	manager.dbs = map[string]*DB{}
+	manager.mutex.Unlock()
+
+	var firstErr error
+
+	for _, db := range dbs {
+		db.StopHealthMonitor()
+
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
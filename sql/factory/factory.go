@@ -0,0 +1,260 @@
+// Package factory builds and inserts model structs for tests and seeding. A factory
+// has default field generators which can be overridden per call, dependencies
+// (e.g. the organization of a membership) are created and linked before insert
+package factory
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/model"
+)
+
+// Generator generates a field value, seq is the sequence number of the object
+// built by the factory (starting at 1)
+type Generator func(seq int) interface{}
+
+// Sequence returns a generator which formats the sequence number, for example
+// Sequence("user%d@example.com")
+func Sequence(format string) Generator {
+	return func(seq int) interface{} {
+		return fmt.Sprintf(format, seq)
+	}
+}
+
+// Creator is implemented by factories so they can be used as dependency
+type Creator interface {
+	CreateObject(queryer database.Queryer) (interface{}, error)
+}
+
+// field is a field generator
+type field struct {
+	path      string
+	generator Generator
+}
+
+// dependency is created before insert, the value at sourcePath is assigned to path
+type dependency struct {
+	path       string
+	creator    Creator
+	sourcePath string
+}
+
+// options of a factory or a single build
+type options struct {
+	table        model.Tabler
+	fields       []*field
+	dependencies []*dependency
+}
+
+// Option configures a factory or overrides fields for a single build
+type Option func(o *options)
+
+// WithTable sets the table used by Create
+func WithTable(table model.Tabler) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithField sets a field (dotted paths are allowed, see structural.SetPath) to a
+// value, a Generator or a func(seq int) interface{} is called for each object
+func WithField(path string, value interface{}) Option {
+	var generator Generator
+
+	switch v := value.(type) {
+	case Generator:
+		generator = v
+	case func(seq int) interface{}:
+		generator = v
+	default:
+		generator = func(int) interface{} {
+			return value
+		}
+	}
+
+	return func(o *options) {
+		o.fields = append(o.fields, &field{path: path, generator: generator})
+	}
+}
+
+// WithDependency creates an object with creator before insert and assigns the
+// value at sourcePath of the created object (e.g. "ID") to path
+func WithDependency(path string, creator Creator, sourcePath string) Option {
+	return func(o *options) {
+		o.dependencies = append(o.dependencies, &dependency{path: path, creator: creator, sourcePath: sourcePath})
+	}
+}
+
+// Factory builds objects of type T
+type Factory[T any] struct {
+	options *options
+	mutex   sync.Mutex
+	seq     int
+}
+
+// New creates a factory for T
+func New[T any](opts ...Option) *Factory[T] {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Factory[T]{options: o}
+}
+
+// nextSeq returns the next sequence number
+func (factory *Factory[T]) nextSeq() int {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	factory.seq++
+
+	return factory.seq
+}
+
+// Build creates an object with defaults (see structural.ApplyDefaults), the factory
+// fields and the overrides applied, dependencies are not created
+func (factory *Factory[T]) Build(overrides ...Option) (*T, error) {
+	obj, _, err := factory.build(overrides)
+	return obj, err
+}
+
+func (factory *Factory[T]) build(overrides []Option) (*T, *options, error) {
+	o := &options{
+		table:        factory.options.table,
+		fields:       append([]*field{}, factory.options.fields...),
+		dependencies: append([]*dependency{}, factory.options.dependencies...),
+	}
+
+	for _, override := range overrides {
+		override(o)
+	}
+
+	obj := new(T)
+
+	if reflect.TypeOf(obj).Elem().Kind() == reflect.Struct {
+		err := structural.ApplyDefaults(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	seq := factory.nextSeq()
+
+	for _, f := range o.fields {
+		err := structural.SetPath(obj, f.path, f.generator(seq))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return obj, o, nil
+}
+
+// Create builds an object, creates its dependencies and inserts it. The primary
+// key field is set from the last insert id if it is still zero
+func (factory *Factory[T]) Create(queryer database.Queryer, overrides ...Option) (*T, error) {
+	obj, o, err := factory.build(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.table == nil {
+		return nil, fmt.Errorf("factory for %v has no table", reflect.TypeOf(obj).Elem())
+	}
+
+	for _, dep := range o.dependencies {
+		created, err := dep.creator.CreateObject(queryer)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := structural.GetPath(created, dep.sourcePath)
+		if err != nil {
+			return nil, err
+		}
+
+		err = structural.SetPath(obj, dep.path, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := o.table.Insert([]interface{}{obj}, queryer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setInsertID(obj, o.table, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// CreateN creates n objects
+func (factory *Factory[T]) CreateN(queryer database.Queryer, n int, overrides ...Option) ([]*T, error) {
+	objs := make([]*T, 0, n)
+
+	for i := 0; i < n; i++ {
+		obj, err := factory.Create(queryer, overrides...)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// CreateObject for the Creator interface
+func (factory *Factory[T]) CreateObject(queryer database.Queryer) (interface{}, error) {
+	return factory.Create(queryer)
+}
+
+// setInsertID sets a zero integer primary key field to the last insert id
+func setInsertID(obj interface{}, table model.Tabler, result sql.Result) error {
+	primary := table.TableDescriptor().PrimaryColumn
+	if primary == nil || result == nil {
+		return nil
+	}
+
+	field := reflect.ValueOf(obj).Elem().FieldByName(primary.ActualName)
+	if !field.IsValid() || !field.IsZero() {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	return structural.SetValue(field, id)
+}
+
+// CleanTables deletes all rows of the tables in reverse order, so list parent
+// tables before the tables that reference them
+func CleanTables(queryer database.Queryer, tables ...model.Tabler) error {
+	for i := len(tables) - 1; i >= 0; i-- {
+		_, err := queryer.Exec(fmt.Sprintf("DELETE FROM `%v`", tables[i].TableName()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
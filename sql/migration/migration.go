@@ -4,9 +4,10 @@ package migration
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
-	"log"
 
+	"github.com/almerlucke/go-utils/logging"
 	"github.com/almerlucke/go-utils/sql/database"
 	"github.com/almerlucke/go-utils/sql/model"
 	"github.com/almerlucke/go-utils/sql/types"
@@ -47,17 +48,21 @@ type (
 	Version struct {
 		version    string
 		migrations []Migration
+		rollback   []Migration
 	}
 )
 
 // Global migration tabler
 var _migrationTable model.Tabler
 
+// Logger is used to log performed migrations, if nil the default logger is used
+var Logger logging.Logger
+
 // Initialize table
 func init() {
 	table, err := model.NewTable("_migration", &Info{})
 	if err != nil {
-		log.Fatalf("failed to create migration table %v", err)
+		panic(fmt.Sprintf("failed to create migration table %v", err))
 	}
 
 	_migrationTable = table
@@ -97,6 +102,29 @@ func (version *Version) Migrate(queryer database.Queryer) error {
 	return nil
 }
 
+// Rollback reverts the version by performing the rollback migrations
+func (version *Version) Rollback(queryer database.Queryer) error {
+	for _, migration := range version.rollback {
+		err := migration.Migrate(queryer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithRollback sets the migrations which revert the version, used by Rollback
+func (version *Version) WithRollback(migrations ...Migration) *Version {
+	version.rollback = migrations
+	return version
+}
+
+// String returns the version string
+func (version *Version) String() string {
+	return version.version
+}
+
 // NewQueryMigration create a new migration with a query
 func NewQueryMigration(query string) Migration {
 	return &QueryMigration{Query: query}
@@ -117,42 +145,77 @@ func NewVersion(version string, migrations []Migration) *Version {
 	return &Version{version: version, migrations: migrations}
 }
 
-// Migrate database versions
-func Migrate(queryer database.Queryer, currentVersion string, versions []*Version) error {
+// info creates the migration table if needed and returns the info row, the row is
+// inserted with version "0" if it does not exist
+func info(queryer database.Queryer) (*Info, error) {
 	// Create table if not exists
 	_, err := queryer.Exec(_migrationTable.TableQuery())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get info row
 	result, err := _migrationTable.Select("*").Run(queryer)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	rows := result.([]*Info)
+	if len(rows) > 0 {
+		return rows[0], nil
 	}
 
 	// Prepare info
 	info := &Info{ID: 1, Version: "0", MigrationDate: types.NewDateTime()}
-	rows := result.([]*Info)
-	if len(rows) == 0 {
-		_, err := _migrationTable.Insert([]interface{}{info}, queryer)
-		if err != nil {
-			return err
+
+	_, err = _migrationTable.Insert([]interface{}{info}, queryer)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Status returns the migration info of the database, version "0" means no
+// migrations were performed
+func Status(queryer database.Queryer) (*Info, error) {
+	return info(queryer)
+}
+
+// Pending returns the versions up to and including currentVersion which are not
+// yet migrated according to info
+func Pending(info *Info, currentVersion string, versions []*Version) []*Version {
+	pending := []*Version{}
+
+	for _, migrationVersion := range versions {
+		if info.Version < migrationVersion.version && migrationVersion.version <= currentVersion {
+			pending = append(pending, migrationVersion)
 		}
-	} else {
-		info = rows[0]
 	}
 
+	return pending
+}
+
+// Migrate database versions
+func Migrate(queryer database.Queryer, currentVersion string, versions []*Version) error {
+	info, err := info(queryer)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.OrDefault(Logger)
+
 	// If current version is greater than database version we need to run migrations
 	if currentVersion > info.Version {
-		for _, migrationVersion := range versions {
-			// We only perform migrations for versions up to info version and including current version
-			if info.Version < migrationVersion.version && migrationVersion.version <= currentVersion {
-				// Perform migration of the version
-				migrationErr := migrationVersion.Migrate(queryer)
-				if migrationErr != nil {
-					return migrationErr
-				}
+		// We only perform migrations for versions up to info version and including current version
+		for _, migrationVersion := range Pending(info, currentVersion, versions) {
+			// Perform migration of the version
+			logger.Info("migrating", "version", migrationVersion.version)
+
+			migrationErr := migrationVersion.Migrate(queryer)
+			if migrationErr != nil {
+				logger.Error("migration failed", "version", migrationVersion.version, "error", migrationErr)
+				return migrationErr
 			}
 		}
 
@@ -166,8 +229,69 @@ func Migrate(queryer database.Queryer, currentVersion string, versions []*Versio
 		}
 	} else if currentVersion < info.Version {
 		// The current code version is lacking behind the database version, this is not allowed
+		logger.Error("database migration version is greater than current version", "database", info.Version, "current", currentVersion)
 		return errors.New("database migration version is greater than current version")
 	}
 
 	return nil
 }
+
+// Rollback reverts the database to targetVersion by performing the rollback
+// migrations of the migrated versions greater than targetVersion in reverse order.
+// All of these versions must have rollback migrations (see Version.WithRollback),
+// otherwise nothing is performed. Use "0" as target to revert all versions
+func Rollback(queryer database.Queryer, targetVersion string, versions []*Version) error {
+	info, err := info(queryer)
+	if err != nil {
+		return err
+	}
+
+	if targetVersion >= info.Version {
+		return nil
+	}
+
+	rollback := []*Version{}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		migrationVersion := versions[i]
+
+		if targetVersion < migrationVersion.version && migrationVersion.version <= info.Version {
+			if len(migrationVersion.rollback) == 0 {
+				return fmt.Errorf("version %v has no rollback migrations", migrationVersion.version)
+			}
+
+			rollback = append(rollback, migrationVersion)
+		}
+	}
+
+	logger := logging.OrDefault(Logger)
+
+	for _, migrationVersion := range rollback {
+		logger.Info("rolling back", "version", migrationVersion.version)
+
+		rollbackErr := migrationVersion.Rollback(queryer)
+		if rollbackErr != nil {
+			logger.Error("rollback failed", "version", migrationVersion.version, "error", rollbackErr)
+			return rollbackErr
+		}
+	}
+
+	info.Version = targetVersion
+	info.MigrationDate = types.NewDateTime()
+
+	_, err = _migrationTable.Update(info, queryer)
+
+	return err
+}
+
+// NewRenameTableMigration creates a migration which renames a table, the dialect
+// determines the statement
+func NewRenameTableMigration(dialect model.Dialect, oldName string, newName string) Migration {
+	return &QueryMigration{Query: dialect.RenameTableQuery(oldName, newName)}
+}
+
+// NewRenameColumnMigration creates a migration which renames a column of a table,
+// the dialect determines the statement
+func NewRenameColumnMigration(dialect model.Dialect, table string, oldName string, newName string) Migration {
+	return &QueryMigration{Query: dialect.RenameColumnQuery(table, oldName, newName)}
+}
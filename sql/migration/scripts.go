@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// UpScriptSuffix is the file suffix of migration scripts
+	UpScriptSuffix = ".up.sql"
+
+	// DownScriptSuffix is the file suffix of rollback scripts
+	DownScriptSuffix = ".down.sql"
+)
+
+// LoadScriptVersions creates versions from the SQL scripts in dir. Scripts are
+// named version_description.up.sql with an optional version_description.down.sql
+// rollback script, e.g. 0001_create_users.up.sql. Scripts with the same version are
+// grouped in one version and performed in file name order, versions are sorted.
+// Like ScriptMigration each script can contain only one query
+func LoadScriptVersions(dir string) ([]*Version, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	versionMap := map[string]*Version{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+
+		up := strings.HasSuffix(name, UpScriptSuffix)
+		if !up && !strings.HasSuffix(name, DownScriptSuffix) {
+			continue
+		}
+
+		versionString := strings.SplitN(name, "_", 2)[0]
+		if up {
+			versionString = strings.TrimSuffix(versionString, UpScriptSuffix)
+		} else {
+			versionString = strings.TrimSuffix(versionString, DownScriptSuffix)
+		}
+
+		if versionString == "" {
+			return nil, fmt.Errorf("script %v has no version", name)
+		}
+
+		version, ok := versionMap[versionString]
+		if !ok {
+			version = NewVersion(versionString, []Migration{})
+			versionMap[versionString] = version
+		}
+
+		migration := NewScriptMigration(filepath.Join(dir, name))
+
+		if up {
+			version.migrations = append(version.migrations, migration)
+		} else {
+			version.rollback = append(version.rollback, migration)
+		}
+	}
+
+	versions := make([]*Version, 0, len(versionMap))
+
+	for _, version := range versionMap {
+		if len(version.migrations) == 0 {
+			return nil, fmt.Errorf("version %v has a rollback script but no migration script", version.version)
+		}
+
+		// Rollback scripts of a version are performed in reverse order
+		for i, j := 0, len(version.rollback)-1; i < j; i, j = i+1, j-1 {
+			version.rollback[i], version.rollback[j] = version.rollback[j], version.rollback[i]
+		}
+
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].version < versions[j].version
+	})
+
+	return versions, nil
+}
@@ -0,0 +1,220 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// Cache is the cache used by CachedTable, a *memory.Cache[string, interface{}]
+// from the cache/memory package can be used
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	SetWithTTL(key string, value interface{}, ttl time.Duration)
+	Delete(key string)
+}
+
+// CachedTable is a read-through caching decorator for a table, Get by primary key
+// and tagged selects run with Run are cached. Insert, Update, UpdateChanges and
+// Delete invalidate the cached row and all tagged selects. Cached objects are
+// copied when returned so callers can modify them. Writes which bypass the
+// cached table (raw queries, other processes) are only seen after the ttl expires
+type CachedTable struct {
+	*Table
+
+	Cache Cache
+	TTL   time.Duration
+
+	mutex      sync.Mutex
+	selectKeys map[string]struct{}
+
+	// generation is incremented by each invalidation, results of queries that ran
+	// during an invalidation are not cached
+	generation uint64
+}
+
+// Cached wraps a table with a read-through cache, values expire after ttl
+func Cached(table *Table, cache Cache, ttl time.Duration) *CachedTable {
+	return &CachedTable{
+		Table:      table,
+		Cache:      cache,
+		TTL:        ttl,
+		selectKeys: map[string]struct{}{},
+	}
+}
+
+// Get selects the row with the given primary key from the cache or the table,
+// rows that do not exist are not cached
+func (cached *CachedTable) Get(key interface{}, queryer database.Queryer) (interface{}, error) {
+	cacheKey := cached.rowKey(key)
+
+	if obj, ok := cached.Cache.Get(cacheKey); ok {
+		return copyResult(obj), nil
+	}
+
+	generation := cached.currentGeneration()
+
+	obj, err := cached.Table.Get(key, queryer)
+	if err != nil {
+		return nil, err
+	}
+
+	cached.store(generation, cacheKey, obj, false)
+
+	return copyResult(obj), nil
+}
+
+// Run runs a select of the table and caches the result under tag and the args,
+// only use it for selects that read from this table
+func (cached *CachedTable) Run(tag string, sel *Select, queryer database.Queryer, args ...interface{}) (interface{}, error) {
+	cacheKey := cached.selectKey(tag, args)
+
+	if result, ok := cached.Cache.Get(cacheKey); ok {
+		return copyResult(result), nil
+	}
+
+	generation := cached.currentGeneration()
+
+	result, err := sel.Run(queryer, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	cached.store(generation, cacheKey, result, true)
+
+	return copyResult(result), nil
+}
+
+// Insert objects into the table and invalidate the tagged selects
+func (cached *CachedTable) Insert(objs []interface{}, queryer database.Queryer) (sql.Result, error) {
+	defer cached.invalidate()
+
+	return cached.Table.Insert(objs, queryer)
+}
+
+// Update object and invalidate its cached row and the tagged selects
+func (cached *CachedTable) Update(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	defer cached.invalidate(obj)
+
+	return cached.Table.Update(obj, queryer)
+}
+
+// UpdateChanges updates the changed columns of new and invalidates its cached row
+// and the tagged selects
+func (cached *CachedTable) UpdateChanges(old interface{}, new interface{}, queryer database.Queryer) (sql.Result, error) {
+	defer cached.invalidate(new)
+
+	return cached.Table.UpdateChanges(old, new, queryer)
+}
+
+// Delete object and invalidate its cached row and the tagged selects
+func (cached *CachedTable) Delete(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	defer cached.invalidate(obj)
+
+	return cached.Table.Delete(obj, queryer)
+}
+
+// Invalidate removes the cached rows of the given primary keys and all tagged selects
+func (cached *CachedTable) Invalidate(keys ...interface{}) {
+	cached.mutex.Lock()
+	cached.generation++
+	selectKeys := cached.selectKeys
+	cached.selectKeys = map[string]struct{}{}
+	cached.mutex.Unlock()
+
+	for _, key := range keys {
+		cached.Cache.Delete(cached.rowKey(key))
+	}
+
+	for key := range selectKeys {
+		cached.Cache.Delete(key)
+	}
+}
+
+// currentGeneration returns the generation to pass to store after querying
+func (cached *CachedTable) currentGeneration() uint64 {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+
+	return cached.generation
+}
+
+// store caches value if no invalidation happened since generation was read, the
+// key of a select is registered so it is removed by the next invalidation
+func (cached *CachedTable) store(generation uint64, cacheKey string, value interface{}, isSelect bool) {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+
+	if cached.generation != generation {
+		return
+	}
+
+	if isSelect {
+		cached.selectKeys[cacheKey] = struct{}{}
+	}
+
+	cached.Cache.SetWithTTL(cacheKey, value, cached.TTL)
+}
+
+// invalidate the cached rows of objs and all tagged selects
+func (cached *CachedTable) invalidate(objs ...interface{}) {
+	keys := make([]interface{}, 0, len(objs))
+
+	for _, obj := range objs {
+		v := reflect.Indirect(reflect.ValueOf(obj))
+		if v.Kind() == reflect.Struct {
+			keys = append(keys, v.FieldByName(cached.Descriptor.PrimaryColumn.ActualName).Interface())
+		}
+	}
+
+	cached.Invalidate(keys...)
+}
+
+func (cached *CachedTable) rowKey(key interface{}) string {
+	return fmt.Sprintf("%v:row:%v", cached.Name, key)
+}
+
+func (cached *CachedTable) selectKey(tag string, args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("%#v", arg)
+	}
+
+	return fmt.Sprintf("%v:select:%v:%v", cached.Name, tag, strings.Join(parts, ","))
+}
+
+// copyResult returns a copy of a cached struct pointer or slice of struct pointers,
+// the structs are copied shallowly
+func copyResult(result interface{}) interface{} {
+	v := reflect.ValueOf(result)
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(v.Elem())
+
+		return copied.Interface()
+	case reflect.Slice:
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			element := v.Index(i)
+			if element.Kind() == reflect.Ptr && !element.IsNil() {
+				copiedElement := reflect.New(element.Type().Elem())
+				copiedElement.Elem().Set(element.Elem())
+				element = copiedElement
+			}
+
+			copied.Index(i).Set(element)
+		}
+
+		return copied.Interface()
+	}
+
+	return result
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the SQL dialect of the database a table is used with
+type Dialect string
+
+// Supported dialects
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite3"
+)
+
+// OnUpdateTimestamp checks if the dialect supports ON UPDATE CURRENT_TIMESTAMP
+// column definitions, if not the modified timestamp is written by Update
+func (dialect Dialect) OnUpdateTimestamp() bool {
+	return dialect.isMySQL()
+}
+
+// isMySQL checks if the dialect is MySQL, an empty dialect defaults to MySQL
+func (dialect Dialect) isMySQL() bool {
+	return dialect == "" || dialect == DialectMySQL
+}
+
+// Quote quotes an identifier for the dialect
+func (dialect Dialect) Quote(name string) string {
+	if dialect == DialectPostgres {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// RenameTableQuery returns the statement to rename a table
+func (dialect Dialect) RenameTableQuery(oldName string, newName string) string {
+	if dialect.isMySQL() {
+		return fmt.Sprintf("RENAME TABLE %v TO %v", dialect.Quote(oldName), dialect.Quote(newName))
+	}
+
+	return fmt.Sprintf("ALTER TABLE %v RENAME TO %v", dialect.Quote(oldName), dialect.Quote(newName))
+}
+
+// RenameColumnQuery returns the statement to rename a column, for MySQL this
+// requires version 8
+func (dialect Dialect) RenameColumnQuery(table string, oldName string, newName string) string {
+	return fmt.Sprintf("ALTER TABLE %v RENAME COLUMN %v TO %v", dialect.Quote(table), dialect.Quote(oldName), dialect.Quote(newName))
+}
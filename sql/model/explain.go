@@ -0,0 +1,137 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+	timeUtils "github.com/almerlucke/go-utils/time"
+)
+
+// ExplainRow is a row of MySQL EXPLAIN output
+type ExplainRow struct {
+	ID           int64   `json:"id"`
+	SelectType   string  `json:"selectType"`
+	Table        string  `json:"table"`
+	Type         string  `json:"type"`
+	PossibleKeys string  `json:"possibleKeys"`
+	Key          string  `json:"key"`
+	KeyLength    string  `json:"keyLength"`
+	Ref          string  `json:"ref"`
+	Rows         int64   `json:"rows"`
+	Filtered     float64 `json:"filtered"`
+	Extra        string  `json:"extra"`
+}
+
+// explainResult is used to scan EXPLAIN output which contains NULL values
+type explainResult struct {
+	ID           sql.NullInt64   `db:"id"`
+	SelectType   sql.NullString  `db:"select_type"`
+	Table        sql.NullString  `db:"table"`
+	Partitions   sql.NullString  `db:"partitions"`
+	Type         sql.NullString  `db:"type"`
+	PossibleKeys sql.NullString  `db:"possible_keys"`
+	Key          sql.NullString  `db:"key"`
+	KeyLength    sql.NullString  `db:"key_len"`
+	Ref          sql.NullString  `db:"ref"`
+	Rows         sql.NullInt64   `db:"rows"`
+	Filtered     sql.NullFloat64 `db:"filtered"`
+	Extra        sql.NullString  `db:"Extra"`
+}
+
+// UsesIndex checks if the row uses a key
+func (row *ExplainRow) UsesIndex() bool {
+	return row.Key != ""
+}
+
+// FullScan checks if the row is a full table scan
+func (row *ExplainRow) FullScan() bool {
+	return row.Type == "ALL"
+}
+
+// Explain runs EXPLAIN for the select query with the given args
+func (sel *Select) Explain(queryer database.Queryer, args ...interface{}) ([]*ExplainRow, error) {
+	if sel.err != nil {
+		return nil, sel.err
+	}
+
+	results := []*explainResult{}
+
+	err := queryer.Select(&results, "EXPLAIN "+sel.Query(), sel.queryArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*ExplainRow, len(results))
+
+	for i, result := range results {
+		rows[i] = &ExplainRow{
+			ID:           result.ID.Int64,
+			SelectType:   result.SelectType.String,
+			Table:        result.Table.String,
+			Type:         result.Type.String,
+			PossibleKeys: result.PossibleKeys.String,
+			Key:          result.Key.String,
+			KeyLength:    result.KeyLength.String,
+			Ref:          result.Ref.String,
+			Rows:         result.Rows.Int64,
+			Filtered:     result.Filtered.Float64,
+			Extra:        result.Extra.String,
+		}
+	}
+
+	return rows, nil
+}
+
+// SlowQueryOptions configures logging of slow queries
+type SlowQueryOptions struct {
+	// Threshold is the duration after which a query is logged as slow, 0 disables
+	// slow query logging
+	Threshold time.Duration
+
+	// Explain runs EXPLAIN for slow selects and logs the plan
+	Explain bool
+
+	// Latency tracks the duration of all selects, can be nil
+	Latency *timeUtils.LatencyTracker
+}
+
+// logSlowSelect logs a select which exceeded the slow query threshold
+func (sel *Select) logSlowSelect(queryer database.Queryer, query string, args []interface{}, duration time.Duration) {
+	options := sel.SlowQuery
+	if options != nil && options.Latency != nil {
+		options.Latency.Observe(duration)
+	}
+
+	if options == nil || options.Threshold <= 0 || duration < options.Threshold {
+		return
+	}
+
+	logger := logging.OrDefault(sel.Logger)
+	logged := loggedQuery(query)
+	logger.Warn("slow query", "sql", logged, "duration", duration)
+
+	if !options.Explain {
+		return
+	}
+
+	rows := []*explainResult{}
+
+	err := queryer.Select(&rows, "EXPLAIN "+query, args...)
+	if err != nil {
+		logger.Warn("slow query explain failed", "sql", logged, "error", err)
+		return
+	}
+
+	for _, row := range rows {
+		logger.Warn("slow query plan",
+			"sql", logged,
+			"table", row.Table.String,
+			"type", row.Type.String,
+			"key", row.Key.String,
+			"rows", row.Rows.Int64,
+			"extra", row.Extra.String,
+		)
+	}
+}
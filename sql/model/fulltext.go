@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchMode is the search modifier of a MATCH ... AGAINST expression
+type MatchMode string
+
+const (
+	// MatchNaturalLanguage searches the query as a natural language phrase
+	MatchNaturalLanguage MatchMode = "IN NATURAL LANGUAGE MODE"
+
+	// MatchBoolean searches with boolean operators, e.g. "+mysql -oracle"
+	MatchBoolean MatchMode = "IN BOOLEAN MODE"
+
+	// MatchQueryExpansion performs a natural language search followed by a second
+	// search with the most relevant words of the first result added
+	MatchQueryExpansion MatchMode = "WITH QUERY EXPANSION"
+)
+
+// MatchExpression returns a MATCH ... AGAINST expression for the given fields, {{Field}}
+// templates in fields are resolved. The query is passed as argument, if mode is
+// empty MatchNaturalLanguage is used
+func (sel *Select) MatchExpression(fields string, mode MatchMode) string {
+	if mode == "" {
+		mode = MatchNaturalLanguage
+	}
+
+	return fmt.Sprintf("MATCH (%v) AGAINST (? %v)", replaceStructFieldsWithSQLFields(fields, sel.From.TemplateMap()), mode)
+}
+
+// Match adds a full-text search condition on the given fields, e.g.
+// Match("{{Title}},{{Body}}", "+go -java", MatchBoolean). The fields must be covered
+// by a single FULLTEXT KEY (see the fulltext sql tag). The condition is added as
+// scope so it is combined with the where clause
+func (sel *Select) Match(fields string, query string, mode MatchMode) *Select {
+	sel.ScopeConditions = append(sel.ScopeConditions, sel.MatchExpression(fields, mode))
+	sel.ScopeArgs = append(sel.ScopeArgs, query)
+	return sel
+}
+
+// fullTextKeys returns the FULLTEXT KEY definitions for the fulltext columns of a
+// table, columns with the same key name share a key
+func fullTextKeys(desc *TableDescriptor) []string {
+	names := []string{}
+	keys := map[string][]string{}
+
+	for _, column := range desc.Columns {
+		if !column.FullText {
+			continue
+		}
+
+		name := column.FullTextKey
+		if name == "" {
+			name = "ft_" + column.Name
+		}
+
+		if _, ok := keys[name]; !ok {
+			names = append(names, name)
+		}
+
+		keys[name] = append(keys[name], "`"+column.Name+"`")
+	}
+
+	definitions := make([]string, len(names))
+
+	for i, name := range names {
+		definitions[i] = fmt.Sprintf("FULLTEXT KEY `%v` (%v)", name, strings.Join(keys[name], ","))
+	}
+
+	return definitions
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// ErrTooManyRows is returned by Run when a select returns more rows than allowed
+// with MaxRows
+var ErrTooManyRows = errors.New("query returned too many rows")
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// Timeout cancels the select when it runs longer than d, Run returns
+// context.DeadlineExceeded. The timeout is only enforced if the queryer implements
+// database.ContextQueryer
+func (sel *Select) Timeout(d time.Duration) *Select {
+	sel.QueryTimeout = d
+	return sel
+}
+
+// Context runs the select with ctx, Run returns the context error when ctx is done.
+// Like Timeout the context is only enforced if the queryer implements
+// database.ContextQueryer
+func (sel *Select) Context(ctx context.Context) *Select {
+	sel.ctx = ctx
+	return sel
+}
+
+// MaxRows makes Run return ErrTooManyRows when the select returns more than n rows,
+// scanning stops at the cutoff. If the select has no limit clause a limit of n + 1
+// is added to the query
+func (sel *Select) MaxRows(n int) *Select {
+	sel.MaxRowCount = n
+	return sel
+}
+
+// guarded checks if the select has a context, timeout or row count cutoff
+func (sel *Select) guarded() bool {
+	return sel.ctx != nil || sel.QueryTimeout > 0 || sel.MaxRowCount > 0
+}
+
+// selectGuarded runs the query with the timeout and scans at most MaxRowCount rows
+// into dest, a pointer to a slice
+func (sel *Select) selectGuarded(dest interface{}, queryer database.Queryer, query string, args []interface{}) error {
+	contextQueryer, ok := queryer.(database.ContextQueryer)
+	if !ok {
+		err := queryer.Select(dest, query, args...)
+		if err != nil {
+			return err
+		}
+
+		if sel.MaxRowCount > 0 && reflect.ValueOf(dest).Elem().Len() > sel.MaxRowCount {
+			return ErrTooManyRows
+		}
+
+		return nil
+	}
+
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("dest must be a pointer to a slice")
+	}
+
+	slice = slice.Elem()
+
+	ctx := sel.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if sel.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sel.QueryTimeout)
+		defer cancel()
+	}
+
+	rows, err := contextQueryer.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	defer rows.Close()
+
+	elemType := slice.Type().Elem()
+	baseType := elemType
+	if baseType.Kind() == reflect.Ptr {
+		baseType = baseType.Elem()
+	}
+
+	structScan := baseType.Kind() == reflect.Struct && !reflect.PtrTo(baseType).Implements(scannerType)
+
+	for rows.Next() {
+		if sel.MaxRowCount > 0 && slice.Len() >= sel.MaxRowCount {
+			return ErrTooManyRows
+		}
+
+		v := reflect.New(baseType)
+
+		if structScan {
+			err = rows.StructScan(v.Interface())
+		} else {
+			err = rows.Scan(v.Interface())
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, v))
+		} else {
+			slice.Set(reflect.Append(slice, v.Elem()))
+		}
+	}
+
+	return contextError(ctx, rows.Err())
+}
+
+// contextError returns the context error if the context is done, drivers report
+// cancelled queries with their own errors
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// BeforeInserter is implemented by models that need to run code before they are
+// inserted with Table.Insert, returning an error aborts the insert
+type BeforeInserter interface {
+	BeforeInsert(queryer database.Queryer) error
+}
+
+// AfterInserter is implemented by models that need to run code after they are
+// inserted with Table.Insert
+type AfterInserter interface {
+	AfterInsert(queryer database.Queryer) error
+}
+
+// BeforeUpdater is implemented by models that need to run code before they are
+// updated with Table.Update or Table.UpdateChanges, returning an error aborts the update
+type BeforeUpdater interface {
+	BeforeUpdate(queryer database.Queryer) error
+}
+
+// AfterUpdater is implemented by models that need to run code after they are
+// updated with Table.Update or Table.UpdateChanges
+type AfterUpdater interface {
+	AfterUpdate(queryer database.Queryer) error
+}
+
+// BeforeDeleter is implemented by models that need to run code before they are
+// deleted with Table.Delete, returning an error aborts the delete
+type BeforeDeleter interface {
+	BeforeDelete(queryer database.Queryer) error
+}
+
+// AfterDeleter is implemented by models that need to run code after they are
+// deleted with Table.Delete
+type AfterDeleter interface {
+	AfterDelete(queryer database.Queryer) error
+}
+
+/*
+	Hooks are called with the same queryer as the operation, when the queryer is a
+	transaction the hooks take part in it. Errors of after hooks are returned
+	together with the result of the operation
+*/
+
+// Indexes of the hook interfaces in hookProbe
+const (
+	hookBeforeInsert = iota
+	hookAfterInsert
+	hookBeforeUpdate
+	hookAfterUpdate
+	hookBeforeDelete
+	hookAfterDelete
+)
+
+// hookProbe detects the hooks implemented by a model type once per type
+var hookProbe = structural.NewProbe(
+	structural.InterfaceType[BeforeInserter](),
+	structural.InterfaceType[AfterInserter](),
+	structural.InterfaceType[BeforeUpdater](),
+	structural.InterfaceType[AfterUpdater](),
+	structural.InterfaceType[BeforeDeleter](),
+	structural.InterfaceType[AfterDeleter](),
+)
+
+// HasHooks checks if obj implements one or more of the hook interfaces
+func HasHooks(obj interface{}) bool {
+	return hookProbe.Capabilities(obj).Any()
+}
+
+func beforeInsert(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookBeforeInsert) {
+		return obj.(BeforeInserter).BeforeInsert(queryer)
+	}
+
+	return nil
+}
+
+func afterInsert(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookAfterInsert) {
+		return obj.(AfterInserter).AfterInsert(queryer)
+	}
+
+	return nil
+}
+
+func beforeUpdate(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookBeforeUpdate) {
+		return obj.(BeforeUpdater).BeforeUpdate(queryer)
+	}
+
+	return nil
+}
+
+func afterUpdate(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookAfterUpdate) {
+		return obj.(AfterUpdater).AfterUpdate(queryer)
+	}
+
+	return nil
+}
+
+func beforeDelete(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookBeforeDelete) {
+		return obj.(BeforeDeleter).BeforeDelete(queryer)
+	}
+
+	return nil
+}
+
+func afterDelete(obj interface{}, queryer database.Queryer) error {
+	if hookProbe.Capabilities(obj).Has(hookAfterDelete) {
+		return obj.(AfterDeleter).AfterDelete(queryer)
+	}
+
+	return nil
+}
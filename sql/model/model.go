@@ -8,9 +8,9 @@ package model
 import (
 	"fmt"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/almerlucke/go-utils/reflection/structural"
 	"github.com/almerlucke/go-utils/sql/types"
@@ -19,8 +19,8 @@ import (
 // Model can be used as basis for records that can be updated and deleted
 type Model struct {
 	ID         uint64         `json:"id" db:"id" sql:"no update,NOT NULL AUTO_INCREMENT"`
-	CreatedAt  types.DateTime `json:"createdAt" db:"created_at" sql:"no update,DEFAULT CURRENT_TIMESTAMP"`
-	ModifiedAt types.DateTime `json:"modifiedAt" db:"modified_at" sql:"no update,DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	CreatedAt  types.DateTime `json:"createdAt" db:"created_at" sql:"no update,created,DEFAULT CURRENT_TIMESTAMP"`
+	ModifiedAt types.DateTime `json:"modifiedAt" db:"modified_at" sql:"no update,modified,DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
 	Deleted    bool           `json:"-" db:"deleted" sql:"DEFAULT 0"`
 }
 
@@ -35,6 +35,12 @@ type ColumnDescriptor struct {
 	HasDefault   bool
 	ActualName   string
 	NoUpdate     bool
+	IsTenant     bool
+	IsCreated    bool
+	IsModified   bool
+	Nullable     bool
+	FullText     bool
+	FullTextKey  string
 }
 
 // TableDescriptor table descriptor, is used by StructToTableDescriptor
@@ -42,6 +48,7 @@ type ColumnDescriptor struct {
 type TableDescriptor struct {
 	RawDescriptor structural.StructDescriptor
 	PrimaryColumn *ColumnDescriptor
+	TenantColumn  *ColumnDescriptor
 	Columns       []*ColumnDescriptor
 	ColumnMap     map[string]*ColumnDescriptor
 }
@@ -52,26 +59,33 @@ func (column *ColumnDescriptor) String() string {
 		return fmt.Sprintf("`%v` %v", column.Name, column.Raw)
 	}
 
-	if column.Raw == "" {
-		return fmt.Sprintf("`%v` %v", column.Name, column.Type)
+	columnType := column.Type
+	if column.Nullable && !strings.Contains(strings.ToUpper(column.Raw), "NULL") {
+		columnType += " NULL"
 	}
 
-	return fmt.Sprintf("`%v` %v %v", column.Name, column.Type, column.Raw)
-}
-
-var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
-var matchAllCap = regexp.MustCompile("([a-z0-9])([A-Z])")
+	if column.Raw == "" {
+		return fmt.Sprintf("`%v` %v", column.Name, columnType)
+	}
 
-func nameToMySQLName(name string) string {
-	snake := matchFirstCap.ReplaceAllString(name, "${1}_${2}")
-	snake = matchAllCap.ReplaceAllString(snake, "${1}_${2}")
-	return strings.ToLower(snake)
+	return fmt.Sprintf("`%v` %v %v", column.Name, columnType, column.Raw)
 }
 
 func fieldToMySQLType(field structural.FieldDescriptor) string {
 	t := field.Type()
 	kind := t.Kind()
 
+	// Custom types registered with structural.RegisterType take precedence
+	if info, ok := structural.LookupType(t); ok && info.SQLType != "" {
+		return info.SQLType
+	}
+
+	// Pointer fields map to the type of their element
+	if kind == reflect.Ptr {
+		t = t.Elem()
+		kind = t.Kind()
+	}
+
 	switch kind {
 	case reflect.Int:
 		if strconv.IntSize == 32 {
@@ -115,15 +129,6 @@ func fieldToMySQLType(field structural.FieldDescriptor) string {
 		if t.Elem().Kind() == reflect.Uint8 {
 			return "blob"
 		}
-	default:
-		if field.Type().PkgPath() == "github.com/almerlucke/go-utils/sql/types" {
-			typeName := field.Type().Name()
-			if typeName == "Date" {
-				return "date"
-			} else if typeName == "DateTime" {
-				return "datetime"
-			}
-		}
 	}
 
 	return ""
@@ -142,11 +147,26 @@ func parseSQLTag(tag string, columnDesc *ColumnDescriptor) bool {
 			columnDesc.IsPrimary = true
 		} else if component == "no update" {
 			columnDesc.NoUpdate = true
+		} else if component == "tenant" {
+			columnDesc.IsTenant = true
+		} else if component == "created" {
+			columnDesc.IsCreated = true
+		} else if component == "modified" {
+			columnDesc.IsModified = true
+		} else if component == "null" {
+			columnDesc.Nullable = true
+		} else if component == "fulltext" {
+			columnDesc.FullText = true
 		} else if component != "" {
 			defs := strings.SplitN(component, "=", 2)
 			if len(defs) == 2 {
 				if defs[0] == "name" {
 					columnDesc.Name = defs[1]
+				} else if defs[0] == "type" {
+					columnDesc.Type = defs[1]
+				} else if defs[0] == "fulltext" {
+					columnDesc.FullText = true
+					columnDesc.FullTextKey = defs[1]
 				}
 			} else {
 				columnDesc.Raw = defs[0]
@@ -165,20 +185,105 @@ func parseSQLTag(tag string, columnDesc *ColumnDescriptor) bool {
 
 // StructToTableDescriptor generates column and table info from structure fields and db/sql tags.
 // The sql tag is a comma separated list of definitions. The following keywords are defined.
-// - override: this indicates that the derived sql type should be replaced by the raw statement in the
-//   sql tag
-// - primary: this indicates that the fields is the primary key, otherwise the first field of the struct
-//   will be taken as primary key
-// - no update: this indicates that the field value will not be updated with Update
-// - name=name: can be used to override the derived name from "db" tag or field name
+//   - override: this indicates that the derived sql type should be replaced by the raw statement in the
+//     sql tag
+//   - primary: this indicates that the fields is the primary key, otherwise the first field of the struct
+//     will be taken as primary key
+//   - no update: this indicates that the field value will not be updated with Update
+//   - tenant: this indicates that the field holds the tenant (e.g. organization) id, see
+//     Table.TenantScope
+//   - created: the field is set to the current time by Insert when it is zero
+//   - modified: the field is set to the current time by Insert and Update
+//   - null: the column allows NULL, nil pointer fields are written as NULL. Pointer fields
+//     are mapped to the SQL type of their element type
+//   - fulltext: a FULLTEXT KEY is added for the column, see Select.Match
+//   - fulltext=key: columns with the same key name share a single FULLTEXT KEY, needed
+//     to match against multiple columns at once
+//   - name=name: can be used to override the derived name from "db" tag or field name
+//   - type=type: replaces the derived sql type, e.g. type=VARCHAR(255). Unlike override the
+//     NULL handling and raw definitions of the tag still apply
+//
+// The columns of an embedded struct can be changed per table with the sqloverride tag on the
+// embedded field (or on a blank _ field), a semicolon separated list of Field=definitions.
+// The definitions are parsed like a sql tag and applied on top of the sql tag of the field,
+// overrides of outer structs take precedence over overrides of inner structs:
+//
+//	type Product struct {
+//		Item `sqloverride:"Description=type=VARCHAR(512);Code=name=product_code"`
+//	}
+//
 // In all other cases the value is inserted as raw sql for a column in the CREATE table query
 // If the tag contains AUTO_INCREMENT or DEFAULT the field is not included with Insert
+//
+// Descriptors are cached per struct type, each call returns a copy that can be
+// modified freely
 func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
+	return StructToTableDescriptorWithNaming(obj, nil)
+}
+
+// StructToTableDescriptorWithNaming generates the table descriptor like StructToTableDescriptor,
+// column names without db tag are derived with naming. If naming is nil DefaultNamingStrategy
+// is used, only descriptors with the default naming strategy are cached
+func StructToTableDescriptorWithNaming(obj interface{}, naming NamingStrategy) (*TableDescriptor, error) {
 	desc, ok := structural.NewStructDescriptor(obj)
 	if !ok {
 		return nil, fmt.Errorf("can't get struct descriptor from object %v", obj)
 	}
 
+	if naming != nil {
+		tableDesc, err := structToTableDescriptor(desc, naming)
+		if err != nil {
+			return nil, err
+		}
+
+		return tableDesc, nil
+	}
+
+	if cached, ok := tableDescriptorCache.Load(desc.Type()); ok {
+		return cached.(*TableDescriptor).copy(desc), nil
+	}
+
+	tableDesc, err := structToTableDescriptor(desc, DefaultNamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	tableDescriptorCache.Store(desc.Type(), tableDesc)
+
+	return tableDesc.copy(desc), nil
+}
+
+// Cache of table descriptors keyed by reflect.Type
+var tableDescriptorCache sync.Map
+
+// copy returns a deep copy of the table descriptor with a new raw descriptor
+func (tableDesc *TableDescriptor) copy(raw structural.StructDescriptor) *TableDescriptor {
+	copied := &TableDescriptor{
+		RawDescriptor: raw,
+		Columns:       make([]*ColumnDescriptor, len(tableDesc.Columns)),
+		ColumnMap:     map[string]*ColumnDescriptor{},
+	}
+
+	for i, column := range tableDesc.Columns {
+		copiedColumn := *column
+
+		copied.Columns[i] = &copiedColumn
+		copied.ColumnMap[copiedColumn.ActualName] = &copiedColumn
+
+		if column == tableDesc.PrimaryColumn {
+			copied.PrimaryColumn = &copiedColumn
+		}
+
+		if column == tableDesc.TenantColumn {
+			copied.TenantColumn = &copiedColumn
+		}
+	}
+
+	return copied
+}
+
+// structToTableDescriptor generates the table descriptor from a struct descriptor
+func structToTableDescriptor(desc structural.StructDescriptor, naming NamingStrategy) (*TableDescriptor, error) {
 	tableDesc := &TableDescriptor{
 		RawDescriptor: desc,
 		Columns:       []*ColumnDescriptor{},
@@ -187,7 +292,12 @@ func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
 
 	var primaryColumn *ColumnDescriptor
 
-	err := desc.ScanFields(true, true, nil, func(field structural.FieldDescriptor, context interface{}) error {
+	overrides, err := sqlOverrides(desc.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	err = desc.ScanFields(true, true, nil, func(field structural.FieldDescriptor, context interface{}) error {
 		if field.Anonymous() {
 			return nil
 		}
@@ -198,7 +308,7 @@ func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
 
 		columnDesc := &ColumnDescriptor{
 			Type:       fieldToMySQLType(field),
-			Name:       nameToMySQLName(fieldName),
+			Name:       naming(fieldName),
 			ActualName: fieldName,
 		}
 
@@ -216,6 +326,10 @@ func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
 			skipColumn = skipColumn || parseSQLTag(fieldTag2, columnDesc)
 		}
 
+		if override, ok := overrides[fieldName]; ok {
+			skipColumn = skipColumn || parseSQLTag(override.definition, columnDesc)
+		}
+
 		if !skipColumn {
 			if columnDesc.Type == "" && !columnDesc.OverrideType {
 				return fmt.Errorf("unmappable field %v", field)
@@ -225,6 +339,10 @@ func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
 				primaryColumn = columnDesc
 			}
 
+			if columnDesc.IsTenant {
+				tableDesc.TenantColumn = columnDesc
+			}
+
 			tableDesc.Columns = append(tableDesc.Columns, columnDesc)
 			tableDesc.ColumnMap[columnDesc.ActualName] = columnDesc
 		}
@@ -232,9 +350,106 @@ func StructToTableDescriptor(obj interface{}) (*TableDescriptor, error) {
 		return nil
 	})
 
-	if primaryColumn == nil && len(tableDesc.Columns) > 0 {
+	if err != nil {
+		return nil, err
+	}
+
+	if primaryColumn != nil {
+		tableDesc.PrimaryColumn = primaryColumn
+	} else if len(tableDesc.Columns) > 0 {
 		tableDesc.PrimaryColumn = tableDesc.Columns[0]
 	}
 
-	return tableDesc, err
+	return tableDesc, nil
+}
+
+// SQLOverrideTag is the tag used to override the sql tags of embedded struct fields,
+// see StructToTableDescriptor
+const SQLOverrideTag = "sqloverride"
+
+// sqlOverride is an override of the sql tag of a field
+type sqlOverride struct {
+	definition string
+	depth      int
+}
+
+// sqlOverrides collects the sqloverride tags of struct type t and its embedded
+// structs keyed by field name
+func sqlOverrides(t reflect.Type) (map[string]sqlOverride, error) {
+	overrides := map[string]sqlOverride{}
+
+	err := collectSQLOverrides(t, 0, map[reflect.Type]bool{}, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func collectSQLOverrides(t reflect.Type, depth int, visiting map[reflect.Type]bool, overrides map[string]sqlOverride) error {
+	if visiting[t] {
+		return nil
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag, ok := field.Tag.Lookup(SQLOverrideTag)
+		if ok {
+			err := parseSQLOverrides(tag, depth, overrides)
+			if err != nil {
+				return fmt.Errorf("invalid %v tag on %v.%v: %v", SQLOverrideTag, t.Name(), field.Name, err)
+			}
+		}
+
+		if !field.Anonymous {
+			continue
+		}
+
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+
+		if embedded.Kind() == reflect.Struct {
+			err := collectSQLOverrides(embedded, depth+1, visiting, overrides)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseSQLOverrides parses a "Field=definition;Field=definition" tag, existing
+// overrides from a lower depth are kept
+func parseSQLOverrides(tag string, depth int, overrides map[string]sqlOverride) error {
+	for _, entry := range strings.Split(tag, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		components := strings.SplitN(entry, "=", 2)
+		if len(components) != 2 || strings.TrimSpace(components[0]) == "" {
+			return fmt.Errorf("expected Field=definition, got %q", entry)
+		}
+
+		name := strings.TrimSpace(components[0])
+
+		if existing, ok := overrides[name]; ok && existing.depth <= depth {
+			continue
+		}
+
+		overrides[name] = sqlOverride{
+			definition: strings.TrimSpace(components[1]),
+			depth:      depth,
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/almerlucke/go-utils/sql/types"
+)
+
+type benchmarkUser struct {
+	ID        int64          `db:"id" sql:"NOT NULL AUTO_INCREMENT"`
+	Name      string         `db:"name" sql:"override,VARCHAR(255) NOT NULL"`
+	Email     string         `db:"email" sql:"override,VARCHAR(255) NOT NULL"`
+	Active    bool           `db:"active"`
+	CreatedAt types.DateTime `db:"created_at" sql:"created,DEFAULT CURRENT_TIMESTAMP"`
+	UpdatedAt types.DateTime `db:"updated_at" sql:"modified,DEFAULT CURRENT_TIMESTAMP"`
+}
+
+func BenchmarkStructToTableDescriptorCached(b *testing.B) {
+	obj := &benchmarkUser{}
+
+	for i := 0; i < b.N; i++ {
+		_, err := StructToTableDescriptor(obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStructToTableDescriptorUncached(b *testing.B) {
+	obj := &benchmarkUser{}
+
+	for i := 0; i < b.N; i++ {
+		// Descriptors with an explicit naming strategy are not cached
+		_, err := StructToTableDescriptorWithNaming(obj, DefaultNamingStrategy)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
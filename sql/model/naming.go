@@ -0,0 +1,80 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy maps struct and field names to table and column names
+type NamingStrategy func(name string) string
+
+var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
+var matchAllCap = regexp.MustCompile("([a-z0-9])([A-Z])")
+
+// LegacySnakeCase maps names to snake_case like the column names of tables created
+// before naming strategies existed, a capital followed by a lowercase letter always
+// starts a word (UserID -> user_id, IDs -> i_ds)
+func LegacySnakeCase(name string) string {
+	snake := matchFirstCap.ReplaceAllString(name, "${1}_${2}")
+	snake = matchAllCap.ReplaceAllString(snake, "${1}_${2}")
+	return strings.ToLower(snake)
+}
+
+// SnakeCase maps names to snake_case, initialisms are kept together
+// (UserID -> user_id, HTTPServer -> http_server, IDs -> ids). It differs from
+// LegacySnakeCase for plural initialisms, which renames columns of existing tables
+func SnakeCase(name string) string {
+	runes := []rune(name)
+
+	var builder strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// Start of a new word after a lowercase letter or digit, or the last
+			// capital of an initialism followed by a lowercase word (not a plural s)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && nextLower && !isPluralSuffix(runes, i+1)) {
+				builder.WriteRune('_')
+			}
+		}
+
+		builder.WriteRune(unicode.ToLower(r))
+	}
+
+	return builder.String()
+}
+
+// isPluralSuffix checks if the rune at i is a lowercase s which ends a word
+func isPluralSuffix(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// LowerCamelCase maps names to lowerCamelCase, a leading initialism is lowercased
+// as a whole (ID -> id, HTTPServer -> httpServer, UserID -> userID)
+func LowerCamelCase(name string) string {
+	runes := []rune(name)
+
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// Keep the last capital of an initialism followed by a lowercase word
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralSuffix(runes, i+1) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// PreserveCase uses names as they are
+func PreserveCase(name string) string {
+	return name
+}
+
+// DefaultNamingStrategy is used when no naming strategy is given, replace it before
+// any table is created as descriptors are cached
+var DefaultNamingStrategy NamingStrategy = LegacySnakeCase
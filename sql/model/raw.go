@@ -0,0 +1,90 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// RawQuery is a hand written query with field templates, the result is scanned
+// into the result type of a table like Select.Run
+type RawQuery struct {
+	Query  string
+	From   Selectable
+	Logger logging.Logger
+
+	err error
+}
+
+var templatePattern = regexp.MustCompile(`\{\{.+?\}\}`)
+
+// Raw creates a raw query for a table or select, {{Field}} templates are replaced
+// with the quoted column names. Unknown fields set an error which is returned by
+// Err, Run and Exec. Raw queries are not limited by a tenant scope
+func Raw(from Selectable, query string) *RawQuery {
+	raw := &RawQuery{
+		From: from,
+	}
+
+	if table, ok := from.(*Table); ok {
+		raw.Logger = table.Logger
+	}
+
+	templateMap := from.TemplateMap()
+
+	raw.Query = templatePattern.ReplaceAllStringFunc(query, func(template string) string {
+		fieldName := strings.TrimSpace(strings.Trim(template, "{}"))
+
+		name, ok := templateMap[fieldName]
+		if !ok {
+			if raw.err == nil {
+				raw.err = fmt.Errorf("unknown field %v in query template", fieldName)
+			}
+
+			return template
+		}
+
+		return "`" + name + "`"
+	})
+
+	return raw
+}
+
+// Err returns the template resolution error
+func (raw *RawQuery) Err() error {
+	return raw.err
+}
+
+// Run executes the query and returns a slice of pointers to the result type
+func (raw *RawQuery) Run(queryer database.Queryer, args ...interface{}) (interface{}, error) {
+	if raw.err != nil {
+		return nil, raw.err
+	}
+
+	v := reflect.New(reflect.SliceOf(reflect.PtrTo(raw.From.ResultType())))
+
+	logQuery(raw.Logger, raw.Query, args)
+
+	err := queryer.Select(v.Interface(), raw.Query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// Exec executes the query without scanning a result, for raw updates and deletes
+func (raw *RawQuery) Exec(queryer database.Queryer, args ...interface{}) (sql.Result, error) {
+	if raw.err != nil {
+		return nil, raw.err
+	}
+
+	logQuery(raw.Logger, raw.Query, args)
+
+	return queryer.Exec(raw.Query, args...)
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// RenameToQuery returns the statement to rename the table
+func (table *Table) RenameToQuery(newName string) string {
+	return table.Dialect.RenameTableQuery(table.Name, newName)
+}
+
+// RenameTo renames the table in the database and updates the table name
+func (table *Table) RenameTo(newName string, queryer database.Queryer) error {
+	_, err := table.exec(queryer, table.RenameToQuery(newName))
+	if err != nil {
+		return err
+	}
+
+	table.Name = newName
+
+	return nil
+}
+
+// RenameColumnQuery returns the statement to rename the column of a struct field
+func (table *Table) RenameColumnQuery(fieldName string, newName string) (string, error) {
+	column, ok := table.Descriptor.ColumnMap[fieldName]
+	if !ok {
+		return "", fmt.Errorf("unknown field %v", fieldName)
+	}
+
+	return table.Dialect.RenameColumnQuery(table.Name, column.Name, newName), nil
+}
+
+// RenameColumn renames the column of a struct field in the database and updates
+// the column name. The db tag of the field must be changed to the new name as well
+func (table *Table) RenameColumn(fieldName string, newName string, queryer database.Queryer) error {
+	query, err := table.RenameColumnQuery(fieldName, newName)
+	if err != nil {
+		return err
+	}
+
+	_, err = table.exec(queryer, query)
+	if err != nil {
+		return err
+	}
+
+	table.Descriptor.ColumnMap[fieldName].Name = newName
+
+	return nil
+}
@@ -2,12 +2,17 @@ package model
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/almerlucke/go-utils/logging"
 	"github.com/almerlucke/go-utils/sql/database"
+	timeUtils "github.com/almerlucke/go-utils/time"
 )
 
 // Selectable can be used as From in Select setup
@@ -26,6 +31,30 @@ type Select struct {
 	GroupByExpression string
 	OrderByExpression string
 	LimitResults      *Limit
+	LockClause        string
+	Windows           []*Window
+
+	// ScopeConditions are added to the where clause with AND, their arguments are
+	// appended to the arguments given to Run
+	ScopeConditions []string
+	ScopeArgs       []interface{}
+
+	// Logger logs the query at debug level, if nil the default logger is used
+	Logger logging.Logger
+
+	// SlowQuery configures slow query logging, can be nil
+	SlowQuery *SlowQueryOptions
+
+	// QueryTimeout and MaxRowCount guard against runaway queries, see Timeout
+	// and MaxRows
+	QueryTimeout time.Duration
+	MaxRowCount  int
+
+	// ctx is the context of the query, see Context
+	ctx context.Context
+
+	// err is set by builder methods which fail, it is returned by Run
+	err error
 }
 
 // NewSelect creates a new select statement
@@ -65,6 +94,173 @@ func (sel *Select) Where(cond string) *Select {
 	return sel
 }
 
+// Columns sets the selected fields to the columns of the given struct field names.
+// Column names are quoted and aliased to the db tag of the field when it differs.
+// Unknown field names set an error which is returned by Err and Run
+func (sel *Select) Columns(fieldNames ...string) *Select {
+	desc := descriptorOf(sel.From)
+	if desc == nil {
+		sel.setErr(errors.New("columns can only be selected from a table"))
+		return sel
+	}
+
+	columns := []*ColumnDescriptor{}
+
+	for _, fieldName := range fieldNames {
+		column, ok := desc.ColumnMap[fieldName]
+		if !ok {
+			sel.setErr(fmt.Errorf("unknown field %v", fieldName))
+			return sel
+		}
+
+		columns = append(columns, column)
+	}
+
+	sel.Fields = columnList(sel.From.ResultType(), columns)
+
+	return sel
+}
+
+// Omit selects all columns except the columns of the given struct field names, for
+// example to skip large blob columns. Unknown field names set an error which is
+// returned by Err and Run
+func (sel *Select) Omit(fieldNames ...string) *Select {
+	desc := descriptorOf(sel.From)
+	if desc == nil {
+		sel.setErr(errors.New("columns can only be omitted from a table"))
+		return sel
+	}
+
+	omit := map[*ColumnDescriptor]bool{}
+
+	for _, fieldName := range fieldNames {
+		column, ok := desc.ColumnMap[fieldName]
+		if !ok {
+			sel.setErr(fmt.Errorf("unknown field %v", fieldName))
+			return sel
+		}
+
+		omit[column] = true
+	}
+
+	columns := []*ColumnDescriptor{}
+
+	for _, column := range desc.Columns {
+		if !omit[column] {
+			columns = append(columns, column)
+		}
+	}
+
+	sel.Fields = columnList(sel.From.ResultType(), columns)
+
+	return sel
+}
+
+// Err returns the first error of the builder methods
+func (sel *Select) Err() error {
+	return sel.err
+}
+
+// setErr keeps the first error
+func (sel *Select) setErr(err error) {
+	if sel.err == nil {
+		sel.err = err
+	}
+}
+
+// descriptorOf returns the table descriptor of a selectable, nested selects are
+// followed to their table. Returns nil if there is no table
+func descriptorOf(from Selectable) *TableDescriptor {
+	switch f := from.(type) {
+	case *Select:
+		return descriptorOf(f.From)
+	case interface{ TableDescriptor() *TableDescriptor }:
+		return f.TableDescriptor()
+	}
+
+	return nil
+}
+
+// columnList returns a quoted, comma separated column list, columns are aliased
+// to the db tag of their field if it differs from the column name
+func columnList(resultType reflect.Type, columns []*ColumnDescriptor) string {
+	names := make([]string, len(columns))
+
+	for i, column := range columns {
+		names[i] = "`" + column.Name + "`"
+
+		if field, ok := resultType.FieldByName(column.ActualName); ok {
+			if tag := field.Tag.Get("db"); tag != "" && tag != "-" && tag != column.Name {
+				names[i] += " AS `" + tag + "`"
+			}
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Scope adds a condition which is always combined with the where clause, the
+// condition args are passed after the args given to Run
+func (sel *Select) Scope(cond string, args ...interface{}) *Select {
+	sel.ScopeConditions = append(sel.ScopeConditions, replaceStructFieldsWithSQLFields(cond, sel.From.TemplateMap()))
+	sel.ScopeArgs = append(sel.ScopeArgs, args...)
+	return sel
+}
+
+// whereClause combines the where condition with the scope conditions
+func (sel *Select) whereClause() string {
+	if len(sel.ScopeConditions) == 0 {
+		return sel.WhereCondition
+	}
+
+	conditions := []string{}
+
+	if sel.WhereCondition != "" {
+		conditions = append(conditions, "("+sel.WhereCondition+")")
+	}
+
+	for _, cond := range sel.ScopeConditions {
+		conditions = append(conditions, "("+cond+")")
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// queryArgs returns args followed by the scope args. When selecting from a nested
+// select the scope args of the nested select are inserted after the args of its
+// placeholders, which are counted in its query
+func (sel *Select) queryArgs(args []interface{}) []interface{} {
+	if inner, ok := sel.From.(*Select); ok && inner.scopeArgCount() > 0 {
+		n := strings.Count(inner.Query(), "?") - inner.scopeArgCount()
+		if n > len(args) {
+			n = len(args)
+		}
+
+		args = append(inner.queryArgs(args[:n:n]), args[n:]...)
+	}
+
+	if len(sel.ScopeArgs) == 0 {
+		return args
+	}
+
+	combined := make([]interface{}, 0, len(args)+len(sel.ScopeArgs))
+	combined = append(combined, args...)
+
+	return append(combined, sel.ScopeArgs...)
+}
+
+// scopeArgCount returns the number of scope args of the select and its nested
+// selects
+func (sel *Select) scopeArgCount() int {
+	count := len(sel.ScopeArgs)
+
+	if inner, ok := sel.From.(*Select); ok {
+		count += inner.scopeArgCount()
+	}
+
+	return count
+}
+
 // GroupBy adds a group by clause to the select definition
 func (sel *Select) GroupBy(cond string) *Select {
 	sel.GroupByExpression = replaceStructFieldsWithSQLFields(cond, sel.From.TemplateMap())
@@ -86,6 +282,48 @@ func (sel *Select) Limit(offset int64, rowCount int64) *Select {
 	return sel
 }
 
+// Expr adds a field expression with an alias to the selected fields, {{Field}}
+// templates in the expression are resolved (e.g. Expr("RANK() OVER w", "rank"))
+func (sel *Select) Expr(expr string, alias string) *Select {
+	field := replaceStructFieldsWithSQLFields(expr, sel.From.TemplateMap())
+	if alias != "" {
+		field += " AS `" + alias + "`"
+	}
+
+	if sel.Fields == "" {
+		sel.Fields = field
+	} else {
+		sel.Fields += ", " + field
+	}
+
+	return sel
+}
+
+// Window adds a named window definition which can be used by window functions in
+// the fields (requires MySQL 8), {{Field}} templates in the definition are resolved,
+// e.g. Window("w", "PARTITION BY {{Country}} ORDER BY {{Score}} DESC")
+func (sel *Select) Window(name string, definition string) *Select {
+	sel.Windows = append(sel.Windows, &Window{
+		Name:       name,
+		Definition: replaceStructFieldsWithSQLFields(definition, sel.From.TemplateMap()),
+	})
+	return sel
+}
+
+// ForUpdate locks the selected rows for update until the end of the transaction,
+// only useful when the select is run with a transaction queryer
+func (sel *Select) ForUpdate() *Select {
+	sel.LockClause = "FOR UPDATE"
+	return sel
+}
+
+// LockInShareMode takes a shared lock on the selected rows until the end of the
+// transaction, other transactions can read but not modify the rows
+func (sel *Select) LockInShareMode() *Select {
+	sel.LockClause = "LOCK IN SHARE MODE"
+	return sel
+}
+
 // FromStatement for Selectable
 func (sel *Select) FromStatement() string {
 	return "(" + sel.Query() + ")"
@@ -102,11 +340,16 @@ func (sel *Select) ResultType() reflect.Type {
 	return sel.From.ResultType()
 }
 
-// Select for nested Select
+// Select for nested Select, the args given to Run are for the placeholders of the
+// nested select followed by those of the outer select. Scope args of the nested select
+// are kept, its query must not contain literal question marks in that case
 func (sel *Select) Select(fields string) *Select {
 	return &Select{
-		From:   sel,
-		Fields: replaceStructFieldsWithSQLFields(fields, sel.TemplateMap()),
+		From:      sel,
+		Fields:    replaceStructFieldsWithSQLFields(fields, sel.TemplateMap()),
+		Logger:    sel.Logger,
+		SlowQuery: sel.SlowQuery,
+		err:       sel.err,
 	}
 }
 
@@ -120,20 +363,35 @@ func (sel *Select) Query() string {
 		buffer.WriteString(fmt.Sprintf(" AS %v", sel.Alias))
 	}
 
-	if sel.WhereCondition != "" {
-		buffer.WriteString(fmt.Sprintf(" WHERE %v", sel.WhereCondition))
+	if where := sel.whereClause(); where != "" {
+		buffer.WriteString(fmt.Sprintf(" WHERE %v", where))
 	}
 
 	if sel.GroupByExpression != "" {
 		buffer.WriteString(fmt.Sprintf(" GROUP BY %v", sel.GroupByExpression))
 	}
 
+	if len(sel.Windows) > 0 {
+		windows := make([]string, len(sel.Windows))
+		for i, window := range sel.Windows {
+			windows[i] = fmt.Sprintf("`%v` AS (%v)", window.Name, window.Definition)
+		}
+
+		buffer.WriteString(" WINDOW " + strings.Join(windows, ", "))
+	}
+
 	if sel.OrderByExpression != "" {
 		buffer.WriteString(fmt.Sprintf(" ORDER BY %v", sel.OrderByExpression))
 	}
 
 	if sel.LimitResults != nil {
 		buffer.WriteString(fmt.Sprintf(" LIMIT %v, %v", sel.LimitResults.Offset, sel.LimitResults.RowCount))
+	} else if sel.MaxRowCount > 0 {
+		buffer.WriteString(fmt.Sprintf(" LIMIT %v", sel.MaxRowCount+1))
+	}
+
+	if sel.LockClause != "" {
+		buffer.WriteString(" " + sel.LockClause)
 	}
 
 	return buffer.String()
@@ -144,7 +402,7 @@ func (sel *Select) Run(queryer database.Queryer, args ...interface{}) (interface
 	resultType := sel.From.ResultType()
 	v := reflect.New(reflect.SliceOf(reflect.PtrTo(resultType)))
 
-	err := queryer.Select(v.Interface(), sel.Query(), args...)
+	err := sel.RunInto(v.Interface(), queryer, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -152,6 +410,54 @@ func (sel *Select) Run(queryer database.Queryer, args ...interface{}) (interface
 	return v.Elem().Interface(), nil
 }
 
+// RunInto runs the select query and scans the rows into dest, a pointer to a slice
+// of structs or struct pointers. Columns are matched to the db tags of the struct,
+// so dest can be a projection struct for joins and aggregates
+func (sel *Select) RunInto(dest interface{}, queryer database.Queryer, args ...interface{}) error {
+	if sel.err != nil {
+		return sel.err
+	}
+
+	query := sel.Query()
+	args = sel.queryArgs(args)
+	logQuery(sel.Logger, query, args)
+
+	stopwatch := timeUtils.NewStopwatch()
+
+	var err error
+	if sel.guarded() {
+		err = sel.selectGuarded(dest, queryer, query, args)
+	} else {
+		err = queryer.Select(dest, query, args...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	sel.logSlowSelect(queryer, query, args, stopwatch.Elapsed())
+
+	return nil
+}
+
+// RunAs runs the select query and scans the rows into a slice of T, see RunInto
+func RunAs[T any](sel *Select, queryer database.Queryer, args ...interface{}) ([]*T, error) {
+	results := []*T{}
+
+	err := sel.RunInto(&results, queryer, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Window is a named window definition used by window functions
+type Window struct {
+	Name       string
+	Definition string
+}
+
 // Limit offset and row count
 type Limit struct {
 	Offset   int64
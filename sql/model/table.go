@@ -5,8 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/reflection/structural"
 	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/sanitize"
 )
 
 // Tabler interface for structs that represent a MySQL table
@@ -24,6 +29,19 @@ type Tabler interface {
 	Delete(interface{}, database.Queryer) (sql.Result, error)
 }
 
+// emptyResult is returned when no query needed to be executed
+type emptyResult struct{}
+
+// LastInsertId for sql.Result
+func (emptyResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+// RowsAffected for sql.Result
+func (emptyResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
 // Table is a definition of a SQL table and conforms to tabler interface
 type Table struct {
 	Engine             string
@@ -31,20 +49,47 @@ type Table struct {
 	Name               string
 	KeysAndConstraints []string
 	Descriptor         *TableDescriptor
+
+	// Dialect of the database, determines if timestamp columns are written
+	// explicitly (see Dialect.OnUpdateTimestamp)
+	Dialect Dialect
+
+	// Logger logs executed queries at debug level, if nil the default logger is used
+	Logger logging.Logger
+
+	// SlowQuery configures slow query logging, can be nil
+	SlowQuery *SlowQueryOptions
 }
 
-// NewTable creates a new table definition from a struct template
+// NewTable creates a new table definition from a struct template, if name is empty
+// the table name is derived from the struct name
 func NewTable(name string, template interface{}) (*Table, error) {
+	return NewTableWithNaming(name, template, nil)
+}
+
+// NewTableWithNaming creates a new table definition from a struct template, column
+// names without db tag and an empty table name are derived with naming. If naming
+// is nil DefaultNamingStrategy is used
+func NewTableWithNaming(name string, template interface{}, naming NamingStrategy) (*Table, error) {
+	desc, err := StructToTableDescriptorWithNaming(template, naming)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		if naming == nil {
+			naming = DefaultNamingStrategy
+		}
+
+		name = naming(desc.RawDescriptor.Type().Name())
+	}
+
 	table := &Table{
 		Engine:             "InnoDB",
 		CharSet:            "utf8mb4",
 		Name:               name,
 		KeysAndConstraints: []string{},
-	}
-
-	desc, err := StructToTableDescriptor(template)
-	if err != nil {
-		return nil, err
+		Dialect:            DialectMySQL,
 	}
 
 	table.Descriptor = desc
@@ -87,10 +132,25 @@ func (table *Table) ResolveQueryTemplates(query string) string {
 	return replaceStructFieldsWithSQLFields(query, table.TemplateMap())
 }
 
-// Insert objects into the table
+// Insert objects into the table, BeforeInsert and AfterInsert hooks implemented by
+// the objects are called
 func (table *Table) Insert(objs []interface{}, queryer database.Queryer) (sql.Result, error) {
 	desc := table.Descriptor
 
+	now := timestamp()
+
+	for _, obj := range objs {
+		err := beforeInsert(obj, queryer)
+		if err != nil {
+			return nil, err
+		}
+
+		err = table.setTimestamps(obj, now, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var buffer bytes.Buffer
 	values := []interface{}{}
 
@@ -100,7 +160,7 @@ func (table *Table) Insert(objs []interface{}, queryer database.Queryer) (sql.Re
 	numValues := 0
 
 	for _, column := range desc.Columns {
-		if column.HasDefault {
+		if column.HasDefault && !table.writeTimestamp(column) {
 			continue
 		} else {
 			if addComma {
@@ -137,7 +197,7 @@ func (table *Table) Insert(objs []interface{}, queryer database.Queryer) (sql.Re
 		buffer.WriteRune('(')
 
 		for _, column := range desc.Columns {
-			if column.HasDefault {
+			if column.HasDefault && !table.writeTimestamp(column) {
 				continue
 			} else {
 				if innerAddComma {
@@ -148,26 +208,238 @@ func (table *Table) Insert(objs []interface{}, queryer database.Queryer) (sql.Re
 
 				buffer.WriteRune('?')
 
-				values = append(values, v.FieldByName(column.ActualName).Interface())
+				values = append(values, columnValue(v, column))
 			}
 		}
 
 		buffer.WriteRune(')')
 	}
 
-	return queryer.Exec(buffer.String(), values...)
+	result, err := table.exec(queryer, buffer.String(), values...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range objs {
+		err = afterInsert(obj, queryer)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
 }
 
 // Select creates a select statement with From set to the table
 func (table *Table) Select(fields string) *Select {
 	return &Select{
-		Fields: replaceStructFieldsWithSQLFields(fields, table.TemplateMap()),
-		From:   table,
+		Fields:    replaceStructFieldsWithSQLFields(fields, table.TemplateMap()),
+		From:      table,
+		Logger:    table.Logger,
+		SlowQuery: table.SlowQuery,
 	}
 }
 
-// Update object, use primary key for where clause
+// Get selects the row with the given primary key, returns a pointer to a struct of
+// the table type or sql.ErrNoRows if the row does not exist
+func (table *Table) Get(key interface{}, queryer database.Queryer) (interface{}, error) {
+	query := fmt.Sprintf("SELECT * FROM `%v` WHERE `%v`=?", table.Name, table.Descriptor.PrimaryColumn.Name)
+	logQuery(table.Logger, query, []interface{}{key})
+
+	obj := reflect.New(table.ResultType())
+
+	err := queryer.Get(obj.Interface(), query, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.Interface(), nil
+}
+
+// GetMany selects the rows with the given primary keys, returns a slice of pointers to
+// structs of the table type ([]*T as interface{}). Missing rows are left out, the order
+// of the rows is not related to the order of keys
+func (table *Table) GetMany(keys []interface{}, queryer database.Queryer) (interface{}, error) {
+	results := reflect.New(reflect.SliceOf(reflect.PtrTo(table.ResultType())))
+
+	if len(keys) == 0 {
+		return results.Elem().Interface(), nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	query := fmt.Sprintf("SELECT * FROM `%v` WHERE `%v` IN (%v)", table.Name, table.Descriptor.PrimaryColumn.Name, placeholders)
+	logQuery(table.Logger, query, keys)
+
+	err := queryer.Select(results.Interface(), query, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results.Elem().Interface(), nil
+}
+
+// Update object, use primary key for where clause. BeforeUpdate and AfterUpdate hooks
+// implemented by the object are called
 func (table *Table) Update(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	return table.updateColumns(obj, table.Descriptor.Columns, nil, queryer)
+}
+
+// UpdateChanges updates only the columns of which the field value differs between
+// old and new, new is used for the values and primary key. If nothing changed
+// no query is executed and a result with zero affected rows is returned
+func (table *Table) UpdateChanges(old interface{}, new interface{}, queryer database.Queryer) (sql.Result, error) {
+	columns, err := table.changedColumns(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return emptyResult{}, nil
+	}
+
+	return table.updateColumns(new, columns, nil, queryer)
+}
+
+// changedColumns returns the updatable columns of which the field value differs
+// between old and new
+func (table *Table) changedColumns(old interface{}, new interface{}) ([]*ColumnDescriptor, error) {
+	changes, err := structural.Diff(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []*ColumnDescriptor{}
+	added := map[*ColumnDescriptor]bool{}
+
+	for _, change := range changes {
+		// Changes of nested struct fields (e.g. Settings.Locale) belong to the
+		// column of the outer field
+		field, _, _ := strings.Cut(change.Path, ".")
+
+		column, ok := table.Descriptor.ColumnMap[field]
+		if !ok || added[column] || column == table.Descriptor.PrimaryColumn || column.NoUpdate {
+			continue
+		}
+
+		added[column] = true
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// columnValue returns the value of the column field of struct value v, nil
+// pointers of nullable columns are written as NULL
+func columnValue(v reflect.Value, column *ColumnDescriptor) interface{} {
+	f := v.FieldByName(column.ActualName)
+
+	if column.Nullable && f.Kind() == reflect.Ptr && f.IsNil() {
+		return nil
+	}
+
+	return f.Interface()
+}
+
+// timestamp returns the current time with the precision of a datetime column
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// writeTimestamp checks if a created or modified column is written explicitly
+// because the dialect does not maintain it
+func (table *Table) writeTimestamp(column *ColumnDescriptor) bool {
+	return (column.IsCreated || column.IsModified) && !table.Dialect.OnUpdateTimestamp()
+}
+
+// setTimestamps sets the modified fields of obj to now, when inserting zero
+// created fields are set as well. Objects which are not pointers are skipped
+func (table *Table) setTimestamps(obj interface{}, now time.Time, insert bool) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+
+	v = v.Elem()
+
+	for _, column := range table.Descriptor.Columns {
+		if !column.IsModified && !(insert && column.IsCreated) {
+			continue
+		}
+
+		field := v.FieldByName(column.ActualName)
+		if !field.CanSet() || (column.IsCreated && !column.IsModified && !field.IsZero()) {
+			continue
+		}
+
+		err := structural.SetValue(field, now)
+		if err != nil {
+			return fmt.Errorf("can't set timestamp field %v: %v", column.ActualName, err)
+		}
+	}
+
+	return nil
+}
+
+// withModifiedColumns adds the modified columns to columns if the dialect does not
+// maintain them
+func (table *Table) withModifiedColumns(columns []*ColumnDescriptor) []*ColumnDescriptor {
+	if table.Dialect.OnUpdateTimestamp() {
+		return columns
+	}
+
+	result := columns
+
+	for _, column := range table.Descriptor.Columns {
+		if !column.IsModified {
+			continue
+		}
+
+		found := false
+
+		for _, c := range columns {
+			if c == column {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result[:len(result):len(result)], column)
+		}
+	}
+
+	return result
+}
+
+// condition is an extra where condition with its arguments
+type condition struct {
+	query string
+	args  []interface{}
+}
+
+// writeConditions appends conditions to a where clause
+func writeConditions(buffer *bytes.Buffer, values []interface{}, conditions []condition) []interface{} {
+	for _, cond := range conditions {
+		buffer.WriteString(" AND " + cond.query)
+		values = append(values, cond.args...)
+	}
+
+	return values
+}
+
+// updateColumns updates the given columns of an object, use primary key and the extra
+// conditions for where clause
+func (table *Table) updateColumns(obj interface{}, columns []*ColumnDescriptor, conditions []condition, queryer database.Queryer) (sql.Result, error) {
+	err := beforeUpdate(obj, queryer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = table.setTimestamps(obj, timestamp(), false)
+	if err != nil {
+		return nil, err
+	}
+
 	var buffer bytes.Buffer
 
 	buffer.WriteString(fmt.Sprintf("UPDATE %v SET ", table.Name))
@@ -183,8 +455,8 @@ func (table *Table) Update(obj interface{}, queryer database.Queryer) (sql.Resul
 	addComma := false
 
 	// Add column names to update query
-	for _, column := range desc.Columns {
-		if column == desc.PrimaryColumn || column.NoUpdate {
+	for _, column := range table.withModifiedColumns(columns) {
+		if column == desc.PrimaryColumn || (column.NoUpdate && !(column.IsModified && table.writeTimestamp(column))) {
 			continue
 		}
 
@@ -197,20 +469,35 @@ func (table *Table) Update(obj interface{}, queryer database.Queryer) (sql.Resul
 		buffer.WriteString(fmt.Sprintf("`%v`=?", column.Name))
 
 		// Get field value
-		f := v.FieldByName(column.ActualName)
-		values = append(values, f.Interface())
+		values = append(values, columnValue(v, column))
 	}
 
 	buffer.WriteString(fmt.Sprintf(" WHERE `%v`=?", desc.PrimaryColumn.Name))
 
 	f := v.FieldByName(desc.PrimaryColumn.ActualName)
 	values = append(values, f.Interface())
+	values = writeConditions(&buffer, values, conditions)
+
+	result, err := table.exec(queryer, buffer.String(), values...)
+	if err != nil {
+		return nil, err
+	}
 
-	return queryer.Exec(buffer.String(), values...)
+	return result, afterUpdate(obj, queryer)
 }
 
-// Delete object
+// Delete object, BeforeDelete and AfterDelete hooks implemented by the object are called
 func (table *Table) Delete(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	return table.delete(obj, nil, queryer)
+}
+
+// delete object, use primary key and the extra conditions for where clause
+func (table *Table) delete(obj interface{}, conditions []condition, queryer database.Queryer) (sql.Result, error) {
+	err := beforeDelete(obj, queryer)
+	if err != nil {
+		return nil, err
+	}
+
 	var buffer bytes.Buffer
 
 	buffer.WriteString(fmt.Sprintf("DELETE FROM %v ", table.Name))
@@ -227,8 +514,49 @@ func (table *Table) Delete(obj interface{}, queryer database.Queryer) (sql.Resul
 
 	f := v.FieldByName(desc.PrimaryColumn.ActualName)
 	values = append(values, f.Interface())
+	values = writeConditions(&buffer, values, conditions)
+
+	result, err := table.exec(queryer, buffer.String(), values...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, afterDelete(obj, queryer)
+}
+
+// exec executes and logs a query
+func (table *Table) exec(queryer database.Queryer, query string, values ...interface{}) (sql.Result, error) {
+	logQuery(table.Logger, query, values)
+
+	start := time.Now()
+	result, err := queryer.Exec(query, values...)
+
+	if options := table.SlowQuery; options != nil && options.Threshold > 0 {
+		if duration := time.Since(start); duration >= options.Threshold {
+			logging.OrDefault(table.Logger).Warn("slow query", "sql", loggedQuery(query), "duration", duration)
+		}
+	}
+
+	return result, err
+}
+
+// QuerySanitizer is applied to queries before they are logged to remove literal
+// values, set it to nil to log queries unchanged
+var QuerySanitizer = sanitize.Query
 
-	return queryer.Exec(buffer.String(), values...)
+// loggedQuery returns the query as it should be logged
+func loggedQuery(query string) string {
+	if QuerySanitizer == nil {
+		return query
+	}
+
+	return QuerySanitizer(query)
+}
+
+// logQuery logs a query at debug level, argument values are not logged as they
+// can contain sensitive data
+func logQuery(logger logging.Logger, query string, args []interface{}) {
+	logging.OrDefault(logger).Debug("query", "sql", loggedQuery(query), "args", len(args))
 }
 
 // ResultType returns the reflect Type for the raw table structure
@@ -270,6 +598,8 @@ func TablerToQuery(tabler Tabler) string {
 		entries = append(entries, fmt.Sprintf("PRIMARY KEY (`%v`)", desc.PrimaryColumn.Name))
 	}
 
+	entries = append(entries, fullTextKeys(desc)...)
+
 	for _, key := range tabler.TableKeysAndConstraints() {
 		entries = append(entries, key)
 	}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/almerlucke/go-utils/reflection/structural"
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// ScopedTable is a table limited to the rows of one tenant. Selects, updates and
+// deletes get the tenant condition, inserts get the tenant id assigned. It
+// conforms to the Tabler interface
+type ScopedTable struct {
+	*Table
+	TenantID interface{}
+}
+
+// TenantScope returns the table scoped to a tenant, the tenant column is declared
+// with the "tenant" keyword in the sql tag. Panics if the table has no tenant column
+func (table *Table) TenantScope(tenantID interface{}) *ScopedTable {
+	if table.Descriptor.TenantColumn == nil {
+		panic(fmt.Sprintf("model: table %v has no tenant column", table.Name))
+	}
+
+	return &ScopedTable{
+		Table:    table,
+		TenantID: tenantID,
+	}
+}
+
+// tenantCondition returns the tenant where condition
+func (scoped *ScopedTable) tenantCondition() condition {
+	return condition{
+		query: fmt.Sprintf("`%v`=?", scoped.Descriptor.TenantColumn.Name),
+		args:  []interface{}{scoped.TenantID},
+	}
+}
+
+// Insert objects with the tenant id assigned to the tenant field, objects must
+// be pointers
+func (scoped *ScopedTable) Insert(objs []interface{}, queryer database.Queryer) (sql.Result, error) {
+	column := scoped.Descriptor.TenantColumn
+
+	for _, obj := range objs {
+		v := reflect.ValueOf(obj)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil, errors.New("scoped insert requires non nil object pointers")
+		}
+
+		err := structural.SetValue(v.Elem().FieldByName(column.ActualName), scoped.TenantID)
+		if err != nil {
+			return nil, fmt.Errorf("can't set tenant field %v: %v", column.ActualName, err)
+		}
+	}
+
+	return scoped.Table.Insert(objs, queryer)
+}
+
+// Select creates a select statement limited to the tenant
+func (scoped *ScopedTable) Select(fields string) *Select {
+	cond := scoped.tenantCondition()
+
+	return scoped.Table.Select(fields).Scope(cond.query, cond.args...)
+}
+
+// Get selects the row with the given primary key if it belongs to the tenant, returns
+// sql.ErrNoRows otherwise, see Table.Get
+func (scoped *ScopedTable) Get(key interface{}, queryer database.Queryer) (interface{}, error) {
+	cond := scoped.tenantCondition()
+	query := fmt.Sprintf("SELECT * FROM `%v` WHERE `%v`=? AND %v", scoped.Name, scoped.Descriptor.PrimaryColumn.Name, cond.query)
+	args := append([]interface{}{key}, cond.args...)
+	logQuery(scoped.Logger, query, args)
+
+	obj := reflect.New(scoped.ResultType())
+
+	err := queryer.Get(obj.Interface(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.Interface(), nil
+}
+
+// GetMany selects the rows of the tenant with the given primary keys, see
+// Table.GetMany
+func (scoped *ScopedTable) GetMany(keys []interface{}, queryer database.Queryer) (interface{}, error) {
+	results := reflect.New(reflect.SliceOf(reflect.PtrTo(scoped.ResultType())))
+
+	if len(keys) == 0 {
+		return results.Elem().Interface(), nil
+	}
+
+	cond := scoped.tenantCondition()
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	query := fmt.Sprintf("SELECT * FROM `%v` WHERE `%v` IN (%v) AND %v", scoped.Name, scoped.Descriptor.PrimaryColumn.Name, placeholders, cond.query)
+	args := append(append([]interface{}{}, keys...), cond.args...)
+	logQuery(scoped.Logger, query, args)
+
+	err := queryer.Select(results.Interface(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results.Elem().Interface(), nil
+}
+
+// Update object if it belongs to the tenant, the tenant column is never updated
+func (scoped *ScopedTable) Update(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	return scoped.updateColumns(obj, scoped.withoutTenant(scoped.Descriptor.Columns), []condition{scoped.tenantCondition()}, queryer)
+}
+
+// UpdateChanges updates the changed columns of an object if it belongs to the tenant,
+// see Table.UpdateChanges
+func (scoped *ScopedTable) UpdateChanges(old interface{}, new interface{}, queryer database.Queryer) (sql.Result, error) {
+	columns, err := scoped.changedColumns(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	columns = scoped.withoutTenant(columns)
+	if len(columns) == 0 {
+		return emptyResult{}, nil
+	}
+
+	return scoped.updateColumns(new, columns, []condition{scoped.tenantCondition()}, queryer)
+}
+
+// withoutTenant filters the tenant column from columns
+func (scoped *ScopedTable) withoutTenant(columns []*ColumnDescriptor) []*ColumnDescriptor {
+	filtered := make([]*ColumnDescriptor, 0, len(columns))
+
+	for _, column := range columns {
+		if column != scoped.Descriptor.TenantColumn {
+			filtered = append(filtered, column)
+		}
+	}
+
+	return filtered
+}
+
+// Delete object if it belongs to the tenant
+func (scoped *ScopedTable) Delete(obj interface{}, queryer database.Queryer) (sql.Result, error) {
+	return scoped.delete(obj, []condition{scoped.tenantCondition()}, queryer)
+}
@@ -0,0 +1,184 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/almerlucke/go-utils/logging"
+	"github.com/almerlucke/go-utils/sql/database"
+)
+
+// View is a database view defined by a select statement, it conforms to Selectable
+// so it can be queried like a table. The result type is the result type of the
+// statement, use RunInto or RunAs to scan into projection structs
+type View struct {
+	Name      string
+	Statement *Select
+
+	// Logger logs executed queries at debug level, if nil the default logger is used
+	Logger logging.Logger
+
+	// SlowQuery configures slow query logging, can be nil
+	SlowQuery *SlowQueryOptions
+}
+
+// NewView creates a new view definition, the statement can not contain query
+// arguments as views are created with DDL
+func NewView(name string, selectStatement *Select) *View {
+	return &View{
+		Name:      name,
+		Statement: selectStatement,
+		Logger:    selectStatement.Logger,
+		SlowQuery: selectStatement.SlowQuery,
+	}
+}
+
+// FromStatement for Selectable
+func (view *View) FromStatement() string {
+	return "`" + view.Name + "`"
+}
+
+// TemplateMap for Selectable
+func (view *View) TemplateMap() map[string]string {
+	return view.Statement.TemplateMap()
+}
+
+// ResultType for Selectable
+func (view *View) ResultType() reflect.Type {
+	return view.Statement.ResultType()
+}
+
+// TableDescriptor returns the descriptor of the table the statement selects from,
+// can be nil
+func (view *View) TableDescriptor() *TableDescriptor {
+	return descriptorOf(view.Statement)
+}
+
+// Select creates a select statement with From set to the view
+func (view *View) Select(fields string) *Select {
+	return &Select{
+		Fields:    replaceStructFieldsWithSQLFields(fields, view.TemplateMap()),
+		From:      view,
+		Logger:    view.Logger,
+		SlowQuery: view.SlowQuery,
+	}
+}
+
+// CreateQuery returns a query string to CREATE OR REPLACE the view
+func (view *View) CreateQuery() string {
+	return fmt.Sprintf("CREATE OR REPLACE VIEW `%v` AS %v", view.Name, view.Statement.Query())
+}
+
+// DropQuery returns a query string to DROP the view
+func (view *View) DropQuery() string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS `%v`", view.Name)
+}
+
+// Create creates or replaces the view in the database
+func (view *View) Create(queryer database.Queryer) error {
+	if err := view.Statement.Err(); err != nil {
+		return err
+	}
+
+	query := view.CreateQuery()
+	logQuery(view.Logger, query, nil)
+
+	_, err := queryer.Exec(query)
+
+	return err
+}
+
+// Drop drops the view from the database
+func (view *View) Drop(queryer database.Queryer) error {
+	query := view.DropQuery()
+	logQuery(view.Logger, query, nil)
+
+	_, err := queryer.Exec(query)
+
+	return err
+}
+
+/*
+	Summary tables
+*/
+
+// SummaryTable is a table which holds the materialized result of a select statement,
+// it is refreshed periodically (e.g. by a scheduled job) instead of being queried live
+// like a view. The statement must select a value for each table column in order,
+// columns with a default are skipped like with Insert
+type SummaryTable struct {
+	Table     *Table
+	Statement *Select
+}
+
+// NewSummaryTable creates a new summary table definition
+func NewSummaryTable(table *Table, selectStatement *Select) *SummaryTable {
+	return &SummaryTable{
+		Table:     table,
+		Statement: selectStatement,
+	}
+}
+
+// RefreshQueries returns the queries that replace the table content with the result
+// of the statement
+func (summary *SummaryTable) RefreshQueries() []string {
+	columns := []string{}
+
+	for _, column := range summary.Table.Descriptor.Columns {
+		if column.HasDefault {
+			continue
+		}
+
+		columns = append(columns, "`"+column.Name+"`")
+	}
+
+	return []string{
+		fmt.Sprintf("DELETE FROM `%v`", summary.Table.Name),
+		fmt.Sprintf("INSERT INTO `%v` (%v) %v", summary.Table.Name, strings.Join(columns, ","), summary.Statement.Query()),
+	}
+}
+
+// Refresh replaces the table content with the result of the statement, args are
+// passed to the statement. Run it with a transaction queryer so readers never see
+// an empty table
+func (summary *SummaryTable) Refresh(queryer database.Queryer, args ...interface{}) error {
+	if err := summary.Statement.Err(); err != nil {
+		return err
+	}
+
+	queries := summary.RefreshQueries()
+
+	_, err := summary.Table.exec(queryer, queries[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = summary.Table.exec(queryer, queries[1], summary.Statement.queryArgs(args)...)
+
+	return err
+}
+
+// RefreshTransactional refreshes the table inside a transaction
+func (summary *SummaryTable) RefreshTransactional(db *database.DB, args ...interface{}) error {
+	return db.Transactional(func(queryer database.Queryer) (bool, error) {
+		err := summary.Refresh(queryer, args...)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+}
+
+// RefreshJob returns a function which can be added to a schedule.Scheduler, it
+// refreshes the table inside a transaction and logs errors
+func (summary *SummaryTable) RefreshJob(db *database.DB, args ...interface{}) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		err := summary.RefreshTransactional(db, args...)
+		if err != nil {
+			logging.OrDefault(summary.Table.Logger).Error("summary table refresh failed", "table", summary.Table.Name, "error", err)
+		}
+	}
+}
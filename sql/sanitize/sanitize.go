@@ -0,0 +1,127 @@
+// Package sanitize removes literal values from SQL queries before they are logged or
+// traced, so personal data in where clauses does not end up in log storage
+package sanitize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Sanitizer redacts literal values and shortens queries
+type Sanitizer struct {
+	// MaxInList is the number of placeholders kept in IN lists, longer lists are
+	// truncated. 0 keeps all placeholders
+	MaxInList int
+
+	// MaxLength truncates the sanitized query, 0 means no limit
+	MaxLength int
+}
+
+// Default sanitizer used by Query
+var Default = &Sanitizer{
+	MaxInList: 3,
+	MaxLength: 2048,
+}
+
+// Query sanitizes a query with the default sanitizer
+func Query(query string) string {
+	return Default.Sanitize(query)
+}
+
+var inListPattern = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+
+// Sanitize replaces string, number and hex literals with ?, truncates IN lists and
+// the query length. Quoted identifiers and placeholders are kept
+func (sanitizer *Sanitizer) Sanitize(query string) string {
+	sanitized := redactLiterals(query)
+
+	if sanitizer.MaxInList > 0 {
+		sanitized = inListPattern.ReplaceAllStringFunc(sanitized, func(list string) string {
+			n := strings.Count(list, "?")
+			if n <= sanitizer.MaxInList {
+				return list
+			}
+
+			return fmt.Sprintf("IN (%v, ... %v more)", strings.Repeat("?, ", sanitizer.MaxInList-1)+"?", n-sanitizer.MaxInList)
+		})
+	}
+
+	if sanitizer.MaxLength > 0 && len(sanitized) > sanitizer.MaxLength {
+		sanitized = sanitized[:sanitizer.MaxLength] + "..."
+	}
+
+	return sanitized
+}
+
+// redactLiterals replaces literals outside of quoted identifiers with ?
+func redactLiterals(query string) string {
+	var builder strings.Builder
+
+	runes := []rune(query)
+	n := len(runes)
+
+	for i := 0; i < n; i++ {
+		c := runes[i]
+
+		switch {
+		case c == '`':
+			// Quoted identifier, copy as is
+			end := i + 1
+			for end < n && runes[end] != '`' {
+				end++
+			}
+
+			if end < n {
+				end++
+			}
+
+			builder.WriteString(string(runes[i:end]))
+			i = end - 1
+		case c == '\'' || c == '"':
+			// String literal, quotes are escaped by doubling or a backslash
+			end := i + 1
+			for end < n {
+				if runes[end] == '\\' {
+					end += 2
+					continue
+				}
+
+				if runes[end] == c {
+					if end+1 < n && runes[end+1] == c {
+						end += 2
+						continue
+					}
+
+					break
+				}
+
+				end++
+			}
+
+			builder.WriteRune('?')
+			i = end
+		case isDigit(c) && (i == 0 || !isIdentifier(runes[i-1])):
+			// Number or hex literal
+			end := i + 1
+			for end < n && (isIdentifier(runes[end]) || runes[end] == '.') {
+				end++
+			}
+
+			builder.WriteRune('?')
+			i = end - 1
+		default:
+			builder.WriteRune(c)
+		}
+	}
+
+	return builder.String()
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c rune) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c > 127
+}
@@ -0,0 +1,250 @@
+// Package testing is an integration test harness for code written against
+// database.Queryer. It connects to the MySQL database given by environment
+// variables or starts a throwaway MySQL docker container, creates tables, runs
+// migrations and hands out per test transactions which are rolled back. Import it
+// with an alias, e.g. sqlTesting, to avoid a clash with the standard library
+package testing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/almerlucke/go-utils/sql/database"
+	"github.com/almerlucke/go-utils/sql/migration"
+	"github.com/almerlucke/go-utils/sql/model"
+	"github.com/almerlucke/go-utils/sql/utils"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvHost     = "TEST_MYSQL_HOST"
+	EnvPort     = "TEST_MYSQL_PORT"
+	EnvUser     = "TEST_MYSQL_USER"
+	EnvPassword = "TEST_MYSQL_PASSWORD"
+	EnvDatabase = "TEST_MYSQL_DATABASE"
+)
+
+// TB is the part of testing.TB used by the harness
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// Options for Start
+type Options struct {
+	// Image is the docker image used when no database is configured in the environment
+	Image string
+
+	// StartTimeout is the maximum time to wait for the database to accept connections
+	StartTimeout time.Duration
+
+	// Tables are created after connecting
+	Tables []model.Tabler
+
+	// Version and Versions are passed to migration.Migrate
+	Version  string
+	Versions []*migration.Version
+}
+
+// Harness holds the test database
+type Harness struct {
+	DB *database.DB
+
+	container string
+}
+
+// ConfigFromEnv returns a configuration from the TEST_MYSQL_* environment variables,
+// false if TEST_MYSQL_HOST is not set
+func ConfigFromEnv() (*database.Configuration, bool) {
+	host := os.Getenv(EnvHost)
+	if host == "" {
+		return nil, false
+	}
+
+	config := database.NewConfiguration(host, envOr(EnvUser, "root"), os.Getenv(EnvPassword), envOr(EnvDatabase, "test"))
+
+	if port, err := strconv.Atoi(os.Getenv(EnvPort)); err == nil {
+		config.Port = port
+	}
+
+	return config, true
+}
+
+func envOr(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return def
+}
+
+// Start connects to the configured database or starts a docker container, creates
+// the tables and runs the migrations. Call it from TestMain and Close the harness
+// when all tests are done
+func Start(options *Options) (*Harness, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	if options.Image == "" {
+		options.Image = "mysql:8"
+	}
+
+	if options.StartTimeout == 0 {
+		options.StartTimeout = 2 * time.Minute
+	}
+
+	if options.Version == "" {
+		options.Version = "0"
+	}
+
+	harness := &Harness{}
+
+	config, ok := ConfigFromEnv()
+	if !ok {
+		var err error
+
+		config, err = harness.startContainer(options.Image)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := connect(config, options.StartTimeout)
+	if err != nil {
+		harness.Close()
+		return nil, err
+	}
+
+	harness.DB = db
+
+	// Table and migration errors are deterministic so they are not retried
+	err = utils.PrepareDatabase(db, options.Version, options.Versions, options.Tables...)
+	if err != nil {
+		harness.Close()
+		return nil, err
+	}
+
+	return harness, nil
+}
+
+// connect retries connecting until the database accepts connections or timeout passes
+func connect(config *database.Configuration, timeout time.Duration) (*database.DB, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		db, err := database.New(config)
+		if err == nil {
+			return db, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("database not available: %v", err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// startContainer starts a MySQL container on a random local port
+func (harness *Harness) startContainer(image string) (*database.Configuration, error) {
+	password := "test"
+	databaseName := "test"
+
+	id, err := docker("run", "-d", "--rm",
+		"-e", "MYSQL_ROOT_PASSWORD="+password,
+		"-e", "MYSQL_DATABASE="+databaseName,
+		"-p", "127.0.0.1::3306",
+		image,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	harness.container = id
+
+	mapping, err := docker("port", id, "3306/tcp")
+	if err != nil {
+		harness.Close()
+		return nil, err
+	}
+
+	// Output is host:port, possibly one line per address family
+	line := strings.Split(mapping, "\n")[0]
+
+	port, err := strconv.Atoi(line[strings.LastIndex(line, ":")+1:])
+	if err != nil {
+		harness.Close()
+		return nil, fmt.Errorf("can't parse container port %q", mapping)
+	}
+
+	config := database.NewConfiguration("127.0.0.1", "root", password, databaseName)
+	config.Port = port
+
+	return config, nil
+}
+
+// docker runs a docker command and returns the trimmed output
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("docker %v: %v: %v", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Close closes the database and removes the container if one was started
+func (harness *Harness) Close() error {
+	var errs []error
+
+	if harness.DB != nil {
+		errs = append(errs, harness.DB.Close())
+	}
+
+	if harness.container != "" {
+		_, err := docker("stop", harness.container)
+		errs = append(errs, err)
+		harness.container = ""
+	}
+
+	return errors.Join(errs...)
+}
+
+// Tx starts a transaction which is rolled back when the test ends, use it as
+// Queryer so tests do not see each others data
+func (harness *Harness) Tx(t TB) database.Queryer {
+	t.Helper()
+
+	tx, err := harness.DB.Beginx()
+	if err != nil {
+		t.Fatalf("begin transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+
+	return tx
+}
+
+// Run calls fn with a transaction which is rolled back when the test ends, for
+// table driven tests run it inside t.Run to isolate each case
+func (harness *Harness) Run(t TB, fn func(queryer database.Queryer)) {
+	t.Helper()
+
+	fn(harness.Tx(t))
+}
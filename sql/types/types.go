@@ -0,0 +1,10 @@
+// Package types defines SQL column types that can be used in model structs.
+// The types register their SQL column type with the structural type registry
+package types
+
+import "github.com/almerlucke/go-utils/reflection/structural"
+
+func init() {
+	structural.RegisterTypeOf(Date{}, &structural.TypeInfo{SQLType: "date"})
+	structural.RegisterTypeOf(DateTime{}, &structural.TypeInfo{SQLType: "datetime"})
+}
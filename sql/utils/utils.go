@@ -14,19 +14,25 @@ func NewDatabase(config *database.Configuration, version string, migrations []*m
 		return nil, err
 	}
 
+	err = PrepareDatabase(db, version, migrations, tables...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// PrepareDatabase creates the tables if they do not exist and performs the migrations
+func PrepareDatabase(db *database.DB, version string, migrations []*migration.Version, tables ...model.Tabler) error {
 	// Create tables if not exist
 	for _, table := range tables {
-		_, err = db.Exec(table.TableQuery())
+		_, err := db.Exec(table.TableQuery())
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	// Perform migrations if necessary
-	err = migration.Migrate(db, version, migrations)
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	return migration.Migrate(db, version, migrations)
 }
@@ -0,0 +1,72 @@
+package time
+
+import "time"
+
+// Period is a calendar period in years, months and days
+type Period struct {
+	Years  int `json:"years"`
+	Months int `json:"months"`
+	Days   int `json:"days"`
+}
+
+// Age returns the period between birthDate and at. Months are counted from the
+// birth day, if the birth day does not exist in a month (e.g. the 31st or 29
+// February) the last day of that month is used. Returns the zero period if at is
+// before birthDate
+func Age(birthDate DateOnly, at DateOnly) Period {
+	if at.Before(birthDate) {
+		return Period{}
+	}
+
+	months := (at.Year-birthDate.Year)*12 + int(at.Month) - int(birthDate.Month)
+
+	anchor := addMonthsClamped(birthDate, months)
+	if anchor.After(at) {
+		months--
+		anchor = addMonthsClamped(birthDate, months)
+	}
+
+	return Period{
+		Years:  months / 12,
+		Months: months % 12,
+		Days:   at.DaysSince(anchor),
+	}
+}
+
+// NextAnniversary returns the first anniversary of date after after, e.g. the next
+// birthday. Anniversaries of 29 February fall on 28 February in non leap years
+func NextAnniversary(date DateOnly, after DateOnly) DateOnly {
+	anniversary := anniversaryIn(date, after.Year)
+	if !anniversary.After(after) {
+		anniversary = anniversaryIn(date, after.Year+1)
+	}
+
+	return anniversary
+}
+
+// anniversaryIn returns the anniversary of date in year
+func anniversaryIn(date DateOnly, year int) DateOnly {
+	return addMonthsClamped(date, (year-date.Year)*12)
+}
+
+// addMonthsClamped adds months to date, the day is clamped to the last day of the
+// resulting month
+func addMonthsClamped(date DateOnly, months int) DateOnly {
+	first := NewDateOnly(date.Year, date.Month+time.Month(months), 1)
+
+	day := date.Day
+	if last := daysIn(first.Year, first.Month); day > last {
+		day = last
+	}
+
+	return DateOnly{
+		Year:  first.Year,
+		Month: first.Month,
+		Day:   day,
+	}
+}
+
+// daysIn returns the number of days of a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
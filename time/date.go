@@ -0,0 +1,410 @@
+package time
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DateOnlyFormat format of DateOnly in JSON, text and SQL
+	DateOnlyFormat = "2006-01-02"
+
+	// TimeOfDayFormat format of TimeOfDay in JSON and text
+	TimeOfDayFormat = "15:04"
+
+	// TimeOfDaySecondsFormat format of TimeOfDay with seconds, used for SQL and
+	// accepted when parsing
+	TimeOfDaySecondsFormat = "15:04:05"
+)
+
+/*
+	DateOnly
+*/
+
+// DateOnly is a calendar date without time and location, e.g. a birthday. Unlike
+// sql.Date it is not tied to a database, the zero DateOnly is an unset date which can
+// be compared with ==. It is written as JSON null, SQL NULL and empty text
+type DateOnly struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDateOnly returns a normalized date, e.g. 2024-02-30 becomes 2024-03-01
+func NewDateOnly(year int, month time.Month, day int) DateOnly {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the date of t in the location of t
+func DateOf(t time.Time) DateOnly {
+	year, month, day := t.Date()
+
+	return DateOnly{
+		Year:  year,
+		Month: month,
+		Day:   day,
+	}
+}
+
+// Today returns the current date in loc
+func Today(loc *time.Location) DateOnly {
+	return DateOf(time.Now().In(loc))
+}
+
+// ParseDateOnly parses a date in DateOnlyFormat
+func ParseDateOnly(s string) (DateOnly, error) {
+	t, err := time.Parse(DateOnlyFormat, s)
+	if err != nil {
+		return DateOnly{}, err
+	}
+
+	return DateOf(t), nil
+}
+
+// Time returns the start of the date in loc
+func (d DateOnly) Time(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// IsZero checks if d is the zero date
+func (d DateOnly) IsZero() bool {
+	return d == DateOnly{}
+}
+
+// String formats d with DateOnlyFormat
+func (d DateOnly) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day)
+}
+
+// Compare returns -1, 0 or 1 if d is before, equal to or after other
+func (d DateOnly) Compare(other DateOnly) int {
+	switch {
+	case d.Year != other.Year:
+		return compareInts(d.Year, other.Year)
+	case d.Month != other.Month:
+		return compareInts(int(d.Month), int(other.Month))
+	}
+
+	return compareInts(d.Day, other.Day)
+}
+
+// Before checks if d is before other
+func (d DateOnly) Before(other DateOnly) bool {
+	return d.Compare(other) < 0
+}
+
+// After checks if d is after other
+func (d DateOnly) After(other DateOnly) bool {
+	return d.Compare(other) > 0
+}
+
+// Equal checks if d and other are the same date
+func (d DateOnly) Equal(other DateOnly) bool {
+	return d == other
+}
+
+// AddDays returns d plus n days
+func (d DateOnly) AddDays(n int) DateOnly {
+	return NewDateOnly(d.Year, d.Month, d.Day+n)
+}
+
+// AddDate returns d plus years, months and days like time.Time.AddDate
+func (d DateOnly) AddDate(years int, months int, days int) DateOnly {
+	return NewDateOnly(d.Year+years, d.Month+time.Month(months), d.Day+days)
+}
+
+// DaysSince returns the number of days from other to d
+func (d DateOnly) DaysSince(other DateOnly) int {
+	return int(d.Time(time.UTC).Sub(other.Time(time.UTC)).Hours() / 24)
+}
+
+// Weekday returns the day of the week of d
+func (d DateOnly) Weekday() time.Weekday {
+	return d.Time(time.UTC).Weekday()
+}
+
+// zeroDateOnly is the text of the zero date, e.g. the MySQL zero date
+const zeroDateOnly = "0000-00-00"
+
+// MarshalText for encoding.TextMarshaler, also used for query params and config. The
+// zero date is empty text
+func (d DateOnly) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText for encoding.TextUnmarshaler, empty text and 0000-00-00 are the
+// zero date
+func (d *DateOnly) UnmarshalText(b []byte) error {
+	if len(b) == 0 || string(b) == zeroDateOnly {
+		*d = DateOnly{}
+		return nil
+	}
+
+	date, err := ParseDateOnly(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = date
+
+	return nil
+}
+
+/*
+	JSON marshal and unmarshal for time.DateOnly
+*/
+
+// MarshalJSON marshal date to "2006-01-02" json string, the zero date to null
+func (d DateOnly) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return []byte(fmt.Sprintf("\"%v\"", d.String())), nil
+}
+
+// UnmarshalJSON unmarshal date from "2006-01-02" json string, null is the zero date
+func (d *DateOnly) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = DateOnly{}
+		return nil
+	}
+
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(s))
+}
+
+/*
+   Valuer interface for SQL driver
+*/
+
+// Value returns the date as "2006-01-02" string, the zero date as NULL
+func (d DateOnly) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+
+	return d.String(), nil
+}
+
+/*
+   Scanner interface for SQL driver
+*/
+
+// Scan can scan []byte, string and time.Time, NULL and 0000-00-00 are scanned as the
+// zero date
+func (d *DateOnly) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = DateOnly{}
+	case []byte:
+		return d.UnmarshalText(v)
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case time.Time:
+		*d = DateOf(v)
+	default:
+		return errors.New("invalid src for time.DateOnly")
+	}
+
+	return nil
+}
+
+/*
+	TimeOfDay
+*/
+
+// TimeOfDay is a wall clock time without date and location, e.g. an opening hour
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+	Second int
+}
+
+// secondsPerDay is the number of seconds of a day
+const secondsPerDay = 24 * 60 * 60
+
+// NewTimeOfDay returns a normalized time of day, values wrap around midnight
+func NewTimeOfDay(hour int, minute int, second int) TimeOfDay {
+	return timeOfDayFromSeconds(hour*3600 + minute*60 + second)
+}
+
+// TimeOfDayOf returns the wall clock time of t in the location of t
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	return TimeOfDay{
+		Hour:   t.Hour(),
+		Minute: t.Minute(),
+		Second: t.Second(),
+	}
+}
+
+// ParseTimeOfDay parses a time of day in TimeOfDayFormat or TimeOfDaySecondsFormat
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	layout := TimeOfDayFormat
+	if len(s) > len(TimeOfDayFormat) {
+		layout = TimeOfDaySecondsFormat
+	}
+
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return TimeOfDay{}, err
+	}
+
+	return TimeOfDayOf(t), nil
+}
+
+// timeOfDayFromSeconds creates a time of day from seconds since midnight
+func timeOfDayFromSeconds(seconds int) TimeOfDay {
+	seconds %= secondsPerDay
+	if seconds < 0 {
+		seconds += secondsPerDay
+	}
+
+	return TimeOfDay{
+		Hour:   seconds / 3600,
+		Minute: seconds % 3600 / 60,
+		Second: seconds % 60,
+	}
+}
+
+// Seconds returns the number of seconds since midnight
+func (t TimeOfDay) Seconds() int {
+	return t.Hour*3600 + t.Minute*60 + t.Second
+}
+
+// On returns the time of day on date in loc
+func (t TimeOfDay) On(date DateOnly, loc *time.Location) time.Time {
+	return time.Date(date.Year, date.Month, date.Day, t.Hour, t.Minute, t.Second, 0, loc)
+}
+
+// String formats t with TimeOfDayFormat, or TimeOfDaySecondsFormat if t has seconds
+func (t TimeOfDay) String() string {
+	if t.Second != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	}
+
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// Compare returns -1, 0 or 1 if t is before, equal to or after other
+func (t TimeOfDay) Compare(other TimeOfDay) int {
+	return compareInts(t.Seconds(), other.Seconds())
+}
+
+// Before checks if t is before other
+func (t TimeOfDay) Before(other TimeOfDay) bool {
+	return t.Compare(other) < 0
+}
+
+// After checks if t is after other
+func (t TimeOfDay) After(other TimeOfDay) bool {
+	return t.Compare(other) > 0
+}
+
+// Equal checks if t and other are the same time of day
+func (t TimeOfDay) Equal(other TimeOfDay) bool {
+	return t == other
+}
+
+// Add returns t plus d, wrapping around midnight. Sub second precision is dropped
+func (t TimeOfDay) Add(d time.Duration) TimeOfDay {
+	return timeOfDayFromSeconds(t.Seconds() + int(d/time.Second))
+}
+
+// Sub returns the duration from other to t, negative if t is before other
+func (t TimeOfDay) Sub(other TimeOfDay) time.Duration {
+	return time.Duration(t.Seconds()-other.Seconds()) * time.Second
+}
+
+// MarshalText for encoding.TextMarshaler, also used for query params and config
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText for encoding.TextUnmarshaler
+func (t *TimeOfDay) UnmarshalText(b []byte) error {
+	timeOfDay, err := ParseTimeOfDay(string(b))
+	if err != nil {
+		return err
+	}
+
+	*t = timeOfDay
+
+	return nil
+}
+
+/*
+	JSON marshal and unmarshal for time.TimeOfDay
+*/
+
+// MarshalJSON marshal time of day to "15:04" json string
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%v\"", t.String())), nil
+}
+
+// UnmarshalJSON unmarshal time of day from "15:04" or "15:04:05" json string
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	return t.UnmarshalText([]byte(s))
+}
+
+/*
+   Valuer interface for SQL driver
+*/
+
+// Value returns the time of day as "15:04:05" string
+func (t TimeOfDay) Value() (driver.Value, error) {
+	return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second), nil
+}
+
+/*
+   Scanner interface for SQL driver
+*/
+
+// Scan can scan []byte, string and time.Time, NULL is scanned as midnight
+func (t *TimeOfDay) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = TimeOfDay{}
+	case []byte:
+		return t.UnmarshalText(v)
+	case string:
+		return t.UnmarshalText([]byte(v))
+	case time.Time:
+		*t = TimeOfDayOf(v)
+	default:
+		return errors.New("invalid src for time.TimeOfDay")
+	}
+
+	return nil
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
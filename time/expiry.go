@@ -0,0 +1,144 @@
+package time
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ExpirySQLFormat format used to scan expiry dates from SQL strings
+	ExpirySQLFormat = "2006-01-02 15:04:05"
+)
+
+// Expiry is a point in time after which something is no longer valid. The zero
+// Expiry is always expired
+type Expiry time.Time
+
+// ExpireIn returns an expiry d from now
+func ExpireIn(d time.Duration) Expiry {
+	return Expiry(time.Now().UTC().Add(d))
+}
+
+// ExpireAt returns an expiry at time t
+func ExpireAt(t time.Time) Expiry {
+	return Expiry(t.UTC())
+}
+
+// Time returns the expiry as time.Time
+func (e Expiry) Time() time.Time {
+	return time.Time(e)
+}
+
+// IsZero true if the expiry is not set
+func (e Expiry) IsZero() bool {
+	return time.Time(e).IsZero()
+}
+
+// Expired true if the expiry lies in the past
+func (e Expiry) Expired() bool {
+	return e.ExpiredAt(time.Now())
+}
+
+// ExpiredAt true if the expiry lies before or at t
+func (e Expiry) ExpiredAt(t time.Time) bool {
+	return !time.Time(e).After(t)
+}
+
+// Remaining returns the duration until expiry, or 0 if expired
+func (e Expiry) Remaining() time.Duration {
+	remaining := time.Until(time.Time(e))
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// Extend returns a new expiry d from now, if the current expiry is further away
+// it is returned as is
+func (e Expiry) Extend(d time.Duration) Expiry {
+	extended := ExpireIn(d)
+	if time.Time(e).After(time.Time(extended)) {
+		return e
+	}
+
+	return extended
+}
+
+// String stringer
+func (e Expiry) String() string {
+	return time.Time(e).Format(time.RFC3339)
+}
+
+/*
+   Valuer interface for SQL driver
+*/
+
+// Value returns time.Time
+func (e Expiry) Value() (driver.Value, error) {
+	return time.Time(e).UTC(), nil
+}
+
+/*
+   Scanner interface for SQL driver
+*/
+
+// Scan can scan []byte, string and time.Time, NULL is scanned as the zero expiry
+func (e *Expiry) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*e = Expiry{}
+	case []byte:
+		return e.scanString(string(v))
+	case string:
+		return e.scanString(v)
+	case time.Time:
+		*e = Expiry(v.UTC())
+	default:
+		return errors.New("invalid src for time.Expiry")
+	}
+
+	return nil
+}
+
+func (e *Expiry) scanString(s string) error {
+	t, err := time.Parse(ExpirySQLFormat, s)
+	if err != nil {
+		return err
+	}
+
+	*e = Expiry(t.UTC())
+
+	return nil
+}
+
+/*
+	JSON marshal and unmarshal for time.Expiry
+*/
+
+// MarshalJSON marshal expiry to RFC 3339 json string
+func (e Expiry) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%v\"", time.Time(e).UTC().Format(time.RFC3339))), nil
+}
+
+// UnmarshalJSON unmarshal expiry from RFC 3339 json string
+func (e *Expiry) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+
+	*e = Expiry(t.UTC())
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package time
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache of loaded IANA zones, time.LoadLocation reads the zone database from
+// disk on every call
+var locationCache sync.Map
+
+// LoadLocation returns the location for an IANA zone name (e.g. "Europe/Amsterdam"),
+// loaded locations are cached. An empty name or "UTC" returns UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locationCache.Store(name, loc)
+
+	return loc, nil
+}
+
+// LocationOrUTC returns the location for an IANA zone name, or UTC if the
+// zone can not be loaded
+func LocationOrUTC(name string) *time.Location {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
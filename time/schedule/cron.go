@@ -0,0 +1,341 @@
+// Package schedule contains a cron expression parser and a lightweight scheduler
+// that runs registered functions on their schedules.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the next activation time after a given time
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// CronSchedule is a parsed cron expression, each field is stored as a bit set
+type CronSchedule struct {
+	Second   uint64
+	Minute   uint64
+	Hour     uint64
+	Dom      uint64
+	Month    uint64
+	Dow      uint64
+	Location *time.Location
+
+	// Dom or Dow is restricted, if both are restricted a day matches when
+	// one of them matches (standard cron behavior)
+	domStar bool
+	dowStar bool
+}
+
+// bounds of a field, max is the end of * and n/s ranges and limit the highest
+// value which may be given explicitly
+type bounds struct {
+	min   uint
+	max   uint
+	limit uint
+	names map[string]uint
+}
+
+var (
+	secondBounds = bounds{0, 59, 59, nil}
+	minuteBounds = bounds{0, 59, 59, nil}
+	hourBounds   = bounds{0, 23, 23, nil}
+	domBounds    = bounds{1, 31, 31, nil}
+	monthBounds  = bounds{1, 12, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as Sunday in values and ranges and folded into 0 after parsing
+	dowBounds = bounds{0, 6, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a standard 5 field cron expression (minute hour dom month dow),
+// a 6 field expression with leading seconds, or one of the descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly. Schedules are
+// evaluated in UTC, use ParseInLocation to evaluate in another location
+func Parse(spec string) (*CronSchedule, error) {
+	return ParseInLocation(spec, time.UTC)
+}
+
+// ParseInLocation parses a cron expression which is evaluated in the given location
+func ParseInLocation(spec string, loc *time.Location) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if descriptor, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields in cron expression %q, found %d", spec, len(fields))
+	}
+
+	schedule := &CronSchedule{
+		Location: loc,
+		domStar:  fields[3] == "*" || fields[3] == "?",
+		dowStar:  fields[5] == "*" || fields[5] == "?",
+	}
+
+	var err error
+
+	if schedule.Second, err = parseField(fields[0], secondBounds); err != nil {
+		return nil, err
+	}
+
+	if schedule.Minute, err = parseField(fields[1], minuteBounds); err != nil {
+		return nil, err
+	}
+
+	if schedule.Hour, err = parseField(fields[2], hourBounds); err != nil {
+		return nil, err
+	}
+
+	if schedule.Dom, err = parseField(fields[3], domBounds); err != nil {
+		return nil, err
+	}
+
+	if schedule.Month, err = parseField(fields[4], monthBounds); err != nil {
+		return nil, err
+	}
+
+	// Allow 7 as alias for sunday
+	if schedule.Dow, err = parseField(fields[5], dowBounds); err != nil {
+		return nil, err
+	}
+
+	if schedule.Dow&(1<<7) != 0 {
+		schedule.Dow = schedule.Dow&^(1<<7) | 1
+	}
+
+	return schedule, nil
+}
+
+// MustParse parses a cron expression and panics on error
+func MustParse(spec string) *CronSchedule {
+	schedule, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return schedule
+}
+
+// parseField parses a comma separated list of ranges into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, expr := range strings.Split(field, ",") {
+		rangeBits, err := parseRange(expr, b)
+		if err != nil {
+			return 0, err
+		}
+
+		bits |= rangeBits
+	}
+
+	return bits, nil
+}
+
+// parseRange parses a single range expression of the form *, ?, n, n-m, */s, n/s or n-m/s
+func parseRange(expr string, b bounds) (uint64, error) {
+	var start, end, step uint
+
+	rangeAndStep := strings.SplitN(expr, "/", 2)
+	lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+
+	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+		if len(lowAndHigh) > 1 {
+			return 0, fmt.Errorf("invalid range %q", expr)
+		}
+
+		start = b.min
+		end = b.max
+	} else {
+		var err error
+
+		start, err = parseValue(lowAndHigh[0], b)
+		if err != nil {
+			return 0, err
+		}
+
+		end = start
+
+		if len(lowAndHigh) == 2 {
+			end, err = parseValue(lowAndHigh[1], b)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	step = 1
+
+	if len(rangeAndStep) == 2 {
+		s, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+		if err != nil || s == 0 {
+			return 0, fmt.Errorf("invalid step in %q", expr)
+		}
+
+		step = uint(s)
+
+		// n/s means n until max with step s
+		if len(lowAndHigh) == 1 && lowAndHigh[0] != "*" && lowAndHigh[0] != "?" {
+			end = b.max
+		}
+	}
+
+	if start < b.min || end > b.limit || start > end {
+		return 0, fmt.Errorf("range %q out of bounds [%d, %d]", expr, b.min, b.limit)
+	}
+
+	var bits uint64
+
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if b.names != nil {
+		if v, ok := b.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	return uint(v), nil
+}
+
+// dayMatches checks dom and dow restrictions for the given time
+func (schedule *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := schedule.Dom&(1<<uint(t.Day())) != 0
+	dowMatch := schedule.Dow&(1<<uint(t.Weekday())) != 0
+
+	if schedule.domStar || schedule.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Next returns the first activation time strictly after the given time, or the
+// zero time if no activation can be found within five years
+func (schedule *CronSchedule) Next(after time.Time) time.Time {
+	loc := schedule.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	origLocation := after.Location()
+
+	t := after.In(loc).Add(time.Second - time.Duration(after.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+	added := false
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for schedule.Month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+
+		t = t.AddDate(0, 1, 0)
+
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !schedule.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+
+		t = t.AddDate(0, 0, 1)
+
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for schedule.Hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+
+		t = t.Add(time.Hour)
+
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for schedule.Minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+
+		t = t.Add(time.Minute)
+
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for schedule.Second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+
+		t = t.Add(time.Second)
+
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// Every is a fixed interval schedule
+type Every time.Duration
+
+// Next returns after plus the interval
+func (every Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(every))
+}
@@ -0,0 +1,52 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDowBits(t *testing.T) {
+	tests := []struct {
+		spec string
+		dow  uint64
+	}{
+		{"0 0 * * *", 0b1111111},
+		{"0 0 * * 7", 0b0000001},
+		{"0 0 * * 0", 0b0000001},
+		{"0 0 * * 5-7", 0b1100001},
+		{"0 0 * * */2", 0b1010101},
+		{"0 0 * * 1/2", 0b0101010},
+		{"0 0 * * 1-7/2", 0b0101011},
+		{"0 0 * * 0/3", 0b1001001},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Fatalf("%v: %v", test.spec, err)
+		}
+
+		if schedule.Dow != test.dow {
+			t.Errorf("%v: dow %b, expected %b", test.spec, schedule.Dow, test.dow)
+		}
+	}
+}
+
+func TestDowStepNext(t *testing.T) {
+	// Saturday
+	after := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	next := MustParse("0 0 * * 1/2").Next(after)
+	if !next.Equal(expected) {
+		t.Errorf("next %v, expected %v", next, expected)
+	}
+}
+
+func TestDowOutOfBounds(t *testing.T) {
+	for _, spec := range []string{"0 0 * * 8", "0 0 * * 6-8"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%v: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+)
+
+// Func is a function that can be scheduled, the context is cancelled when the
+// scheduler stops
+type Func func(ctx context.Context)
+
+// Job is a named function with a schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Func     Func
+}
+
+// Scheduler runs registered jobs on their schedules
+type Scheduler struct {
+	// Logger is used to log recovered panics, if nil the default logger is used
+	Logger logging.Logger
+
+	// Jitter is the maximum random delay added to each activation, this
+	// prevents multiple instances from running jobs at exactly the same time
+	Jitter time.Duration
+
+	// ErrorHandlerFunc is called with the job and the recovered value when a
+	// job panics
+	ErrorHandlerFunc func(job *Job, err interface{})
+
+	mutex   sync.Mutex
+	jobs    []*Job
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	ctx     context.Context
+}
+
+// New creates a new scheduler
+func New() *Scheduler {
+	return &Scheduler{
+		Logger: logging.Default().With("component", "schedule"),
+		jobs:   []*Job{},
+	}
+}
+
+// Add registers a function with a cron expression, see Parse for the
+// supported syntax
+func (scheduler *Scheduler) Add(name string, spec string, fn Func) (*Job, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler.AddSchedule(name, schedule, fn), nil
+}
+
+// AddSchedule registers a function with a schedule, if the scheduler is already
+// running the job is started immediately
+func (scheduler *Scheduler) AddSchedule(name string, schedule Schedule, fn Func) *Job {
+	job := &Job{
+		Name:     name,
+		Schedule: schedule,
+		Func:     fn,
+	}
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.jobs = append(scheduler.jobs, job)
+
+	if scheduler.running {
+		scheduler.start(job)
+	}
+
+	return job
+}
+
+// Jobs returns the registered jobs
+func (scheduler *Scheduler) Jobs() []*Job {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	jobs := make([]*Job, len(scheduler.jobs))
+	copy(jobs, scheduler.jobs)
+
+	return jobs
+}
+
+// Start runs all jobs in the background until ctx is cancelled or Stop is called
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if scheduler.running {
+		return
+	}
+
+	scheduler.ctx, scheduler.cancel = context.WithCancel(ctx)
+	scheduler.running = true
+
+	for _, job := range scheduler.jobs {
+		scheduler.start(job)
+	}
+}
+
+// Run runs all jobs and blocks until ctx is cancelled, running jobs are waited for
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	scheduler.Start(ctx)
+	<-ctx.Done()
+	scheduler.Stop()
+}
+
+// Stop cancels the context of all jobs and waits for running jobs to return
+func (scheduler *Scheduler) Stop() {
+	scheduler.mutex.Lock()
+
+	if !scheduler.running {
+		scheduler.mutex.Unlock()
+		return
+	}
+
+	scheduler.running = false
+	scheduler.cancel()
+	scheduler.mutex.Unlock()
+
+	scheduler.wg.Wait()
+}
+
+// start the job loop, mutex must be held
+func (scheduler *Scheduler) start(job *Job) {
+	ctx := scheduler.ctx
+
+	scheduler.wg.Add(1)
+
+	go func() {
+		defer scheduler.wg.Done()
+
+		for {
+			now := time.Now()
+			next := job.Schedule.Next(now)
+			if next.IsZero() {
+				return
+			}
+
+			delay := next.Sub(now)
+			if scheduler.Jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(scheduler.Jitter)))
+			}
+
+			timer := time.NewTimer(delay)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				scheduler.run(ctx, job)
+			}
+		}
+	}()
+}
+
+// run the job and recover from panics
+func (scheduler *Scheduler) run(ctx context.Context, job *Job) {
+	defer func() {
+		if err := recover(); err != nil {
+			logging.OrDefault(scheduler.Logger).Error("job panic", "job", job.Name, "error", err, "stack", string(debug.Stack()))
+
+			if scheduler.ErrorHandlerFunc != nil {
+				scheduler.ErrorHandlerFunc(job, err)
+			}
+		}
+	}()
+
+	job.Func(ctx)
+}
@@ -0,0 +1,185 @@
+package time
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stopwatch measures elapsed time with laps, it is not safe for concurrent use
+type Stopwatch struct {
+	start   time.Time
+	lastLap time.Time
+	laps    []time.Duration
+}
+
+// NewStopwatch creates a started stopwatch
+func NewStopwatch() *Stopwatch {
+	now := time.Now()
+
+	return &Stopwatch{
+		start:   now,
+		lastLap: now,
+	}
+}
+
+// Reset restarts the stopwatch and clears the laps
+func (sw *Stopwatch) Reset() {
+	now := time.Now()
+
+	sw.start = now
+	sw.lastLap = now
+	sw.laps = nil
+}
+
+// Elapsed returns the time since the stopwatch started
+func (sw *Stopwatch) Elapsed() time.Duration {
+	return time.Since(sw.start)
+}
+
+// Lap records and returns the time since the previous lap or the start
+func (sw *Stopwatch) Lap() time.Duration {
+	now := time.Now()
+	lap := now.Sub(sw.lastLap)
+
+	sw.lastLap = now
+	sw.laps = append(sw.laps, lap)
+
+	return lap
+}
+
+// Split returns the time since the start without recording a lap
+func (sw *Stopwatch) Split() time.Duration {
+	return sw.Elapsed()
+}
+
+// Laps returns the recorded laps
+func (sw *Stopwatch) Laps() []time.Duration {
+	return append([]time.Duration{}, sw.laps...)
+}
+
+// Measure returns the duration of fn
+func Measure(fn func()) time.Duration {
+	start := time.Now()
+	fn()
+
+	return time.Since(start)
+}
+
+// LatencyTracker tracks latencies with an exponentially weighted moving average and
+// a rolling window of the most recent observations for percentiles. It is safe
+// for concurrent use
+type LatencyTracker struct {
+	// Alpha is the weight of a new observation in the moving average, between 0 and 1
+	Alpha float64
+
+	mutex  sync.Mutex
+	ewma   float64
+	count  int64
+	window []time.Duration
+	next   int
+	full   bool
+}
+
+// NewLatencyTracker creates a tracker with the moving average weight alpha and a
+// rolling window of windowSize observations
+func NewLatencyTracker(alpha float64, windowSize int) *LatencyTracker {
+	if windowSize < 1 {
+		windowSize = 1
+	}
+
+	return &LatencyTracker{
+		Alpha:  alpha,
+		window: make([]time.Duration, windowSize),
+	}
+}
+
+// Observe adds a latency
+func (tracker *LatencyTracker) Observe(d time.Duration) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.count == 0 {
+		tracker.ewma = float64(d)
+	} else {
+		tracker.ewma = tracker.Alpha*float64(d) + (1-tracker.Alpha)*tracker.ewma
+	}
+
+	tracker.count++
+
+	tracker.window[tracker.next] = d
+	tracker.next = (tracker.next + 1) % len(tracker.window)
+
+	if tracker.next == 0 {
+		tracker.full = true
+	}
+}
+
+// Time observes the duration of fn
+func (tracker *LatencyTracker) Time(fn func()) {
+	tracker.Observe(Measure(fn))
+}
+
+// Count returns the total number of observations
+func (tracker *LatencyTracker) Count() int64 {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return tracker.count
+}
+
+// Average returns the moving average
+func (tracker *LatencyTracker) Average() time.Duration {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return time.Duration(tracker.ewma)
+}
+
+// Percentile returns the p-th percentile (0-100) of the observations in the window
+func (tracker *LatencyTracker) Percentile(p float64) time.Duration {
+	values := tracker.sortedWindow()
+	if len(values) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p/100*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	} else if rank >= len(values) {
+		rank = len(values) - 1
+	}
+
+	return values[rank]
+}
+
+// Max returns the maximum of the observations in the window
+func (tracker *LatencyTracker) Max() time.Duration {
+	values := tracker.sortedWindow()
+	if len(values) == 0 {
+		return 0
+	}
+
+	return values[len(values)-1]
+}
+
+// sortedWindow returns a sorted copy of the observations in the window
+func (tracker *LatencyTracker) sortedWindow() []time.Duration {
+	tracker.mutex.Lock()
+
+	n := tracker.next
+	if tracker.full {
+		n = len(tracker.window)
+	}
+
+	values := append([]time.Duration{}, tracker.window[:n]...)
+
+	tracker.mutex.Unlock()
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	return values
+}
@@ -0,0 +1,121 @@
+package time
+
+import (
+	"context"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/almerlucke/go-utils/logging"
+)
+
+// Logger logs panics recovered by Every, logging.Default() is used when nil
+var Logger logging.Logger
+
+// Jitter returns d randomly varied by up to fraction of d in both directions, e.g.
+// a fraction of 0.1 returns a duration between 0.9d and 1.1d
+func Jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+
+	delta := (rand.Float64()*2 - 1) * fraction * float64(d)
+
+	jittered := time.Duration(float64(d) + delta)
+	if jittered <= 0 {
+		return time.Nanosecond
+	}
+
+	return jittered
+}
+
+// JitterTicker delivers ticks like time.Ticker, each interval is the base interval
+// varied with Jitter. Use it to spread pollers of multiple instances
+type JitterTicker struct {
+	// C delivers the ticks, ticks are dropped when the receiver is not ready
+	C <-chan time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewJitterTicker creates a started ticker with intervals of base varied by up to
+// jitterFraction of base
+func NewJitterTicker(base time.Duration, jitterFraction float64) *JitterTicker {
+	if base <= 0 {
+		panic("non-positive interval for NewJitterTicker")
+	}
+
+	c := make(chan time.Time, 1)
+
+	ticker := &JitterTicker{
+		C:    c,
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		timer := time.NewTimer(Jitter(base, jitterFraction))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ticker.stop:
+				return
+			case t := <-timer.C:
+				select {
+				case c <- t:
+				default:
+				}
+
+				timer.Reset(Jitter(base, jitterFraction))
+			}
+		}
+	}()
+
+	return ticker
+}
+
+// Stop turns off the ticker, no more ticks are sent after Stop returns
+func (ticker *JitterTicker) Stop() {
+	ticker.stopOnce.Do(func() {
+		close(ticker.stop)
+	})
+}
+
+// Every calls fn every d until ctx is done, it blocks so run it in a goroutine.
+// Panics in fn are recovered and logged, the next call happens on the next tick
+func Every(ctx context.Context, d time.Duration, fn func(ctx context.Context)) {
+	EveryWithJitter(ctx, d, 0, fn)
+}
+
+// EveryWithJitter calls fn like Every with intervals varied by up to jitterFraction
+// of d
+func EveryWithJitter(ctx context.Context, d time.Duration, jitterFraction float64, fn func(ctx context.Context)) {
+	ticker := NewJitterTicker(d, jitterFraction)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ctx.Err() != nil {
+				return
+			}
+
+			callRecovered(ctx, fn)
+		}
+	}
+}
+
+// callRecovered calls fn and logs a panic
+func callRecovered(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.OrDefault(Logger).Error("panic in interval function", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	fn(ctx)
+}
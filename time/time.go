@@ -11,3 +11,13 @@ func StartOfDay(t time.Time) time.Time {
 func EndOfDay(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 999999999, t.Location())
 }
+
+// StartOfDayIn truncate time to start of the day in the given location
+func StartOfDayIn(t time.Time, loc *time.Location) time.Time {
+	return StartOfDay(t.In(loc))
+}
+
+// EndOfDayIn ceil time to end of the day in the given location
+func EndOfDayIn(t time.Time, loc *time.Location) time.Time {
+	return EndOfDay(t.In(loc))
+}
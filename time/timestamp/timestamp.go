@@ -32,3 +32,76 @@ func (timestamp Timestamp) StartOfDay() Timestamp {
 func (timestamp Timestamp) EndOfDay() Timestamp {
 	return New(timeUtils.EndOfDay(timestamp.Time()))
 }
+
+// StartOfDayIn truncate timestamp to start of day in the given location
+func (timestamp Timestamp) StartOfDayIn(loc *time.Location) Timestamp {
+	return New(timeUtils.StartOfDayIn(timestamp.Time(), loc))
+}
+
+// EndOfDayIn truncate timestamp to end of day in the given location
+func (timestamp Timestamp) EndOfDayIn(loc *time.Location) Timestamp {
+	return New(timeUtils.EndOfDayIn(timestamp.Time(), loc))
+}
+
+// millis returns the duration in milliseconds
+func millis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// Truncate rounds the timestamp down to a multiple of d since the Unix epoch.
+// If d is smaller than a millisecond the timestamp is returned unchanged
+func (timestamp Timestamp) Truncate(d time.Duration) Timestamp {
+	m := millis(d)
+	if m <= 0 {
+		return timestamp
+	}
+
+	return Timestamp(timestamp.BucketKey(d) * m)
+}
+
+// Round rounds the timestamp to the nearest multiple of d since the Unix epoch,
+// halfway values are rounded up. If d is smaller than a millisecond the timestamp
+// is returned unchanged
+func (timestamp Timestamp) Round(d time.Duration) Timestamp {
+	m := millis(d)
+	if m <= 0 {
+		return timestamp
+	}
+
+	truncated := timestamp.Truncate(d)
+	if int64(timestamp-truncated)*2 >= m {
+		return truncated + Timestamp(m)
+	}
+
+	return truncated
+}
+
+// BucketKey returns the index of the window of size d (since the Unix epoch)
+// the timestamp falls in. Bucket keys are stable across processes, so they can be
+// used to aggregate time-series events into 1m, 5m, 1h etc. windows. If d is smaller
+// than a millisecond the timestamp itself is returned
+func (timestamp Timestamp) BucketKey(d time.Duration) int64 {
+	m := millis(d)
+	if m <= 0 {
+		return int64(timestamp)
+	}
+
+	key := int64(timestamp) / m
+
+	// Floor division for timestamps before the epoch
+	if int64(timestamp)%m < 0 {
+		key--
+	}
+
+	return key
+}
+
+// BucketStart returns the start timestamp of the bucket with the given key
+func BucketStart(key int64, d time.Duration) Timestamp {
+	m := millis(d)
+	if m <= 0 {
+		return Timestamp(key)
+	}
+
+	return Timestamp(key * m)
+}
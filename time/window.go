@@ -0,0 +1,142 @@
+package time
+
+import (
+	"sync"
+	"time"
+)
+
+// windowBuckets is the number of buckets a window is divided in
+const windowBuckets = 10
+
+// SlidingWindowCounter counts events within a sliding window, events older than the
+// window are pruned automatically. Events are counted in a fixed ring of buckets of
+// a tenth of the window, so memory does not grow with the event rate. A bucket
+// leaves the window with its newest event, events can be counted up to a tenth of
+// the window longer than their own age allows. Events are timestamped with the
+// monotonic clock so wall clock changes don't affect the count. It is safe for
+// concurrent use
+type SlidingWindowCounter struct {
+	window  time.Duration
+	width   time.Duration
+	origin  time.Time
+	mutex   sync.Mutex
+	buckets [windowBuckets + 1]windowBucket
+}
+
+// windowBucket counts the events of the interval with index n since the origin
+type windowBucket struct {
+	n     int64
+	count int
+	last  time.Time
+}
+
+// WindowSnapshot is the state of a sliding window counter at a point in time
+type WindowSnapshot struct {
+	// Window of the counter
+	Window time.Duration
+
+	// Count of events within the window
+	Count int
+
+	// Oldest is the time the oldest bucket of events leaves the window minus the
+	// window and Newest the time of the newest event, zero if the count is 0
+	Oldest time.Time
+	Newest time.Time
+}
+
+// ResetIn returns the time until the oldest event leaves the window
+func (snapshot WindowSnapshot) ResetIn() time.Duration {
+	if snapshot.Count == 0 {
+		return 0
+	}
+
+	return time.Until(snapshot.Oldest.Add(snapshot.Window))
+}
+
+// NewSlidingWindowCounter creates a counter for window
+func NewSlidingWindowCounter(window time.Duration) *SlidingWindowCounter {
+	width := window / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+
+	return &SlidingWindowCounter{
+		window: window,
+		width:  width,
+		origin: time.Now(),
+	}
+}
+
+// Window returns the window of the counter
+func (counter *SlidingWindowCounter) Window() time.Duration {
+	return counter.window
+}
+
+// Add records an event and returns the number of events within the window
+func (counter *SlidingWindowCounter) Add() int {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	now := time.Now()
+	n := int64(now.Sub(counter.origin) / counter.width)
+	bucket := &counter.buckets[n%int64(len(counter.buckets))]
+
+	// Reuse the slot of a bucket which left the window
+	if bucket.n != n {
+		*bucket = windowBucket{n: n}
+	}
+
+	bucket.count++
+	bucket.last = now
+
+	return counter.snapshot(now).Count
+}
+
+// Count returns the number of events within the window
+func (counter *SlidingWindowCounter) Count() int {
+	return counter.Snapshot().Count
+}
+
+// Reset removes all events
+func (counter *SlidingWindowCounter) Reset() {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	counter.buckets = [windowBuckets + 1]windowBucket{}
+}
+
+// Snapshot returns the current state of the counter
+func (counter *SlidingWindowCounter) Snapshot() WindowSnapshot {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	return counter.snapshot(time.Now())
+}
+
+// snapshot sums the buckets of which the newest event is after now minus the
+// window, mutex must be held
+func (counter *SlidingWindowCounter) snapshot(now time.Time) WindowSnapshot {
+	start := now.Add(-counter.window)
+
+	snapshot := WindowSnapshot{
+		Window: counter.window,
+	}
+
+	for _, bucket := range counter.buckets {
+		if bucket.count == 0 || !bucket.last.After(start) {
+			continue
+		}
+
+		snapshot.Count += bucket.count
+
+		if snapshot.Oldest.IsZero() || bucket.last.Before(snapshot.Oldest) {
+			snapshot.Oldest = bucket.last
+		}
+
+		if bucket.last.After(snapshot.Newest) {
+			snapshot.Newest = bucket.last
+		}
+	}
+
+	return snapshot
+}
@@ -0,0 +1,69 @@
+package uuid
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// base62Alphabet is used for short IDs
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ShortLength is the length of a short ID, 22 base62 digits hold 128 bits
+const ShortLength = 22
+
+var base = big.NewInt(62)
+
+// Short encodes the UUID as a fixed length base62 string, the ordering of short
+// IDs matches the ordering of the UUIDs
+func (id UUID) Short() string {
+	n := new(big.Int).SetBytes(id[:])
+	digits := make([]byte, ShortLength)
+	mod := new(big.Int)
+
+	for i := ShortLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		digits[i] = base62Alphabet[mod.Int64()]
+	}
+
+	return string(digits)
+}
+
+// ParseShort decodes a short ID created with Short
+func ParseShort(s string) (UUID, error) {
+	if len(s) != ShortLength {
+		return Nil, fmt.Errorf("invalid short id length %d", len(s))
+	}
+
+	n := new(big.Int)
+
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base62Alphabet, c)
+		if digit < 0 {
+			return Nil, fmt.Errorf("invalid short id character %q", c)
+		}
+
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	if n.BitLen() > 128 {
+		return Nil, fmt.Errorf("short id %q out of range", s)
+	}
+
+	var id UUID
+
+	n.FillBytes(id[:])
+
+	return id, nil
+}
+
+// NewShort generates a short ID from a random version 4 UUID
+func NewShort() (string, error) {
+	id, err := NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	return id.Short(), nil
+}
@@ -0,0 +1,257 @@
+// Package uuid generates and parses RFC 9562 UUIDs, random (version 4) and time
+// ordered (version 7), and encodes them as short base62 IDs
+package uuid
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UUID is a 128 bit universally unique identifier
+type UUID [16]byte
+
+// Nil is the zero UUID
+var Nil UUID
+
+// NewV4 generates a random version 4 UUID
+func NewV4() (UUID, error) {
+	var id UUID
+
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return Nil, err
+	}
+
+	id.setVersion(4)
+
+	return id, nil
+}
+
+var (
+	v7Mutex    sync.Mutex
+	v7LastTime int64
+	v7Sequence uint16
+)
+
+// NewV7 generates a time ordered version 7 UUID, the first 48 bits are the unix
+// time in milliseconds. UUIDs generated in the same millisecond by this process
+// are ordered with a 12 bit sequence counter
+func NewV7() (UUID, error) {
+	var id UUID
+
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return Nil, err
+	}
+
+	v7Mutex.Lock()
+
+	ms := time.Now().UnixMilli()
+	if ms <= v7LastTime {
+		v7Sequence++
+
+		// Sequence overflow, borrow from the next millisecond
+		if v7Sequence > 0xfff {
+			v7Sequence = 0
+			v7LastTime++
+		}
+
+		ms = v7LastTime
+	} else {
+		v7LastTime = ms
+		v7Sequence = uint16(id[6]&0x07)<<8 | uint16(id[7])
+	}
+
+	sequence := v7Sequence
+
+	v7Mutex.Unlock()
+
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	id[6] = byte(sequence >> 8)
+	id[7] = byte(sequence)
+
+	id.setVersion(7)
+
+	return id, nil
+}
+
+// MustV4 generates a version 4 UUID and panics on error
+func MustV4() UUID {
+	id, err := NewV4()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// MustV7 generates a version 7 UUID and panics on error
+func MustV7() UUID {
+	id, err := NewV7()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// setVersion sets the version and the RFC 9562 variant bits
+func (id *UUID) setVersion(version byte) {
+	id[6] = (id[6] & 0x0f) | version<<4
+	id[8] = (id[8] & 0x3f) | 0x80
+}
+
+// Version of the UUID
+func (id UUID) Version() int {
+	return int(id[6] >> 4)
+}
+
+// Time returns the timestamp of a version 7 UUID, other versions return the zero time
+func (id UUID) Time() time.Time {
+	if id.Version() != 7 {
+		return time.Time{}
+	}
+
+	ms := int64(id[0])<<40 | int64(id[1])<<32 | int64(id[2])<<24 | int64(id[3])<<16 | int64(id[4])<<8 | int64(id[5])
+
+	return time.UnixMilli(ms)
+}
+
+// IsNil checks if the UUID is the zero UUID
+func (id UUID) IsNil() bool {
+	return id == Nil
+}
+
+// String returns the canonical representation xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (id UUID) String() string {
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return string(buf[:])
+}
+
+// Parse parses a canonical UUID, braces, the urn:uuid: prefix and the 32 character
+// form without hyphens are accepted as well
+func Parse(s string) (UUID, error) {
+	var id UUID
+
+	switch {
+	case len(s) == 45 && s[:9] == "urn:uuid:":
+		s = s[9:]
+	case len(s) == 38 && s[0] == '{' && s[37] == '}':
+		s = s[1:37]
+	}
+
+	switch len(s) {
+	case 32:
+		_, err := hex.Decode(id[:], []byte(s))
+		if err != nil {
+			return Nil, fmt.Errorf("invalid uuid %q", s)
+		}
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return Nil, fmt.Errorf("invalid uuid %q", s)
+		}
+
+		compact := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+
+		_, err := hex.Decode(id[:], []byte(compact))
+		if err != nil {
+			return Nil, fmt.Errorf("invalid uuid %q", s)
+		}
+	default:
+		return Nil, fmt.Errorf("invalid uuid length %d", len(s))
+	}
+
+	return id, nil
+}
+
+// MustParse parses a UUID and panics on error
+func MustParse(s string) UUID {
+	id, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// IsValid checks if s is a valid UUID
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// FromBytes creates a UUID from a 16 byte slice
+func FromBytes(b []byte) (UUID, error) {
+	var id UUID
+
+	if len(b) != 16 {
+		return Nil, fmt.Errorf("invalid uuid length %d", len(b))
+	}
+
+	copy(id[:], b)
+
+	return id, nil
+}
+
+// MarshalText for encoding.TextMarshaler
+func (id UUID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText for encoding.TextUnmarshaler
+func (id *UUID) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+
+	return nil
+}
+
+// Value for sql Valuer interface, the UUID is stored in canonical string form
+func (id UUID) Value() (driver.Value, error) {
+	return id.String(), nil
+}
+
+// Scan for sql Scanner interface, accepts the string form and 16 raw bytes
+func (id *UUID) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*id = Nil
+		return nil
+	case string:
+		return id.UnmarshalText([]byte(v))
+	case []byte:
+		if len(v) == 16 {
+			copy(id[:], v)
+			return nil
+		}
+
+		return id.UnmarshalText(v)
+	}
+
+	return errors.New("incompatible type for uuid")
+}